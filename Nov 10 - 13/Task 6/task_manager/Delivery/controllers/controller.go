@@ -1,12 +1,25 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"task_manager/Domain"
 )
 
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Error string `json:"error"`
+	// Violations lists the password policy rules a rejected password breaks
+	Violations []string `json:"violations,omitempty"`
+}
+
+// MessageResponse is the body of responses that only confirm an action
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
 // TaskController handles HTTP requests for tasks
 type TaskController struct {
 	taskUseCase domain.TaskUseCase
@@ -19,11 +32,22 @@ func NewTaskController(taskUseCase domain.TaskUseCase) *TaskController {
 	}
 }
 
+// actorFrom builds the use case actor from the values set by AuthMiddleware
+func actorFrom(ctx *gin.Context) domain.Actor {
+	role, _ := ctx.Get("userRole")
+	actorRole, _ := role.(domain.Role)
+	return domain.Actor{UserID: ctx.GetString("userID"), Role: actorRole}
+}
+
 // GetTasks handles GET /tasks
 func (c *TaskController) GetTasks(ctx *gin.Context) {
-	tasks, err := c.taskUseCase.GetAllTasks()
+	tasks, err := c.taskUseCase.GetAllTasks(actorFrom(ctx))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tasks"})
+		if err == domain.ErrForbidden {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tasks"})
+		}
 		return
 	}
 
@@ -34,11 +58,14 @@ func (c *TaskController) GetTasks(ctx *gin.Context) {
 func (c *TaskController) GetTask(ctx *gin.Context) {
 	id := ctx.Param("id")
 
-	task, err := c.taskUseCase.GetTask(id)
+	task, err := c.taskUseCase.GetTask(actorFrom(ctx), id)
 	if err != nil {
-		if err == domain.ErrTaskNotFound {
+		switch err {
+		case domain.ErrTaskNotFound:
 			ctx.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
-		} else {
+		case domain.ErrForbidden:
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve task"})
 		}
 		return
@@ -55,13 +82,15 @@ func (c *TaskController) CreateTask(ctx *gin.Context) {
 		return
 	}
 
-	createdTask, err := c.taskUseCase.CreateTask(task)
+	createdTask, err := c.taskUseCase.CreateTask(actorFrom(ctx), task)
 	if err != nil {
 		switch err {
 		case domain.ErrInvalidInput:
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		case domain.ErrInvalidDueDate:
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case domain.ErrForbidden:
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		default:
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
 		}
@@ -81,13 +110,15 @@ func (c *TaskController) UpdateTask(ctx *gin.Context) {
 		return
 	}
 
-	updatedTask, err := c.taskUseCase.UpdateTask(id, task)
+	updatedTask, err := c.taskUseCase.UpdateTask(actorFrom(ctx), id, task)
 	if err != nil {
 		switch err {
 		case domain.ErrTaskNotFound:
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		case domain.ErrInvalidInput:
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case domain.ErrForbidden:
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		default:
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
 		}
@@ -101,11 +132,14 @@ func (c *TaskController) UpdateTask(ctx *gin.Context) {
 func (c *TaskController) DeleteTask(ctx *gin.Context) {
 	id := ctx.Param("id")
 
-	err := c.taskUseCase.DeleteTask(id)
+	err := c.taskUseCase.DeleteTask(actorFrom(ctx), id)
 	if err != nil {
-		if err == domain.ErrTaskNotFound {
+		switch err {
+		case domain.ErrTaskNotFound:
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		} else {
+		case domain.ErrForbidden:
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task"})
 		}
 		return
@@ -126,16 +160,31 @@ func NewUserController(userUseCase domain.UserUseCase) *UserController {
 	}
 }
 
+// RegisterRequest is the body of POST /register. domain.User never
+// unmarshals a password, so it cannot be bound directly.
+type RegisterRequest struct {
+	Username string `json:"username" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
 // Register handles POST /register
 func (c *UserController) Register(ctx *gin.Context) {
-	var user domain.User
-	if err := ctx.ShouldBindJSON(&user); err != nil {
+	var req RegisterRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
 
-	createdUser, err := c.userUseCase.Register(user)
+	createdUser, err := c.userUseCase.Register(domain.User{
+		Username: req.Username,
+		Email:    req.Email,
+		Password: req.Password,
+	})
 	if err != nil {
+		if respondWeakPassword(ctx, err) {
+			return
+		}
 		switch err {
 		case domain.ErrInvalidInput:
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -150,12 +199,20 @@ func (c *UserController) Register(ctx *gin.Context) {
 	ctx.JSON(http.StatusCreated, createdUser)
 }
 
+// LoginRequest is the body of POST /login
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+// LoginResponse is the response to a successful login
+type LoginResponse struct {
+	Token string `json:"token"`
+}
+
 // Login handles POST /login
 func (c *UserController) Login(ctx *gin.Context) {
-	var loginData struct {
-		Email    string `json:"email" binding:"required,email"`
-		Password string `json:"password" binding:"required"`
-	}
+	var loginData LoginRequest
 
 	if err := ctx.ShouldBindJSON(&loginData); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
@@ -172,7 +229,7 @@ func (c *UserController) Login(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"token": token})
+	ctx.JSON(http.StatusOK, LoginResponse{Token: token})
 }
 
 // GetProfile handles GET /profile
@@ -195,3 +252,158 @@ func (c *UserController) GetProfile(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, user)
 }
+
+// TokenRequest is the body of POST /verify
+type TokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// EmailRequest is the body of the endpoints that email a token
+type EmailRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest is the body of POST /reset-password
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// VerifyEmail handles POST /verify
+func (c *UserController) VerifyEmail(ctx *gin.Context) {
+	var req TokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := c.userUseCase.VerifyEmail(req.Token); err != nil {
+		c.respondTokenError(ctx, err, "Failed to verify email")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, MessageResponse{Message: "Email verified."})
+}
+
+// ResendVerification handles POST /resend-verification
+func (c *UserController) ResendVerification(ctx *gin.Context) {
+	var req EmailRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := c.userUseCase.ResendVerification(req.Email); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	// The same answer whether or not the email is registered
+	ctx.JSON(http.StatusAccepted, MessageResponse{Message: "If the account exists and is unverified, a verification email has been sent."})
+}
+
+// ForgotPassword handles POST /forgot-password
+func (c *UserController) ForgotPassword(ctx *gin.Context) {
+	var req EmailRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := c.userUseCase.ForgotPassword(req.Email); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send password reset email"})
+		return
+	}
+
+	// The same answer whether or not the email is registered
+	ctx.JSON(http.StatusAccepted, MessageResponse{Message: "If the account exists, a password reset email has been sent."})
+}
+
+// ResetPassword handles POST /reset-password
+func (c *UserController) ResetPassword(ctx *gin.Context) {
+	var req ResetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := c.userUseCase.ResetPassword(req.Token, req.Password); err != nil {
+		if respondWeakPassword(ctx, err) {
+			return
+		}
+		c.respondTokenError(ctx, err, "Failed to reset password")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, MessageResponse{Message: "Password updated"})
+}
+
+// respondWeakPassword writes a 400 listing the password policy violations
+// if err is a *domain.WeakPasswordError, and reports whether it did
+func respondWeakPassword(ctx *gin.Context, err error) bool {
+	var weak *domain.WeakPasswordError
+	if !errors.As(err, &weak) {
+		return false
+	}
+
+	ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Password too weak", Violations: weak.Violations})
+	return true
+}
+
+// respondTokenError writes the error of a token-based operation
+func (c *UserController) respondTokenError(ctx *gin.Context, err error, message string) {
+	switch err {
+	case domain.ErrInvalidToken, domain.ErrInvalidInput:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case domain.ErrUserNotFound:
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// ListUsers handles GET /admin/users
+func (c *UserController) ListUsers(ctx *gin.Context) {
+	users, err := c.userUseCase.ListUsers(actorFrom(ctx))
+	if err != nil {
+		if err == domain.ErrForbidden {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve users"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, users)
+}
+
+// PromoteUser handles PUT /admin/users/:id/promote
+func (c *UserController) PromoteUser(ctx *gin.Context) {
+	user, err := c.userUseCase.PromoteUser(actorFrom(ctx), ctx.Param("id"))
+	c.respondRoleChange(ctx, user, err)
+}
+
+// DemoteUser handles PUT /admin/users/:id/demote
+func (c *UserController) DemoteUser(ctx *gin.Context) {
+	user, err := c.userUseCase.DemoteUser(actorFrom(ctx), ctx.Param("id"))
+	c.respondRoleChange(ctx, user, err)
+}
+
+// respondRoleChange writes the result of a promotion or demotion
+func (c *UserController) respondRoleChange(ctx *gin.Context, user domain.User, err error) {
+	if err != nil {
+		switch err {
+		case domain.ErrUserNotFound:
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case domain.ErrForbidden:
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case domain.ErrCannotDemoteSelf:
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user role"})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, user)
+}
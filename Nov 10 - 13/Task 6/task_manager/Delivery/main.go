@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -12,10 +13,9 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"task_manager/Delivery/controllers"
 	"task_manager/Delivery/routers"
-	"task_manager/Domain"
 	"task_manager/Infrastructure"
 	repositories "task_manager/Repositories"
-	"task_manager/Usecases"
+	usecases "task_manager/Usecases"
 )
 
 func main() {
@@ -39,22 +39,48 @@ func main() {
 	// Initialize repositories
 	taskRepo := repositories.NewTaskRepositoryMongo(db)
 	userRepo := repositories.NewUserRepositoryMongo(db)
+	tokenRepo := repositories.NewTokenRepositoryMongo(db)
 
 	// Initialize services
 	passwordSvc := infrastructure.NewPasswordService()
-	jwtSecret := getEnv("JWT_SECRET", "your-secret-key")
-	jwtService := infrastructure.NewJWTService(jwtSecret)
+	keyRing, err := newKeyRing()
+	if err != nil {
+		log.Fatalf("Failed to load signing keys: %v", err)
+	}
+	keyRing.Start()
+	defer keyRing.Close()
+
+	jwtService, err := infrastructure.NewJWTService(
+		keyRing,
+		getEnv("JWT_ISSUER", "task_manager"),
+		getEnv("JWT_AUDIENCE", "task_manager"),
+	)
+	if err != nil {
+		log.Fatalf("Invalid JWT configuration: %v", err)
+	}
+	tokenSvc := infrastructure.NewActionTokenService(getEnv("TOKEN_SECRET", getEnv("JWT_SECRET", "your-secret-key")))
+	mailer := newMailer()
 
 	// Initialize use cases
-	taskUseCase := Usecases.NewTaskUseCase(taskRepo)
-	userUseCase := Usecases.NewUserUseCase(userRepo, passwordSvc, jwtService)
+	taskUseCase := usecases.NewTaskUseCase(taskRepo)
+	userUseCase := usecases.NewUserUseCase(userRepo, tokenRepo, passwordSvc, jwtService, tokenSvc, mailer)
+
+	// ADMIN_EMAIL lets an operator make an existing, verified account an
+	// admin, for example after the only admin lost access
+	if email := getEnv("ADMIN_EMAIL", ""); email != "" {
+		if _, err := userUseCase.BootstrapAdmin(email); err != nil {
+			log.Printf("Failed to make %s an admin: %v", email, err)
+		} else {
+			log.Printf("%s is an admin", email)
+		}
+	}
 
 	// Initialize controllers
 	taskController := controllers.NewTaskController(taskUseCase)
 	userController := controllers.NewUserController(userUseCase)
 
 	// Setup router
-	r := routers.SetupRouter(taskController, userController, jwtService)
+	r := routers.SetupRouter(taskController, userController, jwtService, userRepo)
 
 	// Start server in a goroutine
 	port := getEnv("PORT", "8080")
@@ -100,3 +126,51 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getDurationEnv gets a duration such as "720h" from an environment
+// variable or returns a default value
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return d
+}
+
+// newKeyRing loads the JWT signing keys. Without JWT_KEYS_DIR keys are
+// kept in memory and every restart invalidates all tokens.
+func newKeyRing() (*infrastructure.KeyRing, error) {
+	dir := getEnv("JWT_KEYS_DIR", "")
+	if dir == "" {
+		log.Println("JWT_KEYS_DIR not set; signing keys will not survive a restart")
+	}
+
+	return infrastructure.NewKeyRing(infrastructure.KeyRingConfig{
+		Algorithm:   getEnv("JWT_ALGORITHM", infrastructure.AlgorithmRS256),
+		Dir:         dir,
+		RotateEvery: getDurationEnv("JWT_KEY_ROTATION", 30*24*time.Hour),
+		Overlap:     getDurationEnv("JWT_KEY_OVERLAP", 25*time.Hour),
+	})
+}
+
+// newMailer sends email over SMTP when SMTP_HOST is set, and otherwise
+// writes it to standard output so the flows work in development
+func newMailer() infrastructure.Mailer {
+	host := getEnv("SMTP_HOST", "")
+	if host == "" {
+		log.Println("SMTP_HOST not set; emails will be printed to standard output")
+		return infrastructure.NewLogMailer(os.Stdout)
+	}
+
+	return infrastructure.NewSMTPMailer(
+		host,
+		getEnv("SMTP_PORT", "587"),
+		getEnv("SMTP_USERNAME", ""),
+		getEnv("SMTP_PASSWORD", ""),
+		getEnv("SMTP_FROM", "no-reply@localhost"),
+	)
+}
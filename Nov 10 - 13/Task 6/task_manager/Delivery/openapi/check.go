@@ -0,0 +1,45 @@
+package openapi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CheckRoutes compares the routes registered with gin to the documented
+// ones and returns an error listing every route that is only in one of
+// them. undocumented lists routes, as "METHOD /path", that are left out of
+// the document on purpose, such as the document itself.
+func CheckRoutes(routes gin.RoutesInfo, spec *Spec, undocumented ...string) error {
+	registered := make(map[string]bool)
+	for _, route := range routes {
+		registered[route.Method+" "+route.Path] = true
+	}
+	for _, route := range undocumented {
+		if !registered[route] {
+			return fmt.Errorf("openapi: route %s is listed as undocumented but not registered", route)
+		}
+		delete(registered, route)
+	}
+
+	var problems []string
+	for _, route := range spec.Routes() {
+		if registered[route] {
+			delete(registered, route)
+		} else {
+			problems = append(problems, "documented but not registered: "+route)
+		}
+	}
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		if registered[key] {
+			problems = append(problems, "registered but not documented: "+key)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("openapi: routes and document differ:\n\t%s", strings.Join(problems, "\n\t"))
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package openapi
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema (draft 2020-12), as used by OpenAPI 3.1.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 interface{}        `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+}
+
+// oneOf is a body that is one of several types.
+type oneOf []interface{}
+
+// OneOf documents a body that can be any of the types of values.
+func OneOf(values ...interface{}) interface{} {
+	return oneOf(values)
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	rawJSONType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemas turns Go types into schemas. Named struct types become shared
+// components referenced with $ref.
+type schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+	overrides  map[reflect.Type]*Schema
+}
+
+func newSchemas() *schemas {
+	return &schemas{
+		components: make(map[string]*Schema),
+		names:      make(map[reflect.Type]string),
+		overrides:  make(map[reflect.Type]*Schema),
+	}
+}
+
+// forValue returns the schema for the type of v.
+func (s *schemas) forValue(v interface{}) *Schema {
+	if choices, ok := v.(oneOf); ok {
+		schema := &Schema{}
+		for _, choice := range choices {
+			schema.OneOf = append(schema.OneOf, s.forValue(choice))
+		}
+		return schema
+	}
+	if schema, ok := v.(*Schema); ok {
+		return schema
+	}
+	return s.forType(reflect.TypeOf(v))
+}
+
+func (s *schemas) forType(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	if schema, ok := s.overrides[t]; ok {
+		return schema
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawJSONType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(s.forType(t.Elem()))
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0.0
+		return &Schema{Type: "integer", Minimum: &zero}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.forType(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.forType(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + s.component(t)}
+	}
+	return &Schema{}
+}
+
+// component registers a named struct type and returns its component name.
+func (s *schemas) component(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+
+	// Qualify the name with the package if another type already has it
+	name := t.Name()
+	if _, taken := s.components[name]; taken {
+		pkg := path.Base(t.PkgPath())
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	s.names[t] = name
+	s.components[name] = &Schema{} // placeholder for recursive types
+	s.components[name] = s.object(t)
+	return name
+}
+
+// object describes a struct the way encoding/json marshals it. Fields
+// with binding:"required" are required, and binding:"email" ones are
+// emails.
+func (s *schemas) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	s.addFields(schema, t)
+	return schema
+}
+
+func (s *schemas) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		// Untagged embedded structs have their fields promoted
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				s.addFields(schema, ft)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldSchema := s.forType(field.Type)
+		if strings.Contains(opts, "string") {
+			fieldSchema = &Schema{Type: "string"}
+		}
+		binding := field.Tag.Get("binding")
+		if strings.Contains(binding, "email") && fieldSchema.Type == "string" {
+			fieldSchema = &Schema{Type: "string", Format: "email"}
+		}
+		schema.Properties[name] = fieldSchema
+
+		if strings.Contains(binding, "required") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+func nullable(schema *Schema) *Schema {
+	if schema.Ref == "" && schema.Type != nil {
+		if typ, ok := schema.Type.(string); ok {
+			copied := *schema
+			copied.Type = []string{typ, "null"}
+			return &copied
+		}
+	}
+	return &Schema{AnyOf: []*Schema{schema, {Type: "null"}}}
+}
@@ -0,0 +1,304 @@
+// Package openapi builds an OpenAPI 3.1 document from the routes as they
+// are registered, with schemas derived from the Go request and response
+// types, so the document cannot describe endpoints that do not exist.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version is the OpenAPI version of generated documents.
+const Version = "3.1.0"
+
+// Operation describes an endpoint.
+type Operation struct {
+	Summary     string
+	Description string
+	Tags        []string
+	// Security names the security schemes any one of which authorizes a
+	// request. Leave it empty for public endpoints.
+	Security []string
+	// Scopes are the API key scopes the endpoint needs, added to the
+	// description.
+	Scopes     []string
+	Parameters []Parameter
+	// Request is a value of the JSON request body type, if any.
+	Request   interface{}
+	Responses []Response
+	// Deprecated marks endpoints that will be removed.
+	Deprecated bool
+}
+
+// Parameter is a path, query or header parameter. Path parameters in the
+// route are added as strings unless they are listed.
+type Parameter struct {
+	Name        string
+	In          string
+	Description string
+	Required    bool
+	// Schema is a value of the parameter's type; nil means string.
+	Schema interface{}
+}
+
+// Response is a possible response. A nil Body has no content.
+type Response struct {
+	Status      int
+	Description string
+	Body        interface{}
+	// Headers names response headers and describes them.
+	Headers map[string]string
+}
+
+// Document is the OpenAPI document.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps lower case HTTP methods to operations.
+type PathItem map[string]*OperationObject
+
+type OperationObject struct {
+	Summary     string                    `json:"summary,omitempty"`
+	Description string                    `json:"description,omitempty"`
+	OperationID string                    `json:"operationId"`
+	Tags        []string                  `json:"tags,omitempty"`
+	Parameters  []ParameterObject         `json:"parameters,omitempty"`
+	RequestBody *RequestBody              `json:"requestBody,omitempty"`
+	Responses   map[string]ResponseObject `json:"responses"`
+	Security    []map[string][]string     `json:"security,omitempty"`
+	Deprecated  bool                      `json:"deprecated,omitempty"`
+}
+
+type ParameterObject struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type ResponseObject struct {
+	Description string                  `json:"description"`
+	Headers     map[string]HeaderObject `json:"headers,omitempty"`
+	Content     map[string]MediaType    `json:"content,omitempty"`
+}
+
+type HeaderObject struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Description  string `json:"description,omitempty"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+}
+
+// Spec collects operations into a Document.
+type Spec struct {
+	doc     Document
+	schemas *schemas
+}
+
+// New returns an empty Spec.
+func New(info Info) *Spec {
+	s := &Spec{
+		doc: Document{
+			OpenAPI: Version,
+			Info:    info,
+			Paths:   make(map[string]PathItem),
+			Components: Components{
+				SecuritySchemes: make(map[string]SecurityScheme),
+			},
+		},
+		schemas: newSchemas(),
+	}
+	s.doc.Components.Schemas = s.schemas.components
+	return s
+}
+
+// AddSecurityScheme makes a security scheme available to operations.
+func (s *Spec) AddSecurityScheme(name string, scheme SecurityScheme) {
+	s.doc.Components.SecuritySchemes[name] = scheme
+}
+
+// SetSchema sets the schema used for the type of value, for types that
+// marshal themselves differently from their fields.
+func (s *Spec) SetSchema(value interface{}, schema *Schema) {
+	s.schemas.overrides[reflect.TypeOf(value)] = schema
+}
+
+// Add documents the route registered with gin as method and path.
+func (s *Spec) Add(method, path string, op Operation) {
+	openapiPath, pathParams := convertPath(path)
+
+	obj := &OperationObject{
+		Summary:     op.Summary,
+		Description: op.Description,
+		OperationID: operationID(method, path),
+		Tags:        op.Tags,
+		Responses:   make(map[string]ResponseObject),
+		Deprecated:  op.Deprecated,
+	}
+	if len(op.Scopes) > 0 {
+		if obj.Description != "" {
+			obj.Description += "\n\n"
+		}
+		obj.Description += "API keys need the scope `" + strings.Join(op.Scopes, "`, `") + "`."
+	}
+
+	listed := make(map[string]bool)
+	for _, p := range op.Parameters {
+		listed[p.In+":"+p.Name] = true
+		obj.Parameters = append(obj.Parameters, s.parameter(p))
+	}
+	for _, name := range pathParams {
+		if !listed["path:"+name] {
+			obj.Parameters = append(obj.Parameters, s.parameter(Parameter{Name: name, In: "path"}))
+		}
+	}
+
+	if op.Request != nil {
+		obj.RequestBody = &RequestBody{
+			Required: true,
+			Content:  jsonContent(s.schemas.forValue(op.Request)),
+		}
+	}
+
+	for _, r := range op.Responses {
+		resp := ResponseObject{Description: r.Description}
+		if resp.Description == "" {
+			resp.Description = http.StatusText(r.Status)
+		}
+		if r.Body != nil {
+			resp.Content = jsonContent(s.schemas.forValue(r.Body))
+		}
+		for name, description := range r.Headers {
+			if resp.Headers == nil {
+				resp.Headers = make(map[string]HeaderObject)
+			}
+			resp.Headers[name] = HeaderObject{Description: description, Schema: &Schema{Type: "string"}}
+		}
+		obj.Responses[strconv.Itoa(r.Status)] = resp
+	}
+
+	for _, name := range op.Security {
+		obj.Security = append(obj.Security, map[string][]string{name: {}})
+	}
+
+	item, ok := s.doc.Paths[openapiPath]
+	if !ok {
+		item = make(PathItem)
+		s.doc.Paths[openapiPath] = item
+	}
+	item[strings.ToLower(method)] = obj
+}
+
+// Document returns the document built so far.
+func (s *Spec) Document() *Document {
+	return &s.doc
+}
+
+// Routes returns the documented routes as "METHOD /gin/:path", sorted.
+func (s *Spec) Routes() []string {
+	var routes []string
+	for path, item := range s.doc.Paths {
+		for method := range item {
+			routes = append(routes, strings.ToUpper(method)+" "+ginPath(path))
+		}
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+// ServeJSON serves the document.
+func (s *Spec) ServeJSON(c *gin.Context) {
+	c.JSON(http.StatusOK, s.Document())
+}
+
+func (s *Spec) parameter(p Parameter) ParameterObject {
+	schema := &Schema{Type: "string"}
+	if p.Schema != nil {
+		schema = s.schemas.forValue(p.Schema)
+	}
+	return ParameterObject{
+		Name:        p.Name,
+		In:          p.In,
+		Description: p.Description,
+		Required:    p.Required || p.In == "path",
+		Schema:      schema,
+	}
+}
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
+
+// convertPath turns gin's /tasks/:id into /tasks/{id} and returns the names
+// of the path parameters.
+func convertPath(path string) (string, []string) {
+	segments := strings.Split(path, "/")
+	var params []string
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			params = append(params, segment[1:])
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// ginPath is the inverse of convertPath.
+func ginPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = ":" + segment[1:len(segment)-1]
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// operationID derives an ID like getApiTasksId from the route.
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
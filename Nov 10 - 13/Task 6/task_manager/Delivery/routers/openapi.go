@@ -0,0 +1,46 @@
+package routers
+
+import (
+	"net/http"
+
+	"task_manager/Delivery/controllers"
+	"task_manager/Delivery/openapi"
+	"task_manager/Domain"
+)
+
+// bearerAuth is the security scheme of protected routes
+const bearerAuth = "bearerAuth"
+
+// newSpec creates the OpenAPI document routes are added to
+func newSpec() *openapi.Spec {
+	spec := openapi.New(openapi.Info{
+		Title:   "Task Manager API",
+		Version: "1.0.0",
+	})
+
+	spec.AddSecurityScheme(bearerAuth, openapi.SecurityScheme{
+		Type:         "http",
+		Scheme:       "bearer",
+		BearerFormat: "JWT",
+		Description:  "An access token from login.",
+	})
+	spec.SetSchema(domain.Role(""), &openapi.Schema{Type: "string", Enum: []interface{}{domain.RoleAdmin, domain.RoleUser}})
+
+	return spec
+}
+
+// errorResponse documents an error response
+func errorResponse(status int, description string) openapi.Response {
+	return openapi.Response{Status: status, Description: description, Body: controllers.ErrorResponse{}}
+}
+
+// protected lists the responses of an authenticated route: the successful
+// one with body, the given errors, and those of the auth middleware
+func protected(status int, body interface{}, errors ...openapi.Response) []openapi.Response {
+	responses := []openapi.Response{{Status: status, Body: body}}
+	responses = append(responses, errors...)
+	return append(responses,
+		errorResponse(http.StatusUnauthorized, "Missing or invalid token"),
+		errorResponse(http.StatusForbidden, "Not allowed, e.g. email not verified or not an admin"),
+	)
+}
@@ -1,49 +1,184 @@
 package routers
 
 import (
+	"net/http"
+
 	"task_manager/Delivery/controllers"
+	"task_manager/Delivery/openapi"
 	"task_manager/Domain"
 	"task_manager/Infrastructure"
 
 	"github.com/gin-gonic/gin"
 )
 
-// SetupRouter configures the application routes
+// undocumentedRoutes are left out of the OpenAPI document on purpose.
+var undocumentedRoutes = []string{"GET /openapi.json", "GET /docs"}
+
+// SetupRouter configures the application routes. Routes are registered
+// through openapi.Group so they are documented at /openapi.json as they are
+// registered; the router tests check that no route is left out.
+// Task and admin routes look the user up in userRepo on every request, so
+// role changes and email verification apply at once.
 func SetupRouter(
 	taskController *controllers.TaskController,
 	userController *controllers.UserController,
 	jwtService *infrastructure.JWTService,
+	userRepo domain.UserRepository,
 ) *gin.Engine {
+	r, _ := setupRouter(taskController, userController, jwtService, userRepo)
+	return r
+}
+
+// setupRouter is SetupRouter, also returning the OpenAPI document.
+func setupRouter(
+	taskController *controllers.TaskController,
+	userController *controllers.UserController,
+	jwtService *infrastructure.JWTService,
+	userRepo domain.UserRepository,
+) (*gin.Engine, *openapi.Spec) {
 	r := gin.Default()
+	spec := newSpec()
+	root := openapi.NewGroup(&r.RouterGroup, spec)
+
+	root.Tags("Auth").GET("/.well-known/jwks.json", openapi.Operation{
+		Summary:     "Token signing keys",
+		Description: "The public keys access tokens can be verified with, as a JSON Web Key Set.",
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Body: infrastructure.JWKSet{}},
+		},
+	}, infrastructure.JWKSHandler(jwtService))
 
 	// Public routes
-	api := r.Group("/api")
+	api := root.Group("/api")
 	{
 		// User routes
-		userRoutes := api.Group("/users")
+		userRoutes := api.Group("/users").Tags("Users")
 		{
-			userRoutes.POST("/register", userController.Register)
-			userRoutes.POST("/login", userController.Login)
+			userRoutes.POST("/register", openapi.Operation{
+				Summary:     "Register",
+				Description: "Creates an unverified user and emails a verification link. The first user becomes an admin.",
+				Request:     controllers.RegisterRequest{},
+				Responses: []openapi.Response{
+					{Status: http.StatusCreated, Body: domain.User{}},
+					errorResponse(http.StatusBadRequest, "Invalid input or password too weak"),
+					errorResponse(http.StatusConflict, "Email already registered"),
+				},
+			}, userController.Register)
+			userRoutes.POST("/login", openapi.Operation{
+				Summary: "Log in",
+				Request: controllers.LoginRequest{},
+				Responses: []openapi.Response{
+					{Status: http.StatusOK, Body: controllers.LoginResponse{}},
+					errorResponse(http.StatusBadRequest, "Invalid input"),
+					errorResponse(http.StatusUnauthorized, "Invalid credentials"),
+				},
+			}, userController.Login)
+			userRoutes.POST("/verify", openapi.Operation{
+				Summary: "Verify an email address",
+				Request: controllers.TokenRequest{},
+				Responses: []openapi.Response{
+					{Status: http.StatusOK, Body: controllers.MessageResponse{}},
+					errorResponse(http.StatusBadRequest, "Invalid or expired token"),
+					errorResponse(http.StatusNotFound, "User not found"),
+				},
+			}, userController.VerifyEmail)
+			userRoutes.POST("/resend-verification", openapi.Operation{
+				Summary: "Resend the verification email",
+				Request: controllers.EmailRequest{},
+				Responses: []openapi.Response{
+					{Status: http.StatusAccepted, Body: controllers.MessageResponse{}},
+					errorResponse(http.StatusBadRequest, "Invalid input"),
+				},
+			}, userController.ResendVerification)
+			userRoutes.POST("/forgot-password", openapi.Operation{
+				Summary: "Request a password reset email",
+				Request: controllers.EmailRequest{},
+				Responses: []openapi.Response{
+					{Status: http.StatusAccepted, Body: controllers.MessageResponse{}},
+					errorResponse(http.StatusBadRequest, "Invalid input"),
+				},
+			}, userController.ForgotPassword)
+			userRoutes.POST("/reset-password", openapi.Operation{
+				Summary: "Reset a password",
+				Request: controllers.ResetPasswordRequest{},
+				Responses: []openapi.Response{
+					{Status: http.StatusOK, Body: controllers.MessageResponse{}},
+					errorResponse(http.StatusBadRequest, "Invalid or expired token, or password too weak"),
+					errorResponse(http.StatusNotFound, "User not found"),
+				},
+			}, userController.ResetPassword)
 
-			// Protected routes
-			authorized := userRoutes.Group("")
+			// Protected routes; unverified users can still see their profile
+			authorized := userRoutes.Group("").Security(bearerAuth)
 			authorized.Use(infrastructure.AuthMiddleware(jwtService))
 			{
-				authorized.GET("/profile", userController.GetProfile)
+				authorized.GET("/profile", openapi.Operation{
+					Summary:   "Get the current user",
+					Responses: protected(http.StatusOK, domain.User{}, errorResponse(http.StatusNotFound, "User not found")),
+				}, userController.GetProfile)
 			}
 		}
 
+		// Admin routes
+		adminRoutes := api.Group("/admin").Tags("Admin").Security(bearerAuth)
+		adminRoutes.Use(infrastructure.AuthMiddleware(jwtService))
+		adminRoutes.Use(infrastructure.LoadUser(userRepo))
+		adminRoutes.Use(infrastructure.RequireVerified())
+		adminRoutes.Use(infrastructure.AuthorizeRole(domain.RoleAdmin))
+		{
+			adminRoutes.GET("/users", openapi.Operation{
+				Summary:   "List users",
+				Responses: protected(http.StatusOK, []domain.User{}),
+			}, userController.ListUsers)
+			adminRoutes.PUT("/users/:id/promote", openapi.Operation{
+				Summary:   "Promote a user to admin",
+				Responses: protected(http.StatusOK, domain.User{}, errorResponse(http.StatusNotFound, "User not found")),
+			}, userController.PromoteUser)
+			adminRoutes.PUT("/users/:id/demote", openapi.Operation{
+				Summary: "Demote an admin to user",
+				Responses: protected(http.StatusOK, domain.User{},
+					errorResponse(http.StatusNotFound, "User not found"),
+					errorResponse(http.StatusConflict, "Admins cannot demote themselves")),
+			}, userController.DemoteUser)
+		}
+
 		// Task routes
-		taskRoutes := api.Group("/tasks")
+		taskRoutes := api.Group("/tasks").Tags("Tasks").Security(bearerAuth)
 		taskRoutes.Use(infrastructure.AuthMiddleware(jwtService))
+		taskRoutes.Use(infrastructure.LoadUser(userRepo))
+		taskRoutes.Use(infrastructure.RequireVerified())
 		{
-			taskRoutes.GET("", taskController.GetTasks)
-			taskRoutes.POST("", taskController.CreateTask)
-			taskRoutes.GET("/:id", taskController.GetTask)
-			taskRoutes.PUT("/:id", taskController.UpdateTask)
-			taskRoutes.DELETE("/:id", taskController.DeleteTask)
+			taskRoutes.GET("", openapi.Operation{
+				Summary:     "List tasks",
+				Description: "Users see their own tasks, admins see every task.",
+				Responses:   protected(http.StatusOK, []domain.Task{}),
+			}, taskController.GetTasks)
+			taskRoutes.POST("", openapi.Operation{
+				Summary:   "Create a task",
+				Request:   domain.Task{},
+				Responses: protected(http.StatusCreated, domain.Task{}, errorResponse(http.StatusBadRequest, "Invalid input or due date in the past")),
+			}, taskController.CreateTask)
+			taskRoutes.GET("/:id", openapi.Operation{
+				Summary:   "Get a task",
+				Responses: protected(http.StatusOK, domain.Task{}, errorResponse(http.StatusNotFound, "Task not found")),
+			}, taskController.GetTask)
+			taskRoutes.PUT("/:id", openapi.Operation{
+				Summary: "Update a task",
+				Request: domain.Task{},
+				Responses: protected(http.StatusOK, domain.Task{},
+					errorResponse(http.StatusBadRequest, "Invalid input"),
+					errorResponse(http.StatusNotFound, "Task not found")),
+			}, taskController.UpdateTask)
+			taskRoutes.DELETE("/:id", openapi.Operation{
+				Summary:   "Delete a task",
+				Responses: protected(http.StatusNoContent, nil, errorResponse(http.StatusNotFound, "Task not found")),
+			}, taskController.DeleteTask)
 		}
 	}
 
-	return r
+	// API documentation, which is in undocumentedRoutes
+	r.GET("/openapi.json", spec.ServeJSON)
+	r.GET("/docs", openapi.SwaggerUI(spec.Document().Info.Title, "/openapi.json"))
+
+	return r, spec
 }
@@ -2,6 +2,7 @@ package domain
 
 import (
 	"errors"
+	"strings"
 	"time"
 )
 
@@ -13,6 +14,30 @@ var (
 	ErrEmailAlreadyExists = errors.New("email already exists")
 	ErrInvalidInput       = errors.New("invalid input")
 	ErrInvalidDueDate     = errors.New("due date cannot be in the past")
+	ErrForbidden          = errors.New("forbidden")
+	ErrCannotDemoteSelf   = errors.New("admins cannot demote themselves")
+	ErrInvalidToken       = errors.New("invalid or expired token")
+	ErrEmailNotVerified   = errors.New("email not verified")
+)
+
+// WeakPasswordError lists the password policy rules a password breaks
+type WeakPasswordError struct {
+	Violations []string
+}
+
+// Error describes every violation
+func (e *WeakPasswordError) Error() string {
+	return "password does not meet the policy: " + strings.Join(e.Violations, "; ")
+}
+
+// Role determines what a user is allowed to do
+type Role string
+
+// Available roles. Users stored before roles existed have an empty role
+// and are treated as RoleUser.
+const (
+	RoleAdmin Role = "admin"
+	RoleUser  Role = "user"
 )
 
 // Task represents the core business entity for tasks
@@ -22,6 +47,18 @@ type Task struct {
 	Description string    `json:"description" bson:"description"`
 	DueDate     time.Time `json:"due_date" bson:"due_date"`
 	Status      string    `json:"status" bson:"status"`
+	OwnerID     string    `json:"owner_id" bson:"owner_id"`
+}
+
+// Actor identifies the authenticated user a use case acts on behalf of
+type Actor struct {
+	UserID string
+	Role   Role
+}
+
+// IsAdmin reports whether the actor has administrative rights
+func (a Actor) IsAdmin() bool {
+	return a.Role == RoleAdmin
 }
 
 // User represents the core business entity for users
@@ -30,15 +67,38 @@ type User struct {
 	Username string `json:"username" bson:"username"`
 	Email    string `json:"email" bson:"email"`
 	Password string `json:"-" bson:"password"`
+	Role     Role   `json:"role" bson:"role"`
+	Verified bool   `json:"verified" bson:"verified"`
 }
 
-// TaskRepository defines the interface for task data operations
+// TokenPurpose says what an ActionToken may be used for
+type TokenPurpose string
+
+// Token purposes
+const (
+	PurposeVerifyEmail   TokenPurpose = "verify_email"
+	PurposeResetPassword TokenPurpose = "reset_password"
+)
+
+// ActionToken is a single-use token emailed to a user. Its ID is a hash of
+// the token the user receives, never the token itself.
+type ActionToken struct {
+	ID        string       `bson:"_id"`
+	UserID    string       `bson:"user_id"`
+	Purpose   TokenPurpose `bson:"purpose"`
+	ExpiresAt time.Time    `bson:"expires_at"`
+}
+
+// TaskRepository defines the interface for task data operations. Every
+// query is scoped to the tasks of ownerID; a task owned by someone else is
+// reported as ErrTaskNotFound. An empty ownerID, used for admins, matches
+// the tasks of every owner.
 type TaskRepository interface {
-	GetAll() ([]Task, error)
-	GetByID(id string) (Task, error)
+	GetAll(ownerID string) ([]Task, error)
+	GetByID(id, ownerID string) (Task, error)
 	Create(task Task) (Task, error)
-	Update(id string, task Task) (Task, error)
-	Delete(id string) error
+	Update(id, ownerID string, task Task) (Task, error)
+	Delete(id, ownerID string) error
 }
 
 // UserRepository defines the interface for user data operations
@@ -46,15 +106,38 @@ type UserRepository interface {
 	Create(user User) (User, error)
 	GetByEmail(email string) (User, error)
 	GetByID(id string) (User, error)
+	GetAll() ([]User, error)
+	UpdateRole(id string, role Role) (User, error)
+	Count() (int64, error)
+	// ClaimFirstAdmin records userID as the bootstrap admin unless another
+	// user has been recorded already, and reports whether it did. Only one
+	// of several concurrent callers can succeed.
+	ClaimFirstAdmin(userID string) (bool, error)
+	MarkVerified(id string) error
+	UpdatePassword(id, hashedPassword string) error
+}
+
+// TokenRepository stores action tokens
+type TokenRepository interface {
+	Create(token ActionToken) error
+	// Find returns the unexpired token with the given ID and purpose without
+	// using it up, or ErrInvalidToken
+	Find(id string, purpose TokenPurpose) (ActionToken, error)
+	// Consume deletes and returns the unexpired token with the given ID and
+	// purpose, so it can only be used once. It returns ErrInvalidToken if
+	// there is no such token.
+	Consume(id string, purpose TokenPurpose) (ActionToken, error)
+	DeleteForUser(userID string, purpose TokenPurpose) error
 }
 
-// TaskUseCase defines the business logic for task operations
+// TaskUseCase defines the business logic for task operations. Actors only
+// see and change the tasks they own, except admins, who can manage any task.
 type TaskUseCase interface {
-	GetAllTasks() ([]Task, error)
-	GetTask(id string) (Task, error)
-	CreateTask(task Task) (Task, error)
-	UpdateTask(id string, task Task) (Task, error)
-	DeleteTask(id string) error
+	GetAllTasks(actor Actor) ([]Task, error)
+	GetTask(actor Actor, id string) (Task, error)
+	CreateTask(actor Actor, task Task) (Task, error)
+	UpdateTask(actor Actor, id string, task Task) (Task, error)
+	DeleteTask(actor Actor, id string) error
 }
 
 // UserUseCase defines the business logic for user operations
@@ -62,4 +145,13 @@ type UserUseCase interface {
 	Register(user User) (User, error)
 	Login(email, password string) (string, error)
 	GetUserProfile(id string) (User, error)
+	VerifyEmail(token string) error
+	ResendVerification(email string) error
+	ForgotPassword(email string) error
+	ResetPassword(token, newPassword string) error
+
+	// Admin operations; they return ErrForbidden for other actors
+	ListUsers(actor Actor) ([]User, error)
+	PromoteUser(actor Actor, id string) (User, error)
+	DemoteUser(actor Actor, id string) (User, error)
 }
@@ -0,0 +1,123 @@
+package infrastructure
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"task_manager/Domain"
+)
+
+// stubUsers answers GetByID from a map; other UserRepository methods are
+// not used by the middleware
+type stubUsers struct {
+	domain.UserRepository
+	users map[string]domain.User
+	err   error
+}
+
+func (s stubUsers) GetByID(id string) (domain.User, error) {
+	if s.err != nil {
+		return domain.User{}, s.err
+	}
+	user, ok := s.users[id]
+	if !ok {
+		return domain.User{}, domain.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func newTestJWTService(t *testing.T) *JWTService {
+	t.Helper()
+	keys, err := NewKeyRing(KeyRingConfig{
+		Algorithm:   AlgorithmEdDSA,
+		RotateEvery: 30 * 24 * time.Hour,
+		Overlap:     25 * time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwtService, err := NewJWTService(keys, "test", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jwtService
+}
+
+func TestLoadUserUsesCurrentRoleAndVerification(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtService := newTestJWTService(t)
+
+	// Tokens issued before each change to the stored user
+	tokenUser := domain.User{ID: "u1", Role: domain.RoleAdmin, Verified: false}
+	token, err := jwtService.GenerateToken(tokenUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		stored   map[string]domain.User
+		err      error
+		verified int
+		admin    int
+	}{
+		{
+			name:     "unchanged",
+			stored:   map[string]domain.User{"u1": tokenUser},
+			verified: http.StatusForbidden,
+			admin:    http.StatusForbidden,
+		},
+		{
+			name:     "verified since login",
+			stored:   map[string]domain.User{"u1": {ID: "u1", Role: domain.RoleAdmin, Verified: true}},
+			verified: http.StatusOK,
+			admin:    http.StatusOK,
+		},
+		{
+			name:     "demoted since login",
+			stored:   map[string]domain.User{"u1": {ID: "u1", Role: domain.RoleUser, Verified: true}},
+			verified: http.StatusOK,
+			admin:    http.StatusForbidden,
+		},
+		{
+			name:     "role unset",
+			stored:   map[string]domain.User{"u1": {ID: "u1", Verified: true}},
+			verified: http.StatusOK,
+			admin:    http.StatusForbidden,
+		},
+		{
+			name:     "deleted since login",
+			stored:   map[string]domain.User{},
+			verified: http.StatusUnauthorized,
+			admin:    http.StatusUnauthorized,
+		},
+		{
+			name:     "repository failure",
+			err:      errors.New("connection refused"),
+			verified: http.StatusInternalServerError,
+			admin:    http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			protected := r.Group("", AuthMiddleware(jwtService), LoadUser(stubUsers{users: tt.stored, err: tt.err}), RequireVerified())
+			protected.GET("/tasks", func(c *gin.Context) { c.Status(http.StatusOK) })
+			protected.GET("/admin", AuthorizeRole(domain.RoleAdmin), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			for path, want := range map[string]int{"/tasks": tt.verified, "/admin": tt.admin} {
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				req.Header.Set("Authorization", "Bearer "+token)
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				if w.Code != want {
+					t.Errorf("GET %s = %d, want %d; body %s", path, w.Code, want, w.Body)
+				}
+			}
+		})
+	}
+}
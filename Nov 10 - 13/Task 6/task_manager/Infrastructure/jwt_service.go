@@ -1,59 +1,122 @@
 package infrastructure
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"task_manager/Domain"
+)
+
+const (
+	// tokenTTL is how long access tokens are valid
+	tokenTTL = 24 * time.Hour
+	// clockSkew is how far apart server clocks may be
+	clockSkew = 30 * time.Second
 )
 
 // JWTService handles JWT token generation and validation
 type JWTService struct {
-	secretKey []byte
+	keys     *KeyRing
+	issuer   string
+	audience string
 }
 
-// NewJWTService creates a new JWTService with the given secret key
-func NewJWTService(secretKey string) *JWTService {
-	return &JWTService{
-		secretKey: []byte(secretKey),
+// NewJWTService creates a new JWTService that signs tokens with the current
+// key of keys. Replaced keys must stay valid for as long as the tokens they
+// signed, so the key overlap must be at least the token lifetime.
+func NewJWTService(keys *KeyRing, issuer, audience string) (*JWTService, error) {
+	if keys.Overlap() < tokenTTL {
+		return nil, fmt.Errorf("key overlap must be at least the token lifetime (%s)", tokenTTL)
 	}
+	return &JWTService{
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+	}, nil
 }
 
 // Claims represents the JWT claims structure
 type Claims struct {
-	UserID string `json:"user_id"`
+	UserID   string      `json:"user_id"`
+	Role     domain.Role `json:"role"`
+	Verified bool        `json:"verified"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates a new JWT token for the given user ID
-func (s *JWTService) GenerateToken(userID string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
+// GenerateToken generates a new JWT token for the given user
+func (s *JWTService) GenerateToken(user domain.User) (string, error) {
+	now := time.Now()
 	claims := &Claims{
-		UserID: userID,
+		UserID:   user.ID,
+		Role:     user.Role,
+		Verified: user.Verified,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    s.issuer,
+			Subject:   user.ID,
+			Audience:  jwt.ClaimStrings{s.audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(tokenTTL)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.secretKey)
+	key := s.keys.Current()
+	token := jwt.NewWithClaims(key.Method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.private)
 }
 
-// ValidateToken validates the JWT token and returns the user ID if valid
-func (s *JWTService) ValidateToken(tokenString string) (string, error) {
+// ValidateToken validates the JWT token and returns its claims if valid
+func (s *JWTService) ValidateToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return s.secretKey, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.verificationKey,
+		jwt.WithValidMethods([]string{AlgorithmRS256, AlgorithmEdDSA}),
+		jwt.WithIssuer(s.issuer),
+		jwt.WithAudience(s.audience),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if !token.Valid {
-		return "", jwt.ErrSignatureInvalid
+		return nil, jwt.ErrSignatureInvalid
+	}
+
+	// exp and nbf are only checked when present, and all tokens we issue
+	// have them
+	if claims.ExpiresAt == nil || claims.NotBefore == nil {
+		return nil, jwt.ErrTokenRequiredClaimMissing
+	}
+
+	// Tokens issued before roles existed carry no role
+	if claims.Role == "" {
+		claims.Role = domain.RoleUser
 	}
 
-	return claims.UserID, nil
+	return claims, nil
+}
+
+// JWKS returns the public keys tokens can be verified with
+func (s *JWTService) JWKS() JWKSet {
+	return s.keys.JWKS()
+}
+
+// verificationKey picks the key named by the token's kid header, and only
+// if the token claims the algorithm that key is for
+func (s *JWTService) verificationKey(token *jwt.Token) (interface{}, error) {
+	id, _ := token.Header["kid"].(string)
+	key, ok := s.keys.Lookup(id)
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, errors.New("signing algorithm does not match key")
+	}
+	return key.Public(), nil
 }
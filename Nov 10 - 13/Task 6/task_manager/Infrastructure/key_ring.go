@@ -0,0 +1,419 @@
+package infrastructure
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+const rsaKeyBits = 2048
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// KeyRingConfig configures a KeyRing
+type KeyRingConfig struct {
+	// Algorithm is the algorithm new keys are generated for
+	Algorithm string
+	// Dir is where keys are kept as PEM files, one per key. With an empty
+	// Dir keys only live in memory and every restart logs all users out.
+	Dir string
+	// RotateEvery is how long a key is used for signing
+	RotateEvery time.Duration
+	// Overlap is how long a new key is published before it is used, and
+	// how long a replaced key is still accepted. It must be at least the
+	// token lifetime and longer than clients cache the JWKS.
+	Overlap time.Duration
+}
+
+// SigningKey is a key that signs tokens from ActiveAt until a newer key
+// becomes active
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	ActiveAt  time.Time
+	private   crypto.Signer
+}
+
+// Public returns the key tokens are verified with
+func (k *SigningKey) Public() crypto.PublicKey {
+	return k.private.Public()
+}
+
+// Method returns the JWT signing method for the key
+func (k *SigningKey) Method() jwt.SigningMethod {
+	if k.Algorithm == AlgorithmEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// KeyRing holds the keys tokens are signed and verified with and rotates
+// them on a schedule. The key in use is replaced every RotateEvery; its
+// successor is published Overlap before it takes over, and the old key is
+// still accepted for Overlap afterwards.
+type KeyRing struct {
+	mu   sync.RWMutex
+	cfg  KeyRingConfig
+	keys []*SigningKey // sorted by ActiveAt
+	now  func() time.Time
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewKeyRing loads the keys in cfg.Dir and generates a key if none can be
+// used
+func NewKeyRing(cfg KeyRingConfig) (*KeyRing, error) {
+	if cfg.Algorithm != AlgorithmRS256 && cfg.Algorithm != AlgorithmEdDSA {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", cfg.Algorithm)
+	}
+	if cfg.Overlap <= 0 || cfg.RotateEvery <= cfg.Overlap {
+		return nil, errors.New("key rotation interval must be longer than the overlap")
+	}
+
+	r := &KeyRing{cfg: cfg, now: time.Now}
+	if cfg.Dir != "" {
+		if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+			return nil, err
+		}
+		if err := r.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.maintain(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Overlap returns how long replaced keys are still accepted
+func (r *KeyRing) Overlap() time.Duration {
+	return r.cfg.Overlap
+}
+
+// Current returns the key new tokens are signed with
+func (r *KeyRing) Current() *SigningKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.keys[r.activeIndex()]
+}
+
+// Lookup returns the key with the given ID if it is still accepted
+func (r *KeyRing) Lookup(id string) (*SigningKey, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, k := range r.keys {
+		if k.ID == id {
+			return k, true
+		}
+	}
+	return nil, false
+}
+
+// JWKS returns the public keys of all keys that are published or still
+// accepted
+func (r *KeyRing) JWKS() JWKSet {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	set := JWKSet{Keys: make([]JWK, 0, len(r.keys))}
+	for _, k := range r.keys {
+		jwk := JWK{KeyID: k.ID, Use: "sig", Algorithm: k.Algorithm}
+		switch pub := k.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// Rotate replaces the current key right away, without publishing the new
+// key first. Use it when a key may have leaked; remove the leaked key's
+// file as well so it stops being accepted.
+func (r *KeyRing) Rotate() (*SigningKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, err := r.addKey(r.now())
+	if err != nil {
+		return nil, err
+	}
+	r.prune()
+	return key, nil
+}
+
+// Start rotates keys in the background until Close is called
+func (r *KeyRing) Start() {
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	go r.rotateLoop()
+}
+
+// Close stops background rotation
+func (r *KeyRing) Close() {
+	if r.stop == nil {
+		return
+	}
+	close(r.stop)
+	<-r.done
+}
+
+func (r *KeyRing) rotateLoop() {
+	defer close(r.done)
+
+	// Check often enough that the next key is published well within the
+	// overlap
+	interval := r.cfg.Overlap / 4
+	if interval > time.Hour {
+		interval = time.Hour
+	}
+	if interval < time.Minute {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.maintain(); err != nil {
+				log.Printf("Failed to rotate signing keys: %v", err)
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// maintain publishes the next key when the current one is due for
+// replacement and drops keys that are no longer accepted
+func (r *KeyRing) maintain() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if len(r.keys) == 0 {
+		_, err := r.addKey(now)
+		return err
+	}
+
+	active := r.activeIndex()
+	current := r.keys[active]
+	pending := active < len(r.keys)-1
+	due := !now.Before(current.ActiveAt.Add(r.cfg.RotateEvery - r.cfg.Overlap))
+	if !pending && (due || current.Algorithm != r.cfg.Algorithm) {
+		if _, err := r.addKey(now.Add(r.cfg.Overlap)); err != nil {
+			return err
+		}
+	}
+
+	r.prune()
+	return nil
+}
+
+// activeIndex returns the index of the newest key that is already active,
+// or of the oldest key if none is
+func (r *KeyRing) activeIndex() int {
+	now := r.now()
+	i := sort.Search(len(r.keys), func(i int) bool {
+		return r.keys[i].ActiveAt.After(now)
+	})
+	if i == 0 {
+		return 0
+	}
+	return i - 1
+}
+
+// prune drops keys that were replaced more than Overlap ago
+func (r *KeyRing) prune() {
+	cutoff := r.now().Add(-r.cfg.Overlap)
+	drop := 0
+	for drop < len(r.keys)-1 && !r.keys[drop+1].ActiveAt.After(cutoff) {
+		drop++
+	}
+
+	for _, k := range r.keys[:drop] {
+		if r.cfg.Dir == "" {
+			continue
+		}
+		if err := os.Remove(r.keyPath(k.ID)); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to remove retired signing key %s: %v", k.ID, err)
+		}
+	}
+	r.keys = r.keys[drop:]
+}
+
+// addKey generates a key that becomes active at activeAt and saves it
+func (r *KeyRing) addKey(activeAt time.Time) (*SigningKey, error) {
+	key, err := generateSigningKey(r.cfg.Algorithm, activeAt)
+	if err != nil {
+		return nil, err
+	}
+	if r.cfg.Dir != "" {
+		if err := r.save(key); err != nil {
+			return nil, err
+		}
+	}
+
+	r.keys = append(r.keys, key)
+	sort.SliceStable(r.keys, func(i, j int) bool {
+		return r.keys[i].ActiveAt.Before(r.keys[j].ActiveAt)
+	})
+	return key, nil
+}
+
+func (r *KeyRing) keyPath(id string) string {
+	return filepath.Join(r.cfg.Dir, id+".pem")
+}
+
+// save writes key as a PKCS #8 PEM file. The file's modification time
+// records when the key becomes active.
+func (r *KeyRing) save(key *SigningKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key.private)
+	if err != nil {
+		return err
+	}
+
+	path := r.keyPath(key.ID)
+	tmp := path + ".tmp"
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Chtimes(tmp, key.ActiveAt, key.ActiveAt); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// load reads the keys saved in the key directory
+func (r *KeyRing) load() error {
+	entries, err := os.ReadDir(r.cfg.Dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".pem")
+		if !ok || entry.IsDir() || !keyIDPattern.MatchString(id) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(r.keyPath(id))
+		if err != nil {
+			return err
+		}
+		key, err := parseSigningKey(id, data, info.ModTime())
+		if err != nil {
+			return fmt.Errorf("signing key %s: %w", id, err)
+		}
+		r.keys = append(r.keys, key)
+	}
+
+	sort.SliceStable(r.keys, func(i, j int) bool {
+		return r.keys[i].ActiveAt.Before(r.keys[j].ActiveAt)
+	})
+	return nil
+}
+
+func generateSigningKey(algorithm string, activeAt time.Time) (*SigningKey, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	var private crypto.Signer
+	var err error
+	if algorithm == AlgorithmEdDSA {
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	} else {
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &SigningKey{
+		ID:        hex.EncodeToString(id),
+		Algorithm: algorithm,
+		ActiveAt:  activeAt,
+		private:   private,
+	}, nil
+}
+
+func parseSigningKey(id string, data []byte, activeAt time.Time) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.New("not a PKCS #8 PEM private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{ID: id, ActiveAt: activeAt}
+	switch private := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Algorithm = AlgorithmRS256
+		key.private = private
+	case ed25519.PrivateKey:
+		key.Algorithm = AlgorithmEdDSA
+		key.private = private
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+	return key, nil
+}
@@ -0,0 +1,135 @@
+package infrastructure
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"task_manager/Domain"
+)
+
+// PasswordPolicy defines the rules new passwords must follow
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int // 0 for no limit
+
+	// MinCharacterClasses is how many of lower case, upper case, digits and
+	// symbols must appear
+	MinCharacterClasses int
+
+	// Blocklist holds common passwords. A password is rejected if it equals
+	// an entry, ignoring case, either as typed or with trailing digits and
+	// symbols removed ("Dragon2024!" matches "dragon").
+	Blocklist []string
+
+	// ForbidIdentity rejects passwords containing the username, the email
+	// or the part of the email before the @
+	ForbidIdentity bool
+}
+
+// DefaultPasswordPolicy is the policy used by NewPasswordService
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:           10,
+	MaxLength:           128,
+	MinCharacterClasses: 3,
+	Blocklist:           commonPasswords,
+	ForbidIdentity:      true,
+}
+
+// Validate checks password against the policy. It returns a
+// *domain.WeakPasswordError listing every rule that is broken.
+func (p PasswordPolicy) Validate(password, username, email string) error {
+	var violations []string
+
+	// Check the length in characters, not bytes
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d characters long", p.MaxLength))
+	}
+
+	// Check the mix of character classes
+	if countCharacterClasses(password) < p.MinCharacterClasses {
+		violations = append(violations, fmt.Sprintf(
+			"must contain at least %d of: lower case letters, upper case letters, digits, symbols",
+			p.MinCharacterClasses,
+		))
+	}
+
+	// Check the blocklist
+	lower := strings.ToLower(password)
+	stem := strings.TrimRightFunc(lower, func(r rune) bool { return !unicode.IsLetter(r) })
+	for _, common := range p.Blocklist {
+		if lower == common || stem == common {
+			violations = append(violations, "is too common")
+			break
+		}
+	}
+
+	// Check for the user's own details
+	if p.ForbidIdentity && containsIdentity(lower, username, email) {
+		violations = append(violations, "must not contain your username or email address")
+	}
+
+	if len(violations) > 0 {
+		return &domain.WeakPasswordError{Violations: violations}
+	}
+	return nil
+}
+
+// countCharacterClasses counts how many kinds of characters password uses
+func countCharacterClasses(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
+
+// containsIdentity reports whether the lower-cased password contains the
+// username, the email or its local part. Values under three characters are
+// ignored since they match too many passwords by chance.
+func containsIdentity(password, username, email string) bool {
+	email = strings.ToLower(email)
+	localPart, _, _ := strings.Cut(email, "@")
+
+	for _, identity := range []string{strings.ToLower(username), email, localPart} {
+		if utf8.RuneCountInString(identity) >= 3 && strings.Contains(password, identity) {
+			return true
+		}
+	}
+	return false
+}
+
+// commonPasswords is a short list of the most frequently leaked passwords
+// and password stems
+var commonPasswords = strings.Fields(`
+	123456 1234567 12345678 123456789 1234567890 0123456789 111111 000000
+	121212 123123 654321 666666 696969 112233 123321 987654321 11111111
+	password passw0rd p@ssw0rd p@ssword pass password1 qwerty qwertyuiop
+	qwerty123 asdfgh asdfghjkl zxcvbnm zxcvbn 1q2w3e4r 1q2w3e4r5t qazwsx
+	1qaz2wsx abc123 abcd1234 abcdef aa123456 a123456 iloveyou letmein
+	welcome welcome1 admin administrator root toor changeme default guest
+	login master secret trustno1 monkey dragon football baseball soccer
+	hockey basketball superman batman starwars pokemon princess sunshine
+	shadow michael jordan jennifer hunter killer charlie freedom whatever
+	computer internet samsung google apple summer winter spring autumn
+	flower cookie chocolate cheese pepper ginger butterfly mustang ferrari
+	liverpool chelsea arsenal barcelona madrid matrix hello hello123
+	loveme lovely love123 mypass mypassword passpass test test123 testing
+	temp temp123 user user123 demo demo123 service support qwer1234 azerty
+	solo access blahblah ninja tigger buster thomas robert daniel andrew
+	joshua ashley jessica nicole amanda taylor austin harley ranger
+`)
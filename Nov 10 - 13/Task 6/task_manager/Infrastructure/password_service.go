@@ -1,27 +1,167 @@
 package infrastructure
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// PasswordService handles password hashing and verification
-type PasswordService struct{}
+// ErrPasswordMismatch is returned when a password does not match its hash
+var ErrPasswordMismatch = errors.New("password does not match")
+
+// ErrInvalidHash is returned for stored hashes that cannot be parsed
+var ErrInvalidHash = errors.New("invalid password hash")
+
+// Argon2Params are the argon2id cost settings for new hashes
+type Argon2Params struct {
+	Memory      uint32 // in KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params follow the RFC 9106 recommendation for memory-
+// constrained environments
+var DefaultArgon2Params = Argon2Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 4,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// PasswordService handles password policy, hashing and verification
+type PasswordService struct {
+	policy PasswordPolicy
+	params Argon2Params
+}
 
-// NewPasswordService creates a new PasswordService
+// NewPasswordService creates a PasswordService with the default policy and
+// argon2id parameters
 func NewPasswordService() *PasswordService {
-	return &PasswordService{}
+	return NewPasswordServiceWithConfig(DefaultPasswordPolicy, DefaultArgon2Params)
+}
+
+// NewPasswordServiceWithConfig creates a PasswordService with a custom
+// policy and argon2id parameters
+func NewPasswordServiceWithConfig(policy PasswordPolicy, params Argon2Params) *PasswordService {
+	return &PasswordService{
+		policy: policy,
+		params: params,
+	}
+}
+
+// ValidatePassword checks a new password against the policy
+func (s *PasswordService) ValidatePassword(password, username, email string) error {
+	return s.policy.Validate(password, username, email)
 }
 
-// HashPassword hashes a password using bcrypt
+// HashPassword hashes a password using argon2id. The result is encoded as
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>
+// so the parameters travel with the hash.
 func (s *PasswordService) HashPassword(password string) (string, error) {
-	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
+	salt := make([]byte, s.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
 		return "", err
 	}
-	return string(hashedBytes), nil
+
+	key := argon2.IDKey([]byte(password), salt, s.params.Iterations, s.params.Memory, s.params.Parallelism, s.params.KeyLength)
+
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		s.params.Memory,
+		s.params.Iterations,
+		s.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
 }
 
-// ComparePasswords compares a hashed password with a plain text password
+// ComparePasswords compares a hashed password with a plain text password.
+// Both argon2id hashes and bcrypt hashes created before the switch to
+// argon2id are accepted.
 func (s *PasswordService) ComparePasswords(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	if isBcryptHash(hashedPassword) {
+		return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	}
+
+	params, salt, key, err := decodeArgon2Hash(hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	if subtle.ConstantTimeCompare(key, computed) != 1 {
+		return ErrPasswordMismatch
+	}
+
+	return nil
+}
+
+// NeedsRehash reports whether a stored hash uses bcrypt or argon2id
+// parameters different from the service's, and should be replaced
+func (s *PasswordService) NeedsRehash(hashedPassword string) bool {
+	if isBcryptHash(hashedPassword) {
+		return true
+	}
+
+	params, _, _, err := decodeArgon2Hash(hashedPassword)
+	if err != nil {
+		return true
+	}
+
+	return params != s.params
+}
+
+// isBcryptHash reports whether hash was produced by bcrypt
+func isBcryptHash(hash string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeArgon2Hash parses an encoded argon2id hash
+func decodeArgon2Hash(hash string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil || params.Memory == 0 || params.Iterations == 0 || params.Parallelism == 0 {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
 }
@@ -17,23 +17,52 @@ type TaskRepositoryMongo struct {
 
 // NewTaskRepositoryMongo creates a new TaskRepositoryMongo instance
 func NewTaskRepositoryMongo(db *mongo.Database) *TaskRepositoryMongo {
-	return &TaskRepositoryMongo{
+	repo := &TaskRepositoryMongo{
 		collection: db.Collection("tasks"),
 	}
+
+	// Create index on owner_id, which every query filters by
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := repo.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "owner_id", Value: 1}},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return repo
 }
 
-// GetAll retrieves all tasks from the database
-func (r *TaskRepositoryMongo) GetAll() ([]domain.Task, error) {
+// ownerFilter matches the tasks of ownerID, or every task if it is empty
+func ownerFilter(ownerID string) bson.M {
+	if ownerID == "" {
+		return bson.M{}
+	}
+	return bson.M{"owner_id": ownerID}
+}
+
+// taskFilter matches the task with the given ID within ownerFilter. Task
+// IDs are stored as the hex string of a generated ObjectID.
+func taskFilter(id, ownerID string) bson.M {
+	filter := ownerFilter(ownerID)
+	filter["_id"] = id
+	return filter
+}
+
+// GetAll retrieves the tasks owned by ownerID, or all tasks if it is empty
+func (r *TaskRepositoryMongo) GetAll(ownerID string) ([]domain.Task, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	cursor, err := r.collection.Find(ctx, bson.M{})
+	cursor, err := r.collection.Find(ctx, ownerFilter(ownerID))
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	var tasks []domain.Task
+	tasks := []domain.Task{}
 	if err := cursor.All(ctx, &tasks); err != nil {
 		return nil, err
 	}
@@ -42,17 +71,16 @@ func (r *TaskRepositoryMongo) GetAll() ([]domain.Task, error) {
 }
 
 // GetByID retrieves a task by its ID
-func (r *TaskRepositoryMongo) GetByID(id string) (domain.Task, error) {
+func (r *TaskRepositoryMongo) GetByID(id, ownerID string) (domain.Task, error) {
 	var task domain.Task
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return task, err
+	if !primitive.IsValidObjectID(id) {
+		return task, domain.ErrTaskNotFound
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&task)
+	err := r.collection.FindOne(ctx, taskFilter(id, ownerID)).Decode(&task)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return task, domain.ErrTaskNotFound
@@ -66,7 +94,11 @@ func (r *TaskRepositoryMongo) GetByID(id string) (domain.Task, error) {
 // Create adds a new task to the database
 func (r *TaskRepositoryMongo) Create(task domain.Task) (domain.Task, error) {
 	task.ID = primitive.NewObjectID().Hex()
-	_, err := r.collection.InsertOne(context.Background(), task)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, task)
 	if err != nil {
 		return domain.Task{}, err
 	}
@@ -75,10 +107,9 @@ func (r *TaskRepositoryMongo) Create(task domain.Task) (domain.Task, error) {
 }
 
 // Update modifies an existing task in the database
-func (r *TaskRepositoryMongo) Update(id string, task domain.Task) (domain.Task, error) {
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return domain.Task{}, err
+func (r *TaskRepositoryMongo) Update(id, ownerID string, task domain.Task) (domain.Task, error) {
+	if !primitive.IsValidObjectID(id) {
+		return domain.Task{}, domain.ErrTaskNotFound
 	}
 
 	update := bson.M{
@@ -93,29 +124,29 @@ func (r *TaskRepositoryMongo) Update(id string, task domain.Task) (domain.Task,
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	_, err = r.collection.UpdateByID(ctx, objectID, update)
+	result, err := r.collection.UpdateOne(ctx, taskFilter(id, ownerID), update)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return domain.Task{}, domain.ErrTaskNotFound
-		}
 		return domain.Task{}, err
 	}
 
+	if result.MatchedCount == 0 {
+		return domain.Task{}, domain.ErrTaskNotFound
+	}
+
 	task.ID = id
 	return task, nil
 }
 
 // Delete removes a task from the database
-func (r *TaskRepositoryMongo) Delete(id string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return err
+func (r *TaskRepositoryMongo) Delete(id, ownerID string) error {
+	if !primitive.IsValidObjectID(id) {
+		return domain.ErrTaskNotFound
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	result, err := r.collection.DeleteOne(ctx, taskFilter(id, ownerID))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"task_manager/Domain"
+)
+
+// TokenRepositoryMongo implements the TokenRepository interface for MongoDB
+type TokenRepositoryMongo struct {
+	collection *mongo.Collection
+}
+
+// NewTokenRepositoryMongo creates a new TokenRepositoryMongo instance
+func NewTokenRepositoryMongo(db *mongo.Database) *TokenRepositoryMongo {
+	repo := &TokenRepositoryMongo{
+		collection: db.Collection("tokens"),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// MongoDB removes expired tokens itself through the TTL index; Consume
+	// still checks the expiry because the cleanup runs only once a minute
+	_, err := repo.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "purpose", Value: 1}},
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return repo
+}
+
+// Create stores a new token
+func (r *TokenRepositoryMongo) Create(token domain.ActionToken) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.InsertOne(ctx, token)
+	return err
+}
+
+// validTokenFilter matches an unexpired token with the given ID and purpose
+func validTokenFilter(id string, purpose domain.TokenPurpose) bson.M {
+	return bson.M{
+		"_id":        id,
+		"purpose":    purpose,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+}
+
+// Find retrieves a valid token without using it up
+func (r *TokenRepositoryMongo) Find(id string, purpose domain.TokenPurpose) (domain.ActionToken, error) {
+	var token domain.ActionToken
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := r.collection.FindOne(ctx, validTokenFilter(id, purpose)).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return token, domain.ErrInvalidToken
+		}
+		return token, err
+	}
+
+	return token, nil
+}
+
+// Consume atomically deletes and returns a valid token
+func (r *TokenRepositoryMongo) Consume(id string, purpose domain.TokenPurpose) (domain.ActionToken, error) {
+	var token domain.ActionToken
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := r.collection.FindOneAndDelete(ctx, validTokenFilter(id, purpose)).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return token, domain.ErrInvalidToken
+		}
+		return token, err
+	}
+
+	return token, nil
+}
+
+// DeleteForUser removes every token of a user issued for purpose
+func (r *TokenRepositoryMongo) DeleteForUser(userID string, purpose domain.TokenPurpose) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID, "purpose": purpose})
+	return err
+}
@@ -14,12 +14,14 @@ import (
 // UserRepositoryMongo implements the UserRepository interface for MongoDB
 type UserRepositoryMongo struct {
 	collection *mongo.Collection
+	bootstrap  *mongo.Collection
 }
 
 // NewUserRepositoryMongo creates a new UserRepositoryMongo instance
 func NewUserRepositoryMongo(db *mongo.Database) *UserRepositoryMongo {
 	repo := &UserRepositoryMongo{
 		collection: db.Collection("users"),
+		bootstrap:  db.Collection("bootstrap"),
 	}
 
 	// Create unique index on email
@@ -70,12 +72,50 @@ func (r *UserRepositoryMongo) GetByEmail(email string) (domain.User, error) {
 func (r *UserRepositoryMongo) GetByID(id string) (domain.User, error) {
 	var user domain.User
 
-	objectID, err := primitive.ObjectIDFromHex(id)
+	// User IDs are stored as the hex string of a generated ObjectID
+	err := r.collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&user)
 	if err != nil {
-		return user, err
+		if err == mongo.ErrNoDocuments {
+			return domain.User{}, domain.ErrUserNotFound
+		}
+		return domain.User{}, err
 	}
 
-	err = r.collection.FindOne(context.Background(), bson.M{"_id": objectID}).Decode(&user)
+	return user, nil
+}
+
+// GetAll retrieves every user, oldest first
+func (r *UserRepositoryMongo) GetAll() ([]domain.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	users := []domain.User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// UpdateRole sets a user's role and returns the updated user
+func (r *UserRepositoryMongo) UpdateRole(id string, role domain.Role) (domain.User, error) {
+	var user domain.User
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"role": role}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return domain.User{}, domain.ErrUserNotFound
@@ -85,3 +125,55 @@ func (r *UserRepositoryMongo) GetByID(id string) (domain.User, error) {
 
 	return user, nil
 }
+
+// Count returns the number of registered users
+func (r *UserRepositoryMongo) Count() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return r.collection.CountDocuments(ctx, bson.M{})
+}
+
+// ClaimFirstAdmin inserts the bootstrap admin marker. Its fixed _id makes
+// the insert fail for everyone but the first caller.
+func (r *UserRepositoryMongo) ClaimFirstAdmin(userID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := r.bootstrap.InsertOne(ctx, bson.M{"_id": "first_admin", "user_id": userID})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// MarkVerified records that a user has confirmed their email address
+func (r *UserRepositoryMongo) MarkVerified(id string) error {
+	return r.set(id, bson.M{"verified": true})
+}
+
+// UpdatePassword replaces a user's password hash
+func (r *UserRepositoryMongo) UpdatePassword(id, hashedPassword string) error {
+	return r.set(id, bson.M{"password": hashedPassword})
+}
+
+// set updates fields of a user
+func (r *UserRepositoryMongo) set(id string, fields bson.M) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": fields})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
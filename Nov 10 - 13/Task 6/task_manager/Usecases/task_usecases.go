@@ -18,9 +18,22 @@ func NewTaskUseCase(taskRepo domain.TaskRepository) *TaskUseCaseImpl {
 	}
 }
 
-// GetAllTasks retrieves all tasks
-func (uc *TaskUseCaseImpl) GetAllTasks() ([]domain.Task, error) {
-	tasks, err := uc.taskRepo.GetAll()
+// scope returns the owner filter for the actor's repository queries: their
+// own ID, or no filter at all for admins
+func scope(actor domain.Actor) string {
+	if actor.IsAdmin() {
+		return ""
+	}
+	return actor.UserID
+}
+
+// GetAllTasks retrieves all tasks the actor can manage
+func (uc *TaskUseCaseImpl) GetAllTasks(actor domain.Actor) ([]domain.Task, error) {
+	if actor.UserID == "" {
+		return nil, domain.ErrForbidden
+	}
+
+	tasks, err := uc.taskRepo.GetAll(scope(actor))
 	if err != nil {
 		return nil, err
 	}
@@ -28,9 +41,13 @@ func (uc *TaskUseCaseImpl) GetAllTasks() ([]domain.Task, error) {
 	return tasks, nil
 }
 
-// GetTask retrieves a task by ID
-func (uc *TaskUseCaseImpl) GetTask(id string) (domain.Task, error) {
-	task, err := uc.taskRepo.GetByID(id)
+// GetTask retrieves a task the actor can manage by ID
+func (uc *TaskUseCaseImpl) GetTask(actor domain.Actor, id string) (domain.Task, error) {
+	if actor.UserID == "" {
+		return domain.Task{}, domain.ErrForbidden
+	}
+
+	task, err := uc.taskRepo.GetByID(id, scope(actor))
 	if err != nil {
 		return domain.Task{}, err
 	}
@@ -38,8 +55,12 @@ func (uc *TaskUseCaseImpl) GetTask(id string) (domain.Task, error) {
 	return task, nil
 }
 
-// CreateTask creates a new task
-func (uc *TaskUseCaseImpl) CreateTask(task domain.Task) (domain.Task, error) {
+// CreateTask creates a new task owned by the actor
+func (uc *TaskUseCaseImpl) CreateTask(actor domain.Actor, task domain.Task) (domain.Task, error) {
+	if actor.UserID == "" {
+		return domain.Task{}, domain.ErrForbidden
+	}
+
 	// Validate required fields
 	if task.Title == "" || task.Status == "" {
 		return domain.Task{}, domain.ErrInvalidInput
@@ -50,6 +71,9 @@ func (uc *TaskUseCaseImpl) CreateTask(task domain.Task) (domain.Task, error) {
 		return domain.Task{}, domain.ErrInvalidDueDate
 	}
 
+	// The owner always comes from the actor, never from the request
+	task.OwnerID = actor.UserID
+
 	// Create the task
 	createdTask, err := uc.taskRepo.Create(task)
 	if err != nil {
@@ -59,19 +83,24 @@ func (uc *TaskUseCaseImpl) CreateTask(task domain.Task) (domain.Task, error) {
 	return createdTask, nil
 }
 
-// UpdateTask updates an existing task
-func (uc *TaskUseCaseImpl) UpdateTask(id string, task domain.Task) (domain.Task, error) {
+// UpdateTask updates a task the actor can manage
+func (uc *TaskUseCaseImpl) UpdateTask(actor domain.Actor, id string, task domain.Task) (domain.Task, error) {
+	if actor.UserID == "" {
+		return domain.Task{}, domain.ErrForbidden
+	}
+
 	// Check if task exists
-	existingTask, err := uc.taskRepo.GetByID(id)
+	existingTask, err := uc.taskRepo.GetByID(id, scope(actor))
 	if err != nil {
 		return domain.Task{}, err
 	}
 
-	// Update fields
+	// Update fields, keeping the ID and owner even when an admin edits it
 	task.ID = existingTask.ID
+	task.OwnerID = existingTask.OwnerID
 
 	// Update the task
-	updatedTask, err := uc.taskRepo.Update(id, task)
+	updatedTask, err := uc.taskRepo.Update(id, scope(actor), task)
 	if err != nil {
 		return domain.Task{}, err
 	}
@@ -79,16 +108,14 @@ func (uc *TaskUseCaseImpl) UpdateTask(id string, task domain.Task) (domain.Task,
 	return updatedTask, nil
 }
 
-// DeleteTask deletes a task by ID
-func (uc *TaskUseCaseImpl) DeleteTask(id string) error {
-	// Check if task exists
-	_, err := uc.taskRepo.GetByID(id)
-	if err != nil {
-		return err
+// DeleteTask deletes a task the actor can manage by ID
+func (uc *TaskUseCaseImpl) DeleteTask(actor domain.Actor, id string) error {
+	if actor.UserID == "" {
+		return domain.ErrForbidden
 	}
 
-	// Delete the task
-	err = uc.taskRepo.Delete(id)
+	// Delete the task; the owner filter makes other users' tasks not found
+	err := uc.taskRepo.Delete(id, scope(actor))
 	if err != nil {
 		return err
 	}
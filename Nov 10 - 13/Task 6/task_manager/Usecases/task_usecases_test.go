@@ -0,0 +1,162 @@
+package usecases
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"task_manager/Domain"
+)
+
+// fakeTaskRepo is an in-memory domain.TaskRepository that scopes queries
+// by owner like the MongoDB repository
+type fakeTaskRepo struct {
+	mu     sync.Mutex
+	tasks  []domain.Task
+	nextID int
+}
+
+func ownedBy(task domain.Task, ownerID string) bool {
+	return ownerID == "" || task.OwnerID == ownerID
+}
+
+func (r *fakeTaskRepo) GetAll(ownerID string) ([]domain.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tasks := []domain.Task{}
+	for _, task := range r.tasks {
+		if ownedBy(task, ownerID) {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+func (r *fakeTaskRepo) GetByID(id, ownerID string) (domain.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, err := r.index(id, ownerID)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	return r.tasks[i], nil
+}
+
+func (r *fakeTaskRepo) Create(task domain.Task) (domain.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	task.ID = "task-" + strconv.Itoa(r.nextID)
+	r.tasks = append(r.tasks, task)
+	return task, nil
+}
+
+func (r *fakeTaskRepo) Update(id, ownerID string, task domain.Task) (domain.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, err := r.index(id, ownerID)
+	if err != nil {
+		return domain.Task{}, err
+	}
+	r.tasks[i] = task
+	return task, nil
+}
+
+func (r *fakeTaskRepo) Delete(id, ownerID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, err := r.index(id, ownerID)
+	if err != nil {
+		return err
+	}
+	r.tasks = append(r.tasks[:i], r.tasks[i+1:]...)
+	return nil
+}
+
+func (r *fakeTaskRepo) index(id, ownerID string) (int, error) {
+	for i, task := range r.tasks {
+		if task.ID == id && ownedBy(task, ownerID) {
+			return i, nil
+		}
+	}
+	return 0, domain.ErrTaskNotFound
+}
+
+func TestAdminTaskAccess(t *testing.T) {
+	uc := NewTaskUseCase(&fakeTaskRepo{})
+	alice := domain.Actor{UserID: "alice", Role: domain.RoleUser}
+	bob := domain.Actor{UserID: "bob", Role: domain.RoleUser}
+	admin := domain.Actor{UserID: "root", Role: domain.RoleAdmin}
+
+	aliceTask, err := uc.CreateTask(alice, domain.Task{Title: "Alice's", Status: "pending", OwnerID: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aliceTask.OwnerID != "alice" {
+		t.Errorf("OwnerID = %q, want the creating actor", aliceTask.OwnerID)
+	}
+	bobTask, err := uc.CreateTask(bob, domain.Task{Title: "Bob's", Status: "pending"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Users only see their own tasks, admins see all of them
+	for _, tt := range []struct {
+		actor domain.Actor
+		want  int
+	}{{alice, 1}, {bob, 1}, {admin, 2}} {
+		tasks, err := uc.GetAllTasks(tt.actor)
+		if err != nil || len(tasks) != tt.want {
+			t.Errorf("GetAllTasks as %s = %d tasks, %v, want %d", tt.actor.UserID, len(tasks), err, tt.want)
+		}
+	}
+
+	if _, err := uc.GetTask(alice, bobTask.ID); !errors.Is(err, domain.ErrTaskNotFound) {
+		t.Errorf("user reading another's task: error = %v, want %v", err, domain.ErrTaskNotFound)
+	}
+	if _, err := uc.UpdateTask(alice, bobTask.ID, domain.Task{Title: "Mine now", Status: "done"}); !errors.Is(err, domain.ErrTaskNotFound) {
+		t.Errorf("user updating another's task: error = %v, want %v", err, domain.ErrTaskNotFound)
+	}
+	if err := uc.DeleteTask(alice, bobTask.ID); !errors.Is(err, domain.ErrTaskNotFound) {
+		t.Errorf("user deleting another's task: error = %v, want %v", err, domain.ErrTaskNotFound)
+	}
+
+	if task, err := uc.GetTask(admin, bobTask.ID); err != nil || task.Title != "Bob's" {
+		t.Errorf("admin reading a task = %+v, %v", task, err)
+	}
+
+	// Admin edits keep the original owner
+	updated, err := uc.UpdateTask(admin, bobTask.ID, domain.Task{Title: "Reviewed", Status: "done", OwnerID: "root"})
+	if err != nil {
+		t.Fatalf("admin updating a task: %v", err)
+	}
+	if updated.OwnerID != "bob" || updated.Title != "Reviewed" {
+		t.Errorf("admin update = %+v, want owner bob and the new title", updated)
+	}
+	if task, _ := uc.GetTask(bob, bobTask.ID); task.Title != "Reviewed" {
+		t.Errorf("owner sees title %q after admin update", task.Title)
+	}
+
+	if err := uc.DeleteTask(admin, aliceTask.ID); err != nil {
+		t.Fatalf("admin deleting a task: %v", err)
+	}
+	if _, err := uc.GetTask(alice, aliceTask.ID); !errors.Is(err, domain.ErrTaskNotFound) {
+		t.Errorf("task still there after admin delete: error = %v", err)
+	}
+
+	// A demoted admin is an ordinary user again
+	demoted := domain.Actor{UserID: "root", Role: domain.RoleUser}
+	if tasks, err := uc.GetAllTasks(demoted); err != nil || len(tasks) != 0 {
+		t.Errorf("GetAllTasks as demoted admin = %d tasks, %v, want 0", len(tasks), err)
+	}
+
+	if _, err := uc.GetAllTasks(domain.Actor{}); !errors.Is(err, domain.ErrForbidden) {
+		t.Errorf("GetAllTasks without an actor: error = %v, want %v", err, domain.ErrForbidden)
+	}
+}
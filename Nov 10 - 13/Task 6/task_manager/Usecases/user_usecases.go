@@ -1,27 +1,46 @@
 package usecases
 
 import (
+	"fmt"
+	"log"
+	"time"
+
 	"task_manager/Domain"
 	"task_manager/Infrastructure"
 )
 
+// How long emailed tokens stay valid
+const (
+	verificationTokenTTL = 24 * time.Hour
+	resetTokenTTL        = time.Hour
+)
+
 // UserUseCaseImpl implements the UserUseCase interface
 type UserUseCaseImpl struct {
-	userRepo     domain.UserRepository
+	userRepo    domain.UserRepository
+	tokenRepo   domain.TokenRepository
 	passwordSvc *infrastructure.PasswordService
 	jwtService  *infrastructure.JWTService
+	tokenSvc    *infrastructure.ActionTokenService
+	mailer      infrastructure.Mailer
 }
 
 // NewUserUseCase creates a new UserUseCaseImpl instance
 func NewUserUseCase(
 	userRepo domain.UserRepository,
+	tokenRepo domain.TokenRepository,
 	passwordSvc *infrastructure.PasswordService,
 	jwtService *infrastructure.JWTService,
+	tokenSvc *infrastructure.ActionTokenService,
+	mailer infrastructure.Mailer,
 ) *UserUseCaseImpl {
 	return &UserUseCaseImpl{
-		userRepo:     userRepo,
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
 		passwordSvc: passwordSvc,
 		jwtService:  jwtService,
+		tokenSvc:    tokenSvc,
+		mailer:      mailer,
 	}
 }
 
@@ -32,6 +51,11 @@ func (uc *UserUseCaseImpl) Register(user domain.User) (domain.User, error) {
 		return domain.User{}, domain.ErrInvalidInput
 	}
 
+	// Enforce the password policy
+	if err := uc.passwordSvc.ValidatePassword(user.Password, user.Username, user.Email); err != nil {
+		return domain.User{}, err
+	}
+
 	// Hash the password
 	hashedPassword, err := uc.passwordSvc.HashPassword(user.Password)
 	if err != nil {
@@ -40,15 +64,46 @@ func (uc *UserUseCaseImpl) Register(user domain.User) (domain.User, error) {
 
 	user.Password = hashedPassword
 
+	// New accounts must confirm their email address
+	user.Verified = false
+
+	// The first user becomes the admin; everyone else starts as a user.
+	// Concurrent first registrations all count zero users, so the bootstrap
+	// marker decides which one of them is promoted.
+	count, err := uc.userRepo.Count()
+	if err != nil {
+		return domain.User{}, err
+	}
+	user.Role = domain.RoleUser
+
 	// Create the user
 	createdUser, err := uc.userRepo.Create(user)
 	if err != nil {
 		return domain.User{}, err
 	}
 
+	if count == 0 {
+		first, err := uc.userRepo.ClaimFirstAdmin(createdUser.ID)
+		if err != nil {
+			return domain.User{}, err
+		}
+		if first {
+			createdUser, err = uc.userRepo.UpdateRole(createdUser.ID, domain.RoleAdmin)
+			if err != nil {
+				return domain.User{}, err
+			}
+		}
+	}
+
 	// Don't return the hashed password
 	createdUser.Password = ""
 
+	// The account exists even if the email cannot be sent; the user can ask
+	// for another one with ResendVerification
+	if err := uc.sendVerification(createdUser); err != nil {
+		log.Printf("Failed to send verification email to %s: %v", createdUser.Email, err)
+	}
+
 	return createdUser, nil
 }
 
@@ -66,8 +121,19 @@ func (uc *UserUseCaseImpl) Login(email, password string) (string, error) {
 		return "", domain.ErrInvalidCredentials
 	}
 
+	// Upgrade outdated hashes while the plain password is known; a failure
+	// here must not block the login
+	if uc.passwordSvc.NeedsRehash(user.Password) {
+		if err := uc.rehashPassword(user.ID, password); err != nil {
+			log.Printf("Failed to rehash password for user %s: %v", user.ID, err)
+		}
+	}
+
 	// Generate JWT token
-	token, err := uc.jwtService.GenerateToken(user.ID)
+	if user.Role == "" {
+		user.Role = domain.RoleUser
+	}
+	token, err := uc.jwtService.GenerateToken(user)
 	if err != nil {
 		return "", err
 	}
@@ -87,3 +153,230 @@ func (uc *UserUseCaseImpl) GetUserProfile(id string) (domain.User, error) {
 
 	return user, nil
 }
+
+// ListUsers retrieves every user; only admins may call it
+func (uc *UserUseCaseImpl) ListUsers(actor domain.Actor) ([]domain.User, error) {
+	if !actor.IsAdmin() {
+		return nil, domain.ErrForbidden
+	}
+
+	users, err := uc.userRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	// Don't return the hashed passwords
+	for i := range users {
+		users[i].Password = ""
+	}
+
+	return users, nil
+}
+
+// PromoteUser gives a user the admin role; only admins may call it
+func (uc *UserUseCaseImpl) PromoteUser(actor domain.Actor, id string) (domain.User, error) {
+	return uc.setRole(actor, id, domain.RoleAdmin)
+}
+
+// DemoteUser takes the admin role away from a user; only admins may call
+// it, and not on themselves, so there is always at least one admin left
+func (uc *UserUseCaseImpl) DemoteUser(actor domain.Actor, id string) (domain.User, error) {
+	if actor.IsAdmin() && actor.UserID == id {
+		return domain.User{}, domain.ErrCannotDemoteSelf
+	}
+	return uc.setRole(actor, id, domain.RoleUser)
+}
+
+// BootstrapAdmin gives the admin role to the user registered with email. It
+// is meant for operators, at startup, when the first user to register was
+// not the intended admin or every admin has lost access. Only verified
+// accounts are promoted, so registering someone else's address is not
+// enough to become an admin.
+func (uc *UserUseCaseImpl) BootstrapAdmin(email string) (domain.User, error) {
+	user, err := uc.userRepo.GetByEmail(email)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if !user.Verified {
+		return domain.User{}, domain.ErrEmailNotVerified
+	}
+
+	if user.Role != domain.RoleAdmin {
+		user, err = uc.userRepo.UpdateRole(user.ID, domain.RoleAdmin)
+		if err != nil {
+			return domain.User{}, err
+		}
+	}
+
+	// Don't return the hashed password
+	user.Password = ""
+
+	return user, nil
+}
+
+// setRole changes a user's role on behalf of an admin
+func (uc *UserUseCaseImpl) setRole(actor domain.Actor, id string, role domain.Role) (domain.User, error) {
+	if !actor.IsAdmin() {
+		return domain.User{}, domain.ErrForbidden
+	}
+
+	user, err := uc.userRepo.UpdateRole(id, role)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	// Don't return the hashed password
+	user.Password = ""
+
+	return user, nil
+}
+
+// VerifyEmail consumes an email verification token and marks its user
+// verified
+func (uc *UserUseCaseImpl) VerifyEmail(token string) error {
+	actionToken, err := uc.consumeToken(token, domain.PurposeVerifyEmail)
+	if err != nil {
+		return err
+	}
+
+	return uc.userRepo.MarkVerified(actionToken.UserID)
+}
+
+// ResendVerification emails a new verification token to an unverified
+// user. Unknown and already verified addresses are silently ignored so the
+// endpoint does not reveal which emails are registered.
+func (uc *UserUseCaseImpl) ResendVerification(email string) error {
+	user, err := uc.userRepo.GetByEmail(email)
+	if err == domain.ErrUserNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.Verified {
+		return nil
+	}
+
+	return uc.sendVerification(user)
+}
+
+// ForgotPassword emails a password reset token. Unknown addresses are
+// silently ignored so the endpoint does not reveal which emails are
+// registered.
+func (uc *UserUseCaseImpl) ForgotPassword(email string) error {
+	user, err := uc.userRepo.GetByEmail(email)
+	if err == domain.ErrUserNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Only the most recent reset link works
+	if err := uc.tokenRepo.DeleteForUser(user.ID, domain.PurposeResetPassword); err != nil {
+		return err
+	}
+
+	token, err := uc.issueToken(user.ID, domain.PurposeResetPassword, resetTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf(
+		"Hello %s,\n\nSomeone asked to reset your password. To choose a new one, send this token with your new password to POST /api/users/reset-password:\n\n%s\n\nThe token expires in %s. If you did not ask for a reset, ignore this email.",
+		user.Username, token, resetTokenTTL,
+	)
+	return uc.mailer.Send(user.Email, "Reset your password", body)
+}
+
+// ResetPassword consumes a password reset token and sets a new password.
+// Receiving the email also proves the user owns the address, so the account
+// becomes verified.
+func (uc *UserUseCaseImpl) ResetPassword(token, newPassword string) error {
+	if newPassword == "" {
+		return domain.ErrInvalidInput
+	}
+
+	id, err := uc.tokenSvc.ParseToken(token)
+	if err != nil {
+		return domain.ErrInvalidToken
+	}
+
+	// Check the new password before using up the token, so a rejected
+	// password can be retried with the same email
+	actionToken, err := uc.tokenRepo.Find(id, domain.PurposeResetPassword)
+	if err != nil {
+		return err
+	}
+	user, err := uc.userRepo.GetByID(actionToken.UserID)
+	if err != nil {
+		return err
+	}
+	if err := uc.passwordSvc.ValidatePassword(newPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	if _, err := uc.tokenRepo.Consume(id, domain.PurposeResetPassword); err != nil {
+		return err
+	}
+
+	if err := uc.rehashPassword(user.ID, newPassword); err != nil {
+		return err
+	}
+
+	return uc.userRepo.MarkVerified(user.ID)
+}
+
+// rehashPassword stores a fresh hash of password for a user
+func (uc *UserUseCaseImpl) rehashPassword(userID, password string) error {
+	hashedPassword, err := uc.passwordSvc.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	return uc.userRepo.UpdatePassword(userID, hashedPassword)
+}
+
+// sendVerification emails a new verification token to user
+func (uc *UserUseCaseImpl) sendVerification(user domain.User) error {
+	token, err := uc.issueToken(user.ID, domain.PurposeVerifyEmail, verificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf(
+		"Hello %s,\n\nPlease confirm your email address by sending this token to POST /api/users/verify:\n\n%s\n\nThe token expires in %s.",
+		user.Username, token, verificationTokenTTL,
+	)
+	return uc.mailer.Send(user.Email, "Verify your email address", body)
+}
+
+// issueToken creates and stores a token, returning the value to email
+func (uc *UserUseCaseImpl) issueToken(userID string, purpose domain.TokenPurpose, ttl time.Duration) (string, error) {
+	token, id, err := uc.tokenSvc.NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = uc.tokenRepo.Create(domain.ActionToken{
+		ID:        id,
+		UserID:    userID,
+		Purpose:   purpose,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// consumeToken checks a token's signature and uses it up
+func (uc *UserUseCaseImpl) consumeToken(token string, purpose domain.TokenPurpose) (domain.ActionToken, error) {
+	id, err := uc.tokenSvc.ParseToken(token)
+	if err != nil {
+		return domain.ActionToken{}, domain.ErrInvalidToken
+	}
+
+	return uc.tokenRepo.Consume(id, purpose)
+}
@@ -0,0 +1,454 @@
+package usecases
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"task_manager/Domain"
+	"task_manager/Infrastructure"
+)
+
+const testPassword = "Correct-Horse-42"
+
+// fakeUserRepo is an in-memory domain.UserRepository
+type fakeUserRepo struct {
+	mu        sync.Mutex
+	users     []domain.User
+	nextID    int
+	bootstrap string
+}
+
+func (r *fakeUserRepo) Create(user domain.User) (domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if existing.Email == user.Email {
+			return domain.User{}, domain.ErrEmailAlreadyExists
+		}
+	}
+	r.nextID++
+	user.ID = "user-" + strconv.Itoa(r.nextID)
+	r.users = append(r.users, user)
+	return user, nil
+}
+
+func (r *fakeUserRepo) GetByEmail(email string) (domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return domain.User{}, domain.ErrUserNotFound
+}
+
+func (r *fakeUserRepo) GetByID(id string) (domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, err := r.index(id)
+	if err != nil {
+		return domain.User{}, err
+	}
+	return r.users[i], nil
+}
+
+func (r *fakeUserRepo) GetAll() ([]domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]domain.User{}, r.users...), nil
+}
+
+func (r *fakeUserRepo) UpdateRole(id string, role domain.Role) (domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, err := r.index(id)
+	if err != nil {
+		return domain.User{}, err
+	}
+	r.users[i].Role = role
+	return r.users[i], nil
+}
+
+func (r *fakeUserRepo) Count() (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return int64(len(r.users)), nil
+}
+
+func (r *fakeUserRepo) ClaimFirstAdmin(userID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.bootstrap != "" {
+		return false, nil
+	}
+	r.bootstrap = userID
+	return true, nil
+}
+
+func (r *fakeUserRepo) MarkVerified(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, err := r.index(id)
+	if err != nil {
+		return err
+	}
+	r.users[i].Verified = true
+	return nil
+}
+
+func (r *fakeUserRepo) UpdatePassword(id, hashedPassword string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, err := r.index(id)
+	if err != nil {
+		return err
+	}
+	r.users[i].Password = hashedPassword
+	return nil
+}
+
+func (r *fakeUserRepo) index(id string) (int, error) {
+	for i, user := range r.users {
+		if user.ID == id {
+			return i, nil
+		}
+	}
+	return 0, domain.ErrUserNotFound
+}
+
+// fakeTokenRepo is an in-memory domain.TokenRepository
+type fakeTokenRepo struct {
+	mu     sync.Mutex
+	tokens map[string]domain.ActionToken
+}
+
+func (r *fakeTokenRepo) Create(token domain.ActionToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.tokens == nil {
+		r.tokens = make(map[string]domain.ActionToken)
+	}
+	r.tokens[token.ID] = token
+	return nil
+}
+
+func (r *fakeTokenRepo) Find(id string, purpose domain.TokenPurpose) (domain.ActionToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[id]
+	if !ok || token.Purpose != purpose || time.Now().After(token.ExpiresAt) {
+		return domain.ActionToken{}, domain.ErrInvalidToken
+	}
+	return token, nil
+}
+
+func (r *fakeTokenRepo) Consume(id string, purpose domain.TokenPurpose) (domain.ActionToken, error) {
+	token, err := r.Find(id, purpose)
+	if err != nil {
+		return domain.ActionToken{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tokens, id)
+	return token, nil
+}
+
+func (r *fakeTokenRepo) DeleteForUser(userID string, purpose domain.TokenPurpose) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, token := range r.tokens {
+		if token.UserID == userID && token.Purpose == purpose {
+			delete(r.tokens, id)
+		}
+	}
+	return nil
+}
+
+// discardMailer drops every email
+type discardMailer struct{}
+
+func (discardMailer) Send(to, subject, body string) error { return nil }
+
+// newUserUseCase returns a use case over users, with cheap password hashing
+// and in-memory signing keys
+func newUserUseCase(t *testing.T, users *fakeUserRepo) *UserUseCaseImpl {
+	t.Helper()
+
+	keys, err := infrastructure.NewKeyRing(infrastructure.KeyRingConfig{
+		Algorithm:   infrastructure.AlgorithmEdDSA,
+		RotateEvery: 30 * 24 * time.Hour,
+		Overlap:     25 * time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwtService, err := infrastructure.NewJWTService(keys, "test", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	passwordSvc := infrastructure.NewPasswordServiceWithConfig(infrastructure.DefaultPasswordPolicy, infrastructure.Argon2Params{
+		Memory:      64,
+		Iterations:  1,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	})
+
+	return NewUserUseCase(users, &fakeTokenRepo{}, passwordSvc, jwtService,
+		infrastructure.NewActionTokenService("test-secret"), discardMailer{})
+}
+
+// seed stores users directly, bypassing registration
+func seed(users *fakeUserRepo, roles ...domain.Role) []domain.User {
+	created := make([]domain.User, len(roles))
+	for i, role := range roles {
+		n := strconv.Itoa(len(users.users) + 1)
+		created[i], _ = users.Create(domain.User{
+			Username: "seed" + n,
+			Email:    "seed" + n + "@example.com",
+			Password: "hash",
+			Role:     role,
+			Verified: true,
+		})
+	}
+	return created
+}
+
+func register(t *testing.T, uc *UserUseCaseImpl, name string) domain.User {
+	t.Helper()
+	user, err := uc.Register(domain.User{Username: name, Email: name + "@example.com", Password: testPassword})
+	if err != nil {
+		t.Fatalf("Register(%s): %v", name, err)
+	}
+	return user
+}
+
+func TestRegisterFirstUserBecomesAdmin(t *testing.T) {
+	users := &fakeUserRepo{}
+	uc := newUserUseCase(t, users)
+
+	first := register(t, uc, "alice")
+	second := register(t, uc, "bob")
+
+	if first.Role != domain.RoleAdmin {
+		t.Errorf("first user role = %q, want %q", first.Role, domain.RoleAdmin)
+	}
+	if second.Role != domain.RoleUser {
+		t.Errorf("second user role = %q, want %q", second.Role, domain.RoleUser)
+	}
+	if first.Password != "" || second.Password != "" {
+		t.Error("Register returned a password hash")
+	}
+	if stored, _ := users.GetByID(first.ID); stored.Role != domain.RoleAdmin {
+		t.Errorf("stored first user role = %q, want %q", stored.Role, domain.RoleAdmin)
+	}
+}
+
+func TestRegisterConcurrentFirstUsers(t *testing.T) {
+	const registrations = 20
+
+	users := &fakeUserRepo{}
+	uc := newUserUseCase(t, users)
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < registrations; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			name := "user" + strconv.Itoa(i)
+			if _, err := uc.Register(domain.User{Username: name, Email: name + "@example.com", Password: testPassword}); err != nil {
+				t.Errorf("Register(%s): %v", name, err)
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	all, _ := users.GetAll()
+	admins := 0
+	for _, user := range all {
+		if user.Role == domain.RoleAdmin {
+			admins++
+		}
+	}
+	if len(all) != registrations || admins != 1 {
+		t.Fatalf("%d users with %d admins, want %d users with 1 admin", len(all), admins, registrations)
+	}
+}
+
+// Databases that had users before the bootstrap marker existed must not
+// promote the next registration
+func TestRegisterWithExistingUsers(t *testing.T) {
+	users := &fakeUserRepo{}
+	seed(users, domain.RoleAdmin)
+	uc := newUserUseCase(t, users)
+
+	if user := register(t, uc, "carol"); user.Role != domain.RoleUser {
+		t.Errorf("role = %q, want %q", user.Role, domain.RoleUser)
+	}
+	if users.bootstrap != "" {
+		t.Errorf("bootstrap marker claimed by %q", users.bootstrap)
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	users := &fakeUserRepo{}
+	seeded := seed(users, domain.RoleAdmin, domain.RoleUser, domain.RoleUser)
+	uc := newUserUseCase(t, users)
+
+	list, err := uc.ListUsers(domain.Actor{UserID: seeded[0].ID, Role: domain.RoleAdmin})
+	if err != nil {
+		t.Fatalf("ListUsers as admin: %v", err)
+	}
+	if len(list) != len(seeded) {
+		t.Fatalf("ListUsers returned %d users, want %d", len(list), len(seeded))
+	}
+	for _, user := range list {
+		if user.Password != "" {
+			t.Errorf("user %s listed with a password hash", user.ID)
+		}
+	}
+	if stored, _ := users.GetByID(seeded[0].ID); stored.Password == "" {
+		t.Error("ListUsers cleared the stored password")
+	}
+
+	for _, actor := range []domain.Actor{
+		{UserID: seeded[1].ID, Role: domain.RoleUser},
+		{UserID: seeded[1].ID},
+		{},
+	} {
+		if _, err := uc.ListUsers(actor); !errors.Is(err, domain.ErrForbidden) {
+			t.Errorf("ListUsers as %+v: error = %v, want %v", actor, err, domain.ErrForbidden)
+		}
+	}
+}
+
+func TestPromoteUser(t *testing.T) {
+	users := &fakeUserRepo{}
+	seeded := seed(users, domain.RoleAdmin, domain.RoleUser, domain.RoleUser)
+	admin := domain.Actor{UserID: seeded[0].ID, Role: domain.RoleAdmin}
+	uc := newUserUseCase(t, users)
+
+	promoted, err := uc.PromoteUser(admin, seeded[1].ID)
+	if err != nil {
+		t.Fatalf("PromoteUser: %v", err)
+	}
+	if promoted.Role != domain.RoleAdmin || promoted.Password != "" {
+		t.Errorf("PromoteUser returned %+v", promoted)
+	}
+	if stored, _ := users.GetByID(seeded[1].ID); stored.Role != domain.RoleAdmin {
+		t.Errorf("stored role = %q, want %q", stored.Role, domain.RoleAdmin)
+	}
+
+	// Promoting an admin again is harmless
+	if _, err := uc.PromoteUser(admin, seeded[1].ID); err != nil {
+		t.Errorf("PromoteUser of an admin: %v", err)
+	}
+
+	if _, err := uc.PromoteUser(admin, "missing"); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("PromoteUser of unknown user: error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+
+	user := domain.Actor{UserID: seeded[2].ID, Role: domain.RoleUser}
+	if _, err := uc.PromoteUser(user, seeded[2].ID); !errors.Is(err, domain.ErrForbidden) {
+		t.Errorf("PromoteUser by a user: error = %v, want %v", err, domain.ErrForbidden)
+	}
+	if stored, _ := users.GetByID(seeded[2].ID); stored.Role != domain.RoleUser {
+		t.Errorf("user promoted themselves to %q", stored.Role)
+	}
+}
+
+func TestDemoteUser(t *testing.T) {
+	users := &fakeUserRepo{}
+	seeded := seed(users, domain.RoleAdmin, domain.RoleAdmin, domain.RoleUser)
+	admin := domain.Actor{UserID: seeded[0].ID, Role: domain.RoleAdmin}
+	uc := newUserUseCase(t, users)
+
+	demoted, err := uc.DemoteUser(admin, seeded[1].ID)
+	if err != nil {
+		t.Fatalf("DemoteUser: %v", err)
+	}
+	if demoted.Role != domain.RoleUser || demoted.Password != "" {
+		t.Errorf("DemoteUser returned %+v", demoted)
+	}
+	if stored, _ := users.GetByID(seeded[1].ID); stored.Role != domain.RoleUser {
+		t.Errorf("stored role = %q, want %q", stored.Role, domain.RoleUser)
+	}
+
+	if _, err := uc.DemoteUser(admin, admin.UserID); !errors.Is(err, domain.ErrCannotDemoteSelf) {
+		t.Errorf("DemoteUser of self: error = %v, want %v", err, domain.ErrCannotDemoteSelf)
+	}
+	if stored, _ := users.GetByID(admin.UserID); stored.Role != domain.RoleAdmin {
+		t.Errorf("admin demoted themselves to %q", stored.Role)
+	}
+
+	if _, err := uc.DemoteUser(admin, "missing"); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Errorf("DemoteUser of unknown user: error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+
+	user := domain.Actor{UserID: seeded[2].ID, Role: domain.RoleUser}
+	if _, err := uc.DemoteUser(user, seeded[0].ID); !errors.Is(err, domain.ErrForbidden) {
+		t.Errorf("DemoteUser by a user: error = %v, want %v", err, domain.ErrForbidden)
+	}
+	if stored, _ := users.GetByID(seeded[0].ID); stored.Role != domain.RoleAdmin {
+		t.Errorf("user demoted an admin to %q", stored.Role)
+	}
+}
+
+func TestBootstrapAdmin(t *testing.T) {
+	users := &fakeUserRepo{}
+	uc := newUserUseCase(t, users)
+	seeded := seed(users, domain.RoleAdmin, domain.RoleUser)
+	unverified := register(t, uc, "mallory")
+
+	tests := []struct {
+		name  string
+		email string
+		want  error
+	}{
+		{"verified user", seeded[1].Email, nil},
+		{"already an admin", seeded[0].Email, nil},
+		{"unverified user", unverified.Email, domain.ErrEmailNotVerified},
+		{"unknown email", "nobody@example.com", domain.ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := uc.BootstrapAdmin(tt.email)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("BootstrapAdmin(%s) error = %v, want %v", tt.email, err, tt.want)
+			}
+			if err != nil {
+				return
+			}
+			if user.Role != domain.RoleAdmin || user.Password != "" {
+				t.Errorf("BootstrapAdmin returned role %q and password %q", user.Role, user.Password)
+			}
+			if stored, _ := users.GetByEmail(tt.email); stored.Role != domain.RoleAdmin {
+				t.Errorf("stored role = %q, want %q", stored.Role, domain.RoleAdmin)
+			}
+		})
+	}
+
+	if stored, _ := users.GetByID(unverified.ID); stored.Role != domain.RoleUser {
+		t.Errorf("unverified user role = %q, want %q", stored.Role, domain.RoleUser)
+	}
+}
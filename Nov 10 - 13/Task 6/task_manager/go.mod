@@ -6,4 +6,6 @@ require (
 	github.com/gin-gonic/gin v1.9.0
 	go.mongodb.org/mongo-driver v1.12.4
 	github.com/google/uuid v1.3.0
+	github.com/golang-jwt/jwt/v5 v5.0.0
+	golang.org/x/crypto v0.14.0
 )
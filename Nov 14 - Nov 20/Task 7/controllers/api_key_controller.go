@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"task_manager/data"
+	"task_manager/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyController struct {
+	apiKeyService *data.APIKeyService
+}
+
+func NewAPIKeyController(ks *data.APIKeyService) *APIKeyController {
+	return &APIKeyController{apiKeyService: ks}
+}
+
+// API Key Handlers
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type APIKeyResponse struct {
+	ID     uint     `json:"id"`
+	Name   string   `json:"name"`
+	Prefix string   `json:"prefix"`
+	Scopes []string `json:"scopes"`
+	// MFA is whether requests with the key count as two-factor
+	// authenticated.
+	MFA bool `json:"mfa"`
+	// Key is the key itself, only set in the response that creates it.
+	Key        string     `json:"key,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+func (kc *APIKeyController) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	key, plainKey, err := kc.apiKeyService.CreateAPIKey(userID.(uint), req.Name, req.Scopes, req.ExpiresAt, c.GetBool("mfa"))
+	if err != nil {
+		if errors.Is(err, data.ErrUnknownScope) || errors.Is(err, data.ErrExpiryInPast) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create API key"})
+		return
+	}
+
+	// This is the only time the key is shown
+	resp := apiKeyResponse(key)
+	resp.Key = plainKey
+	c.JSON(http.StatusCreated, resp)
+}
+
+func (kc *APIKeyController) ListAPIKeys(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	keys, err := kc.apiKeyService.ListAPIKeys(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch API keys"})
+		return
+	}
+
+	resp := make([]APIKeyResponse, len(keys))
+	for i := range keys {
+		resp[i] = apiKeyResponse(&keys[i])
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (kc *APIKeyController) RevokeAPIKey(c *gin.Context) {
+	keyID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid API key ID"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	if err := kc.apiKeyService.RevokeAPIKey(userID.(uint), uint(keyID)); err != nil {
+		if errors.Is(err, data.ErrAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke API key"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func apiKeyResponse(key *models.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.ScopeList(),
+		MFA:        key.MFA,
+		CreatedAt:  key.CreatedAt,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+	}
+}
@@ -1,22 +1,35 @@
 package controllers
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 	"task_manager/data"
+	"task_manager/dto"
 	"task_manager/middleware"
 	"task_manager/models"
+	"task_manager/oidc"
+	"task_manager/password"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type AuthController struct {
-	userService *data.UserService
+	userService   *data.UserService
+	tokens        *middleware.TokenService
+	oidcProviders map[string]*oidc.Provider
+	pendingLogins *oidc.PendingLogins
 }
 
-func NewAuthController(us *data.UserService) *AuthController {
-	return &AuthController{userService: us}
+func NewAuthController(us *data.UserService, tokens *middleware.TokenService) *AuthController {
+	return &AuthController{
+		userService:   us,
+		tokens:        tokens,
+		oidcProviders: make(map[string]*oidc.Provider),
+		pendingLogins: oidc.NewPendingLogins(),
+	}
 }
 
 type TaskController struct {
@@ -33,6 +46,27 @@ type RegisterRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type UserResponse struct {
+	ID       uint        `json:"id"`
+	Username string      `json:"username"`
+	Role     models.Role `json:"role"`
+}
+
+type RegisterResponse struct {
+	Token string       `json:"token"`
+	User  UserResponse `json:"user"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+	// Violations lists what is wrong with a rejected password.
+	Violations []string `json:"violations,omitempty"`
+}
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
 func (ac *AuthController) Register(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -63,23 +97,24 @@ func (ac *AuthController) Register(c *gin.Context) {
 
 	user, err := ac.userService.CreateUser(req.Username, req.Password, role)
 	if err != nil {
+		var policyErr *password.PolicyError
+		if errors.As(err, &policyErr) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "password too weak", Violations: policyErr.Violations})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
 		return
 	}
 
-	token, err := middleware.GenerateToken(user)
+	token, err := ac.tokens.GenerateToken(user, false)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"token": token,
-		"user": gin.H{
-			"id":       user.ID,
-			"username": user.Username,
-			"role":     user.Role,
-		},
+	c.JSON(http.StatusCreated, RegisterResponse{
+		Token: token,
+		User:  userResponse(user),
 	})
 }
 
@@ -88,6 +123,21 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type LoginResponse struct {
+	Token            string       `json:"token"`
+	MFASetupRequired bool         `json:"mfa_setup_required"`
+	User             UserResponse `json:"user"`
+	// RecoveryCodesRemaining is set when a recovery code was used.
+	RecoveryCodesRemaining *int64 `json:"recovery_codes_remaining,omitempty"`
+}
+
+// MFAChallengeResponse is the login response for users with two-factor
+// authentication enabled.
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
 func (ac *AuthController) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -106,22 +156,65 @@ func (ac *AuthController) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := middleware.GenerateToken(user)
+	// Upgrade old hashes while the plain password is at hand
+	if err := ac.userService.RehashPassword(user, req.Password); err != nil {
+		log.Println("Warning: failed to rehash password:", err)
+	}
+
+	ac.completeLogin(c, user, false)
+}
+
+// completeLogin responds to a successful first factor. If the user has
+// two-factor authentication enabled they get a challenge token, which is
+// exchanged for a real one at /api/v1/auth/login/mfa. mfa records whether the
+// first factor already included a second one.
+func (ac *AuthController) completeLogin(c *gin.Context, user *models.User, mfa bool) {
+	if user.MFAEnabled {
+		challenge, err := ac.tokens.GenerateMFAChallenge(user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, MFAChallengeResponse{MFARequired: true, MFAToken: challenge})
+		return
+	}
+
+	required, err := ac.userService.MFARequired(user.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check MFA policy"})
+		return
+	}
+
+	token, err := ac.tokens.GenerateToken(user, mfa)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"token": token,
-		"user": gin.H{
-			"id":       user.ID,
-			"username": user.Username,
-			"role":     user.Role,
-		},
+	c.JSON(http.StatusOK, LoginResponse{
+		Token:            token,
+		MFASetupRequired: required && !mfa,
+		User:             userResponse(user),
 	})
 }
 
+func userResponse(user *models.User) UserResponse {
+	return UserResponse{
+		ID:       user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+	}
+}
+
+// JWKS serves the public keys tokens are signed with, so other services
+// can verify them without sharing a secret.
+func (ac *AuthController) JWKS(c *gin.Context) {
+	// New keys are published an overlap ahead of use, so caching for a few
+	// minutes is safe
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, ac.tokens.JWKS())
+}
+
 func (ac *AuthController) PromoteUser(c *gin.Context) {
 	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -133,122 +226,142 @@ func (ac *AuthController) PromoteUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to promote user"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "user promoted to admin"})
+	c.JSON(http.StatusOK, MessageResponse{Message: "user promoted to admin"})
 }
 
 // Task Handlers
-type CreateTaskRequest struct {
-	Title       string `json:"title" binding:"required"`
-	Description string `json:"description"`
-}
+//
+// The exported handlers serve /api/v1 and speak dto types. The unexported
+// ones do the work and respond only on errors, so each API version can
+// present the task its own way.
 
 func (tc *TaskController) CreateTask(c *gin.Context) {
-	var req CreateTaskRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+	if task, ok := tc.createTask(c); ok {
+		c.JSON(http.StatusCreated, dto.FromTask(task))
 	}
+}
 
-	userID, _ := c.Get("userID")
-	task := &models.Task{
-		Title:       req.Title,
-		Description: req.Description,
-		UserID:      userID.(uint),
+func (tc *TaskController) GetTask(c *gin.Context) {
+	if task, ok := tc.getTask(c); ok {
+		c.JSON(http.StatusOK, dto.FromTask(task))
 	}
+}
 
-	if err := tc.taskService.CreateTask(task); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create task"})
-		return
+func (tc *TaskController) GetAllTasks(c *gin.Context) {
+	if tasks, ok := tc.getAllTasks(c); ok {
+		c.JSON(http.StatusOK, dto.FromTasks(tasks))
 	}
+}
 
-	c.JSON(http.StatusCreated, task)
+func (tc *TaskController) UpdateTask(c *gin.Context) {
+	if task, ok := tc.updateTask(c); ok {
+		c.JSON(http.StatusOK, dto.FromTask(task))
+	}
 }
 
-func (tc *TaskController) GetTask(c *gin.Context) {
+func (tc *TaskController) DeleteTask(c *gin.Context) {
 	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task ID"})
 		return
 	}
 
+	// Verify task exists and belongs to user
 	task, err := tc.taskService.GetTaskByID(uint(taskID))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
 		return
 	}
-	c.JSON(http.StatusOK, task)
-}
 
-func (tc *TaskController) GetAllTasks(c *gin.Context) {
-	tasks, err := tc.taskService.GetAllTasks()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch tasks"})
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("userRole")
+	if task.UserID != userID && role != models.AdminRole {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not authorized to delete this task"})
 		return
 	}
-	c.JSON(http.StatusOK, tasks)
-}
 
-func (tc *TaskController) UpdateTask(c *gin.Context) {
-	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+	if err := tc.taskService.DeleteTask(uint(taskID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete task"})
 		return
 	}
 
-	var task models.Task
-	if err := c.ShouldBindJSON(&task); err != nil {
+	c.Status(http.StatusNoContent)
+}
+
+func (tc *TaskController) createTask(c *gin.Context) (*models.Task, bool) {
+	var req dto.CreateTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		return nil, false
 	}
 
-	// Verify task exists and belongs to user
-	existingTask, err := tc.taskService.GetTaskByID(uint(taskID))
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
-		return
+	userID, _ := c.Get("userID")
+	task := req.ToTask(userID.(uint))
+
+	if err := tc.taskService.CreateTask(task); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create task"})
+		return nil, false
 	}
+	return task, true
+}
 
-	userID, _ := c.Get("userID")
-	role, _ := c.Get("userRole")
-	if existingTask.UserID != userID && role != models.AdminRole {
-		c.JSON(http.StatusForbidden, gin.H{"error": "not authorized to update this task"})
-		return
+func (tc *TaskController) getTask(c *gin.Context) (*models.Task, bool) {
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+		return nil, false
 	}
 
-	task.ID = uint(taskID)
-	if err := tc.taskService.UpdateTask(&task); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update task"})
-		return
+	task, err := tc.taskService.GetTaskByID(uint(taskID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return nil, false
 	}
+	return task, true
+}
 
-	c.JSON(http.StatusOK, task)
+func (tc *TaskController) getAllTasks(c *gin.Context) ([]models.Task, bool) {
+	tasks, err := tc.taskService.GetAllTasks()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch tasks"})
+		return nil, false
+	}
+	return tasks, true
 }
 
-func (tc *TaskController) DeleteTask(c *gin.Context) {
+// updateTask applies the request to the stored task, so the ID, owner and
+// timestamps can not be set by the client.
+func (tc *TaskController) updateTask(c *gin.Context) (*models.Task, bool) {
 	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task ID"})
-		return
+		return nil, false
+	}
+
+	var req dto.UpdateTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
 	}
 
 	// Verify task exists and belongs to user
 	task, err := tc.taskService.GetTaskByID(uint(taskID))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
-		return
+		return nil, false
 	}
 
 	userID, _ := c.Get("userID")
 	role, _ := c.Get("userRole")
 	if task.UserID != userID && role != models.AdminRole {
-		c.JSON(http.StatusForbidden, gin.H{"error": "not authorized to delete this task"})
-		return
+		c.JSON(http.StatusForbidden, gin.H{"error": "not authorized to update this task"})
+		return nil, false
 	}
 
-	if err := tc.taskService.DeleteTask(uint(taskID)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete task"})
-		return
+	req.ApplyTo(task)
+	if err := tc.taskService.UpdateTask(task); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update task"})
+		return nil, false
 	}
-
-	c.Status(http.StatusNoContent)
+	return task, true
 }
@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Legacy Task Handlers
+//
+// These serve the unversioned /api/tasks routes, which return models.Task
+// as it was before /api/v1. Requests are bound to the same dto types as in
+// v1, so the ID and owner cannot be set here either. Deleting has no body
+// and uses DeleteTask.
+
+func (tc *TaskController) LegacyCreateTask(c *gin.Context) {
+	if task, ok := tc.createTask(c); ok {
+		c.JSON(http.StatusCreated, task)
+	}
+}
+
+func (tc *TaskController) LegacyGetTask(c *gin.Context) {
+	if task, ok := tc.getTask(c); ok {
+		c.JSON(http.StatusOK, task)
+	}
+}
+
+func (tc *TaskController) LegacyGetAllTasks(c *gin.Context) {
+	if tasks, ok := tc.getAllTasks(c); ok {
+		c.JSON(http.StatusOK, tasks)
+	}
+}
+
+func (tc *TaskController) LegacyUpdateTask(c *gin.Context) {
+	if task, ok := tc.updateTask(c); ok {
+		c.JSON(http.StatusOK, task)
+	}
+}
@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"task_manager/data"
+	"task_manager/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MFA Handlers
+type MFACodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type MFALoginRequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type MFAPolicyRequest struct {
+	RequiredRoles []models.Role `json:"required_roles" binding:"required"`
+}
+
+type MFAEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type RecoveryCodesResponse struct {
+	// Token is a new token with the mfa claim, set when enrollment is
+	// confirmed.
+	Token         string   `json:"token,omitempty"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MFAPolicyResponse struct {
+	RequiredRoles []models.Role `json:"required_roles"`
+}
+
+func (ac *AuthController) EnrollMFA(c *gin.Context) {
+	user, ok := ac.currentUser(c)
+	if !ok {
+		return
+	}
+
+	secret, uri, err := ac.userService.BeginMFAEnrollment(user)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, MFAEnrollmentResponse{
+		Secret:     secret,
+		OTPAuthURI: uri,
+	})
+}
+
+func (ac *AuthController) ConfirmMFA(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+		return
+	}
+
+	user, ok := ac.currentUser(c)
+	if !ok {
+		return
+	}
+
+	codes, err := ac.userService.ConfirmMFAEnrollment(user, req.Code)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	// The old token lacks the mfa claim, so hand out one that has it
+	token, err := ac.tokens.GenerateToken(user, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, RecoveryCodesResponse{
+		Token:         token,
+		RecoveryCodes: codes,
+	})
+}
+
+func (ac *AuthController) LoginMFA(c *gin.Context) {
+	var req MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (req.Code == "") == (req.RecoveryCode == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "exactly one of code or recovery_code is required"})
+		return
+	}
+
+	userID, err := ac.tokens.ParseMFAChallenge(req.MFAToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	user, err := ac.userService.GetUserByID(userID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": data.ErrInvalidMFACode.Error()})
+		return
+	}
+
+	if err := ac.userService.VerifyMFA(user, req.Code, req.RecoveryCode); err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	token, err := ac.tokens.GenerateToken(user, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		return
+	}
+
+	resp := LoginResponse{
+		Token: token,
+		User:  userResponse(user),
+	}
+	if req.RecoveryCode != "" {
+		if remaining, err := ac.userService.CountRecoveryCodes(user); err == nil {
+			resp.RecoveryCodesRemaining = &remaining
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (ac *AuthController) RegenerateRecoveryCodes(c *gin.Context) {
+	user, ok := ac.verifiedUser(c)
+	if !ok {
+		return
+	}
+
+	codes, err := ac.userService.RegenerateRecoveryCodes(user)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (ac *AuthController) DisableMFA(c *gin.Context) {
+	user, ok := ac.verifiedUser(c)
+	if !ok {
+		return
+	}
+
+	if err := ac.userService.DisableMFA(user); err != nil {
+		respondMFAError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (ac *AuthController) GetMFAPolicy(c *gin.Context) {
+	roles, err := ac.userService.MFARequiredRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load MFA policy"})
+		return
+	}
+	c.JSON(http.StatusOK, MFAPolicyResponse{RequiredRoles: roles})
+}
+
+func (ac *AuthController) UpdateMFAPolicy(c *gin.Context) {
+	var req MFAPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ac.userService.SetMFARequiredRoles(req.RequiredRoles); err != nil {
+		if errors.Is(err, data.ErrUnknownRole) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update MFA policy"})
+		return
+	}
+	ac.GetMFAPolicy(c)
+}
+
+// MFARequired is the policy check for middleware.RequireMFA.
+func (ac *AuthController) MFARequired(role models.Role) (bool, error) {
+	return ac.userService.MFARequired(role)
+}
+
+func (ac *AuthController) currentUser(c *gin.Context) (*models.User, bool) {
+	userID, _ := c.Get("userID")
+	id, _ := userID.(uint)
+	user, err := ac.userService.GetUserByID(id)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return nil, false
+	}
+	return user, true
+}
+
+// verifiedUser returns the current user after checking a fresh second
+// factor, so a stolen token alone cannot change the user's MFA settings.
+func (ac *AuthController) verifiedUser(c *gin.Context) (*models.User, bool) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Code == "") == (req.RecoveryCode == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "exactly one of code or recovery_code is required"})
+		return nil, false
+	}
+
+	user, ok := ac.currentUser(c)
+	if !ok {
+		return nil, false
+	}
+	if err := ac.userService.VerifyMFA(user, req.Code, req.RecoveryCode); err != nil {
+		respondMFAError(c, err)
+		return nil, false
+	}
+	return user, true
+}
+
+func respondMFAError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, data.ErrInvalidMFACode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, data.ErrMFALocked):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, data.ErrMFARequiredForRole):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, data.ErrMFANotEnrolled),
+		errors.Is(err, data.ErrMFANotEnabled),
+		errors.Is(err, data.ErrMFAAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "two-factor authentication failed"})
+	}
+}
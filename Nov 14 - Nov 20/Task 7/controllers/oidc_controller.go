@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"task_manager/data"
+	"task_manager/models"
+	"task_manager/oidc"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcLoginCookie holds the pending login, sealed, between starting a
+// sign-in and the provider's callback.
+const oidcLoginCookie = "oidc_login"
+
+// AddOIDCProvider enables single sign-on with provider.
+func (ac *AuthController) AddOIDCProvider(provider *oidc.Provider) {
+	ac.oidcProviders[provider.Name()] = provider
+}
+
+// OIDC Handlers
+type OIDCProviderResponse struct {
+	Name string `json:"name"`
+	// LoginURL is in the API version the providers were listed with.
+	LoginURL string `json:"login_url"`
+}
+
+type AuthorizationURLResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+// OIDCErrorResponse is returned when the provider reports that sign-in
+// failed.
+type OIDCErrorResponse struct {
+	Error         string `json:"error"`
+	ProviderError string `json:"provider_error"`
+}
+
+func (ac *AuthController) ListOIDCProviders(c *gin.Context) {
+	names := make([]string, 0, len(ac.oidcProviders))
+	for name := range ac.oidcProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	providers := make([]OIDCProviderResponse, len(names))
+	for i, name := range names {
+		providers[i] = OIDCProviderResponse{
+			Name:     name,
+			LoginURL: c.FullPath() + "/" + name + "/login",
+		}
+	}
+	c.JSON(http.StatusOK, providers)
+}
+
+// OIDCLogin sends the browser to the provider to sign in.
+func (ac *AuthController) OIDCLogin(c *gin.Context) {
+	authURL, ok := ac.startOIDC(c, 0)
+	if !ok {
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// LinkOIDC returns the URL at which the current user can sign in to the
+// provider to link that account to theirs.
+func (ac *AuthController) LinkOIDC(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	authURL, ok := ac.startOIDC(c, userID.(uint))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, AuthorizationURLResponse{AuthorizationURL: authURL})
+}
+
+// OIDCCallback is where the provider sends the browser back to. It signs
+// the user in, or finishes linking.
+func (ac *AuthController) OIDCCallback(c *gin.Context) {
+	provider, ok := ac.oidcProviders[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown identity provider"})
+		return
+	}
+
+	// Without the cookie the callback comes from a browser that did not
+	// start the login, e.g. one tricked into signing in to someone else's
+	// account
+	cookie, _ := c.Cookie(oidcLoginCookie)
+	pending, ok := ac.pendingLogins.Finish(c.Query("state"), cookie)
+	if !ok || pending.Provider != provider.Name() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired sign-in state"})
+		return
+	}
+	setOIDCLoginCookie(c, provider, "", -1)
+	if providerErr := c.Query("error"); providerErr != "" {
+		c.JSON(http.StatusUnauthorized, OIDCErrorResponse{
+			Error:         "sign-in failed at the identity provider",
+			ProviderError: providerErr,
+		})
+		return
+	}
+
+	identity, err := provider.Exchange(c.Request.Context(), c.Query("code"), pending.Verifier, pending.Nonce)
+	if err != nil {
+		log.Printf("Warning: OIDC sign-in with %s failed: %v", provider.Name(), err)
+		if errors.Is(err, oidc.ErrDiscovery) {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "identity provider is unavailable"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "sign-in failed"})
+		return
+	}
+
+	external := data.ExternalIdentity{
+		Issuer:            identity.Issuer,
+		Subject:           identity.Subject,
+		Email:             identity.Email,
+		EmailVerified:     identity.EmailVerified,
+		TrustEmail:        provider.TrustsEmail(),
+		PreferredUsername: identity.PreferredUsername,
+		Role:              mappedRole(provider, identity),
+	}
+
+	if pending.LinkUserID != 0 {
+		if err := ac.userService.LinkExternal(pending.LinkUserID, external); err != nil {
+			if errors.Is(err, data.ErrIdentityLinked) {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to link account"})
+			return
+		}
+		c.JSON(http.StatusOK, MessageResponse{Message: "account linked"})
+		return
+	}
+
+	user, err := ac.userService.SignInExternal(external)
+	if err != nil {
+		log.Println("Warning: failed to sign in external user:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to sign in"})
+		return
+	}
+	ac.completeLogin(c, user, hasMFA(identity))
+}
+
+// startOIDC sets the cookie for a new login and returns the provider's
+// authorization URL for it. linkUserID is 0 for a sign-in.
+func (ac *AuthController) startOIDC(c *gin.Context, linkUserID uint) (string, bool) {
+	provider, ok := ac.oidcProviders[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown identity provider"})
+		return "", false
+	}
+
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start sign-in"})
+		return "", false
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start sign-in"})
+		return "", false
+	}
+
+	state, cookie, err := ac.pendingLogins.Start(oidc.PendingLogin{
+		Provider:   provider.Name(),
+		Verifier:   verifier,
+		Nonce:      nonce,
+		LinkUserID: linkUserID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start sign-in"})
+		return "", false
+	}
+
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), state, nonce, oidc.Challenge(verifier))
+	if err != nil {
+		log.Printf("Warning: OIDC discovery for %s failed: %v", provider.Name(), err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "identity provider is unavailable"})
+		return "", false
+	}
+	setOIDCLoginCookie(c, provider, cookie, int(oidc.LoginTTL.Seconds()))
+	return authURL, true
+}
+
+// setOIDCLoginCookie sets or, with a negative maxAge, clears the login
+// cookie. It is only sent to the provider's callback. SameSite=Lax still
+// sends it on the provider's redirect back, which is a top-level navigation.
+func setOIDCLoginCookie(c *gin.Context, provider *oidc.Provider, value string, maxAge int) {
+	path := "/"
+	if u, err := url.Parse(provider.RedirectURL()); err == nil && u.Path != "" {
+		path = u.Path
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcLoginCookie,
+		Value:    value,
+		Path:     path,
+		MaxAge:   maxAge,
+		Secure:   strings.HasPrefix(provider.RedirectURL(), "https://"),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// mappedRole returns the role the provider's groups give the user, or ""
+// to keep the local role if the provider has no group mapping.
+func mappedRole(provider *oidc.Provider, identity *oidc.Identity) models.Role {
+	if !provider.MapsGroups() {
+		return ""
+	}
+	for _, role := range provider.Roles(identity) {
+		if models.Role(role) == models.AdminRole {
+			return models.AdminRole
+		}
+	}
+	return models.UserRole
+}
+
+// hasMFA reports whether the provider says the user signed in with more
+// than one factor (RFC 8176).
+func hasMFA(identity *oidc.Identity) bool {
+	for _, method := range identity.AMR {
+		if method == "mfa" {
+			return true
+		}
+	}
+	return false
+}
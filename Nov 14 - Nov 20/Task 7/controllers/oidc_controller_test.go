@@ -0,0 +1,538 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"task_manager/controllers"
+	"task_manager/data"
+	"task_manager/keyring"
+	"task_manager/middleware"
+	"task_manager/models"
+	"task_manager/oidc"
+	"task_manager/oidctest"
+	"task_manager/router"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const (
+	clientID     = "task-manager"
+	clientSecret = "secret"
+	// appURL is where the providers send the browser back to. Requests to
+	// it are served by the router in-process.
+	appURL = "http://task-manager.test"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+}
+
+// newApp returns the application's routes over a database of their own,
+// with single sign-on enabled for providers.
+func newApp(t *testing.T, providers ...oidc.Config) *gin.Engine {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Task{}, &models.RecoveryCode{}, &models.Setting{}, &models.APIKey{}, &models.Identity{}); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := keyring.New(keyring.Config{
+		Algorithm:   keyring.AlgorithmEdDSA,
+		RotateEvery: 30 * 24 * time.Hour,
+		Overlap:     25 * time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := middleware.NewTokenService(keys, "task_manager", "task_manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apiKeyService := data.NewAPIKeyService(db)
+	authController := controllers.NewAuthController(data.NewUserService(db), tokens)
+	for _, cfg := range providers {
+		authController.AddOIDCProvider(oidc.NewProvider(cfg, nil))
+	}
+	return router.SetupRouter(authController, controllers.NewTaskController(data.NewTaskService(db)),
+		controllers.NewAPIKeyController(apiKeyService), middleware.AuthMiddleware(tokens, apiKeyService))
+}
+
+// newIdP starts a mock identity provider and returns it with the
+// configuration of a provider called name that uses it.
+func newIdP(t *testing.T, name string) (*oidctest.Server, oidc.Config) {
+	t.Helper()
+	idp := oidctest.NewServer(clientID, clientSecret)
+	t.Cleanup(idp.Close)
+	return idp, oidc.Config{
+		Name:         name,
+		Issuer:       idp.Issuer(),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  appURL + "/api/v1/auth/oidc/" + name + "/callback",
+	}
+}
+
+func do(t *testing.T, r http.Handler, method, target, token string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = strings.NewReader(string(b))
+	}
+	req := httptest.NewRequest(method, target, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %q: %v", w.Body, err)
+	}
+}
+
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status = %d, want %d; body %s", w.Code, status, w.Body)
+	}
+}
+
+// newBrowser returns the cookie jar of a browser of its own.
+func newBrowser(t *testing.T) http.CookieJar {
+	t.Helper()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jar
+}
+
+// browse sends a request to the app like a browser with jar: with the
+// jar's cookies for the app, keeping the cookies the app sets.
+func browse(t *testing.T, app http.Handler, jar http.CookieJar, method, target, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, appURL+target, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for _, cookie := range jar.Cookies(req.URL) {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	jar.SetCookies(req.URL, w.Result().Cookies())
+	return w
+}
+
+// startLogin starts a sign-in with provider in the browser with jar and
+// returns the provider's authorization URL the browser is redirected to.
+func startLogin(t *testing.T, app http.Handler, jar http.CookieJar, provider string) string {
+	t.Helper()
+	w := browse(t, app, jar, http.MethodGet, "/api/v1/auth/oidc/"+provider+"/login", "")
+	expectStatus(t, w, http.StatusFound)
+	return w.Header().Get("Location")
+}
+
+// authorize visits the provider's authorization URL like a browser and
+// returns the callback it redirects back to, as a request URI of the app.
+func authorize(t *testing.T, authURL string) string {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorization endpoint status = %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+	location, err := resp.Location()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(location.String(), appURL+"/") {
+		t.Fatalf("provider redirected to %s, want the app", location)
+	}
+	return location.RequestURI()
+}
+
+// signIn signs in at provider as the provider's current user, in a new
+// browser.
+func signIn(t *testing.T, app http.Handler, provider string) controllers.LoginResponse {
+	t.Helper()
+	jar := newBrowser(t)
+	w := browse(t, app, jar, http.MethodGet, authorize(t, startLogin(t, app, jar, provider)), "")
+	expectStatus(t, w, http.StatusOK)
+	var login controllers.LoginResponse
+	decode(t, w, &login)
+	if login.Token == "" {
+		t.Fatalf("sign-in returned no token: %s", w.Body)
+	}
+	return login
+}
+
+// withQuery returns rawURL with key set to value.
+func withQuery(t *testing.T, rawURL, key, value string) string {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	q.Set(key, value)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func TestOIDCSignIn(t *testing.T) {
+	t.Parallel()
+	idp, corp := newIdP(t, "corp")
+	app := newApp(t, corp)
+	idp.SetUser(oidctest.User{Subject: "42", Email: "Ada@Example.com", EmailVerified: true, PreferredUsername: "Ada"})
+
+	jar := newBrowser(t)
+	authURL := startLogin(t, app, jar, "corp")
+	params, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := params.Query()
+	for _, key := range []string{"state", "nonce", "code_challenge"} {
+		if q.Get(key) == "" {
+			t.Errorf("authorization URL %s has no %s", authURL, key)
+		}
+	}
+	if q.Get("code_challenge_method") != "S256" {
+		t.Errorf("code_challenge_method = %q, want S256", q.Get("code_challenge_method"))
+	}
+
+	callback := authorize(t, authURL)
+	w := browse(t, app, jar, http.MethodGet, callback, "")
+	expectStatus(t, w, http.StatusOK)
+	var login controllers.LoginResponse
+	decode(t, w, &login)
+	if login.User.Username != "ada" || login.User.Role != models.UserRole {
+		t.Errorf("provisioned user = %+v, want ada with role user", login.User)
+	}
+
+	// The token works on the API
+	expectStatus(t, do(t, app, http.MethodGet, "/api/v1/tasks", login.Token, nil), http.StatusOK)
+
+	// A state can only be used once
+	expectStatus(t, browse(t, app, jar, http.MethodGet, callback, ""), http.StatusBadRequest)
+
+	// Signing in again finds the same user
+	if again := signIn(t, app, "corp"); again.User.ID != login.User.ID {
+		t.Errorf("second sign-in as user %d, want %d", again.User.ID, login.User.ID)
+	}
+}
+
+// A callback is only accepted with the state, PKCE verifier and nonce of a
+// login the app started.
+func TestOIDCCallbackRejectsTampering(t *testing.T) {
+	t.Parallel()
+	idp, corp := newIdP(t, "corp")
+	_, partner := newIdP(t, "partner")
+	app := newApp(t, corp, partner)
+	idp.SetUser(oidctest.User{Subject: "42", Email: "ada@example.com", EmailVerified: true})
+
+	tests := []struct {
+		name string
+		// callback returns the callback to request, given the one the
+		// provider redirected to for a login started at corp
+		callback func(t *testing.T, authURL string) string
+		status   int
+	}{
+		{
+			name: "unknown state",
+			callback: func(t *testing.T, authURL string) string {
+				return withQuery(t, authorize(t, authURL), "state", "forged")
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "missing state",
+			callback: func(t *testing.T, authURL string) string {
+				return withQuery(t, authorize(t, authURL), "state", "")
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "state of another provider",
+			callback: func(t *testing.T, authURL string) string {
+				return strings.Replace(authorize(t, authURL), "/oidc/corp/", "/oidc/partner/", 1)
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "wrong PKCE verifier",
+			callback: func(t *testing.T, authURL string) string {
+				return authorize(t, withQuery(t, authURL, "code_challenge", oidc.Challenge("someone else's verifier")))
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "code from another login",
+			callback: func(t *testing.T, authURL string) string {
+				stolen, err := url.Parse(authorize(t, startLogin(t, app, newBrowser(t), "corp")))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return withQuery(t, authorize(t, authURL), "code", stolen.Query().Get("code"))
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "wrong nonce",
+			callback: func(t *testing.T, authURL string) string {
+				return authorize(t, withQuery(t, authURL, "nonce", "replayed"))
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "no nonce",
+			callback: func(t *testing.T, authURL string) string {
+				return authorize(t, withQuery(t, authURL, "nonce", ""))
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "provider error",
+			callback: func(t *testing.T, authURL string) string {
+				return authorize(t, withQuery(t, authURL, "response_type", "token"))
+			},
+			status: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jar := newBrowser(t)
+			w := browse(t, app, jar, http.MethodGet, tt.callback(t, startLogin(t, app, jar, "corp")), "")
+			expectStatus(t, w, tt.status)
+			if strings.Contains(w.Body.String(), `"token"`) {
+				t.Errorf("rejected callback returned a token: %s", w.Body)
+			}
+		})
+	}
+
+	// None of the rejected callbacks signed anyone in, so the first real
+	// sign-in provisions the first user
+	if login := signIn(t, app, "corp"); login.User.ID != 1 {
+		t.Errorf("user ID = %d after rejected callbacks, want 1", login.User.ID)
+	}
+}
+
+// A callback only signs in the browser that started the login, so nobody
+// can be signed in to an account of someone else's choosing by following
+// a link to a callback.
+func TestOIDCCallbackBoundToBrowser(t *testing.T) {
+	t.Parallel()
+	idp, corp := newIdP(t, "corp")
+	app := newApp(t, corp)
+	idp.SetUser(oidctest.User{Subject: "mallory", Email: "mallory@example.com", EmailVerified: true})
+
+	mallory := newBrowser(t)
+	callback := authorize(t, startLogin(t, app, mallory, "corp"))
+
+	// The victim has not started a sign-in, or has one of their own
+	victim := newBrowser(t)
+	expectStatus(t, browse(t, app, victim, http.MethodGet, callback, ""), http.StatusBadRequest)
+	startLogin(t, app, victim, "corp")
+	expectStatus(t, browse(t, app, victim, http.MethodGet, callback, ""), http.StatusBadRequest)
+
+	// Neither attempt used up the login in the browser that started it
+	expectStatus(t, browse(t, app, mallory, http.MethodGet, callback, ""), http.StatusOK)
+
+	// A forged cookie is rejected too
+	forged := newBrowser(t)
+	u, err := url.Parse(appURL + "/api/v1/auth/oidc/corp/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged.SetCookies(u, []*http.Cookie{{Name: "oidc_login", Value: "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"}})
+	expectStatus(t, browse(t, app, forged, http.MethodGet, authorize(t, startLogin(t, app, newBrowser(t), "corp")), ""), http.StatusBadRequest)
+}
+
+// Sign-ins at different providers only end up at the same user when both
+// providers verified the email and are trusted to.
+func TestOIDCLinksByVerifiedEmailOnly(t *testing.T) {
+	t.Parallel()
+	corpIdP, corp := newIdP(t, "corp")
+	corp.TrustEmail = true
+	partnerIdP, partner := newIdP(t, "partner")
+	partner.TrustEmail = true
+	guestIdP, guest := newIdP(t, "guest")
+	app := newApp(t, corp, partner, guest)
+
+	corpIdP.SetUser(oidctest.User{Subject: "ada", Email: "ada@example.com", EmailVerified: true})
+	ada := signIn(t, app, "corp")
+	corpIdP.SetUser(oidctest.User{Subject: "bob", Email: "bob@example.com", EmailVerified: false})
+	bob := signIn(t, app, "corp")
+
+	tests := []struct {
+		name   string
+		user   oidctest.User
+		wantID uint
+		// same is whether the sign-in should end up at wantID
+		same bool
+	}{
+		{"verified email of a verified identity", oidctest.User{Subject: "p-ada", Email: "ADA@example.com", EmailVerified: true}, ada.User.ID, true},
+		{"unverified email of a verified identity", oidctest.User{Subject: "p-mallory", Email: "ada@example.com", EmailVerified: false}, ada.User.ID, false},
+		{"verified email of an unverified identity", oidctest.User{Subject: "p-bob", Email: "bob@example.com", EmailVerified: true}, bob.User.ID, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			partnerIdP.SetUser(tt.user)
+			login := signIn(t, app, "partner")
+			if (login.User.ID == tt.wantID) != tt.same {
+				t.Errorf("signed in as user %d; user %d, same = %v", login.User.ID, tt.wantID, tt.same)
+			}
+
+			// The identity stays with the user it was first linked to
+			if again := signIn(t, app, "partner"); again.User.ID != login.User.ID {
+				t.Errorf("second sign-in as user %d, want %d", again.User.ID, login.User.ID)
+			}
+		})
+	}
+
+	// A provider that is not trusted for email linking cannot claim an
+	// account by its email, nor have its claims matched later
+	guestIdP.SetUser(oidctest.User{Subject: "g-ada", Email: "ada@example.com", EmailVerified: true})
+	if login := signIn(t, app, "guest"); login.User.ID == ada.User.ID {
+		t.Error("untrusted provider signed in to the user with the same email")
+	}
+	guestIdP.SetUser(oidctest.User{Subject: "g-carol", Email: "carol@example.com", EmailVerified: true})
+	carol := signIn(t, app, "guest")
+	partnerIdP.SetUser(oidctest.User{Subject: "p-carol", Email: "carol@example.com", EmailVerified: true})
+	if login := signIn(t, app, "partner"); login.User.ID == carol.User.ID {
+		t.Error("trusted provider signed in to a user whose email only an untrusted provider verified")
+	}
+}
+
+func TestOIDCLinkAccount(t *testing.T) {
+	t.Parallel()
+	idp, corp := newIdP(t, "corp")
+	app := newApp(t, corp)
+
+	register := func(username string) controllers.RegisterResponse {
+		t.Helper()
+		w := do(t, app, http.MethodPost, "/api/v1/auth/register", "", controllers.RegisterRequest{Username: username, Password: "correct-Horse-7-battery"})
+		expectStatus(t, w, http.StatusCreated)
+		var registered controllers.RegisterResponse
+		decode(t, w, &registered)
+		return registered
+	}
+	// startLink starts linking in the browser with jar and returns the
+	// callback the provider redirects back to
+	startLink := func(jar http.CookieJar, token string) string {
+		t.Helper()
+		w := browse(t, app, jar, http.MethodPost, "/api/v1/auth/oidc/corp/link", token)
+		expectStatus(t, w, http.StatusOK)
+		var resp controllers.AuthorizationURLResponse
+		decode(t, w, &resp)
+		return authorize(t, resp.AuthorizationURL)
+	}
+	link := func(token string) *httptest.ResponseRecorder {
+		t.Helper()
+		jar := newBrowser(t)
+		return browse(t, app, jar, http.MethodGet, startLink(jar, token), "")
+	}
+
+	alice := register("alice")
+	carol := register("carol")
+
+	// An unverified email can still be linked by the signed-in user
+	idp.SetUser(oidctest.User{Subject: "alice-at-corp", Email: "alice@example.com"})
+	expectStatus(t, link(alice.Token), http.StatusOK)
+	if login := signIn(t, app, "corp"); login.User.ID != alice.User.ID {
+		t.Errorf("sign-in after linking as user %d, want %d", login.User.ID, alice.User.ID)
+	}
+
+	// Linking again is a no-op, linking to someone else a conflict
+	expectStatus(t, link(alice.Token), http.StatusOK)
+	expectStatus(t, link(carol.Token), http.StatusConflict)
+
+	// Carol cannot get someone else's browser to link their provider account
+	// to her by sending them the link she started
+	idp.SetUser(oidctest.User{Subject: "dave-at-corp", Email: "dave@example.com"})
+	carolsBrowser := newBrowser(t)
+	callback := startLink(carolsBrowser, carol.Token)
+	expectStatus(t, browse(t, app, newBrowser(t), http.MethodGet, callback, ""), http.StatusBadRequest)
+	if login := signIn(t, app, "corp"); login.User.ID == carol.User.ID {
+		t.Error("provider account linked from another browser")
+	}
+
+	expectStatus(t, do(t, app, http.MethodPost, "/api/v1/auth/oidc/corp/link", "", nil), http.StatusUnauthorized)
+}
+
+func TestOIDCGroupRoles(t *testing.T) {
+	t.Parallel()
+	mappedIdP, mapped := newIdP(t, "corp")
+	mapped.GroupRoles = map[string]string{"task-admins": "admin", "staff": "user"}
+	mapped.TrustEmail = true
+	plainIdP, plain := newIdP(t, "partner")
+	plain.TrustEmail = true
+	app := newApp(t, mapped, plain)
+
+	// The plain provider signs in the same verified email, and so the same
+	// user, but leaves the role to the app
+	plainIdP.SetUser(oidctest.User{Subject: "ada", Email: "ada@example.com", EmailVerified: true})
+
+	tests := []struct {
+		name     string
+		groups   []string
+		provider string
+		role     models.Role
+	}{
+		{"no groups", nil, "corp", models.UserRole},
+		{"admin group", []string{"staff", "task-admins"}, "corp", models.AdminRole},
+		{"unmapped provider keeps the role", nil, "partner", models.AdminRole},
+		{"removed from admin group", []string{"staff"}, "corp", models.UserRole},
+		{"unknown groups", []string{"task-admins-old", "Task-Admins"}, "corp", models.UserRole},
+		{"unmapped provider keeps the demotion", nil, "partner", models.UserRole},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mappedIdP.SetUser(oidctest.User{Subject: "ada", Email: "ada@example.com", EmailVerified: true, Groups: tt.groups})
+			login := signIn(t, app, tt.provider)
+			if login.User.Role != tt.role {
+				t.Fatalf("role = %q, want %q", login.User.Role, tt.role)
+			}
+
+			// The token carries the role
+			admin := http.StatusForbidden
+			if tt.role == models.AdminRole {
+				admin = http.StatusOK
+			}
+			expectStatus(t, do(t, app, http.MethodGet, "/api/v1/admin/mfa-policy", login.Token, nil), admin)
+		})
+	}
+}
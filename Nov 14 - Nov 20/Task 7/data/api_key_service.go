@@ -0,0 +1,181 @@
+package data
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"task_manager/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// apiKeyPrefix makes keys easy to recognize, e.g. by secret scanners.
+	apiKeyPrefix = "tm"
+
+	// lastUsedResolution limits how often a busy key's last-used time is
+	// written.
+	lastUsedResolution = time.Minute
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	ErrInvalidAPIKey  = errors.New("invalid or expired API key")
+	ErrUnknownScope   = errors.New("unknown scope")
+	ErrExpiryInPast   = errors.New("expiry must be in the future")
+)
+
+type APIKeyService struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyService(db *gorm.DB) *APIKeyService {
+	return &APIKeyService{db: db}
+}
+
+// CreateAPIKey creates a key for userID and returns it along with the
+// plain key, which is not stored and cannot be shown again. With no
+// scopes the key gets all of them; a nil expiresAt means it never expires.
+// mfa is whether the creating session used two-factor authentication.
+func (s *APIKeyService) CreateAPIKey(userID uint, name string, scopes []string, expiresAt *time.Time, mfa bool) (*models.APIKey, string, error) {
+	if len(scopes) == 0 {
+		scopes = models.AllScopes
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", ErrExpiryInPast
+	}
+
+	prefix, secret, err := newAPIKeyParts()
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := &models.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   hashAPIKeySecret(secret),
+		Scopes:    strings.Join(scopes, ","),
+		MFA:       mfa,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.db.Create(key).Error; err != nil {
+		return nil, "", err
+	}
+
+	return key, prefix + "_" + secret, nil
+}
+
+// ListAPIKeys returns userID's keys that have not been revoked, newest
+// first.
+func (s *APIKeyService) ListAPIKeys(userID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := s.db.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Find(&keys).Error
+	return keys, err
+}
+
+// RevokeAPIKey revokes one of userID's keys. Keys of other users are
+// reported as not found.
+func (s *APIKeyService) RevokeAPIKey(userID, id uint) error {
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.APIKey{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// Authenticate returns the key and its owner for a plain key, and records
+// that the key was used.
+func (s *APIKeyService) Authenticate(plainKey string) (*models.APIKey, *models.User, error) {
+	parts := strings.SplitN(plainKey, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyPrefix {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	var key models.APIKey
+	err := s.db.Where("prefix = ?", parts[0]+"_"+parts[1]).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	hash := hashAPIKeySecret(parts[2])
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(key.KeyHash)) != 1 || key.Expired(now) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	var user models.User
+	err = s.db.First(&user, key.UserID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+		if err := s.db.Model(&key).Update("last_used_at", now).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	return &key, &user, nil
+}
+
+func normalizeScopes(scopes []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var result []string
+	for _, scope := range scopes {
+		known := false
+		for _, s := range models.AllScopes {
+			if s == scope {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, ErrUnknownScope
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	return result, nil
+}
+
+// newAPIKeyParts returns a random prefix like "tm_559d83a7f190", which is
+// stored to look keys up, and a random secret, which is only stored hashed.
+func newAPIKeyParts() (prefix, secret string, err error) {
+	p := make([]byte, 6)
+	if _, err := rand.Read(p); err != nil {
+		return "", "", err
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	return apiKeyPrefix + "_" + hex.EncodeToString(p), base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashAPIKeySecret hashes a key secret. Secrets are 256 random bits, so
+// unlike passwords they need no slow hash.
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,191 @@
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"task_manager/models"
+)
+
+func newAPIKeyOwner(t *testing.T, s *APIKeyService, username string) *models.User {
+	t.Helper()
+	user := &models.User{Username: username, Password: "x", Role: models.UserRole}
+	if err := s.db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	s := NewAPIKeyService(newDB(t))
+	user := newAPIKeyOwner(t, s, "ada")
+
+	key, plain, err := s.CreateAPIKey(user.ID, "ci", nil, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.SplitN(plain, "_", 3)
+	if len(parts) != 3 || parts[0] != "tm" || key.Prefix != parts[0]+"_"+parts[1] {
+		t.Fatalf("key %q with prefix %q, want tm_<id>_<secret>", plain, key.Prefix)
+	}
+
+	// Only a hash of the secret is stored
+	var stored models.APIKey
+	if err := s.db.First(&stored, key.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(parts[2]))
+	if stored.KeyHash != hex.EncodeToString(sum[:]) || strings.Contains(stored.KeyHash, parts[2]) {
+		t.Errorf("stored hash %q is not the SHA-256 of the secret", stored.KeyHash)
+	}
+	if stored.Scopes != "tasks:read,tasks:write" || !stored.MFA {
+		t.Errorf("stored scopes %q, MFA %v, want all scopes and MFA", stored.Scopes, stored.MFA)
+	}
+
+	_, other, _ := s.CreateAPIKey(user.ID, "ci", nil, nil, true)
+	if other == plain {
+		t.Error("two keys are the same")
+	}
+}
+
+func TestCreateAPIKeyRejects(t *testing.T) {
+	s := NewAPIKeyService(newDB(t))
+	user := newAPIKeyOwner(t, s, "ada")
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name      string
+		scopes    []string
+		expiresAt *time.Time
+		err       error
+	}{
+		{"unknown scope", []string{"tasks:read", "admin"}, nil, ErrUnknownScope},
+		{"expiry in the past", nil, &past, ErrExpiryInPast},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := s.CreateAPIKey(user.ID, "ci", tt.scopes, tt.expiresAt, false); !errors.Is(err, tt.err) {
+				t.Errorf("error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	s := NewAPIKeyService(newDB(t))
+	user := newAPIKeyOwner(t, s, "ada")
+	other := newAPIKeyOwner(t, s, "grace")
+
+	create := func(expiresAt *time.Time) (*models.APIKey, string) {
+		key, plain, err := s.CreateAPIKey(user.ID, "ci", []string{models.ScopeTasksRead}, expiresAt, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key, plain
+	}
+	soon := time.Now().Add(time.Hour)
+	validKey, valid := create(&soon)
+	expired, expiredPlain := create(&soon)
+	if err := s.db.Model(expired).Update("expires_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	revoked, revokedPlain := create(nil)
+	if err := s.RevokeAPIKey(other.ID, revoked.ID); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Fatalf("revoking another user's key: error = %v, want %v", err, ErrAPIKeyNotFound)
+	}
+	if err := s.RevokeAPIKey(user.ID, revoked.ID); err != nil {
+		t.Fatal(err)
+	}
+	prefix := validKey.Prefix
+
+	tests := []struct {
+		name  string
+		plain string
+		valid bool
+	}{
+		{"valid", valid, true},
+		{"expired", expiredPlain, false},
+		{"revoked", revokedPlain, false},
+		{"wrong secret", prefix + "_" + strings.Repeat("A", 43), false},
+		{"unknown prefix", "tm_000000000000" + valid[len(prefix):], false},
+		{"wrong product prefix", "xx" + valid[2:], false},
+		{"malformed", "not-a-key", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, owner, err := s.Authenticate(tt.plain)
+			if !tt.valid {
+				if !errors.Is(err, ErrInvalidAPIKey) {
+					t.Errorf("error = %v, want %v", err, ErrInvalidAPIKey)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if owner.ID != user.ID || !key.HasScope(models.ScopeTasksRead) || key.HasScope(models.ScopeTasksWrite) {
+				t.Errorf("key %+v of user %d", key, owner.ID)
+			}
+		})
+	}
+
+	keys, err := s.ListAPIKeys(user.ID)
+	if err != nil || len(keys) != 2 {
+		t.Errorf("ListAPIKeys = %d keys, %v, want the 2 not revoked", len(keys), err)
+	}
+}
+
+func TestAuthenticateLastUsedThrottle(t *testing.T) {
+	s := NewAPIKeyService(newDB(t))
+	user := newAPIKeyOwner(t, s, "ada")
+	key, plain, err := s.CreateAPIKey(user.ID, "ci", nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastUsed := func() *time.Time {
+		var stored models.APIKey
+		if err := s.db.First(&stored, key.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return stored.LastUsedAt
+	}
+	setLastUsed := func(at time.Time) {
+		if err := s.db.Model(key).Update("last_used_at", at).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, _, err := s.Authenticate(plain); err != nil {
+		t.Fatal(err)
+	}
+	if lastUsed() == nil {
+		t.Fatal("first use not recorded")
+	}
+
+	tests := []struct {
+		name    string
+		ago     time.Duration
+		updated bool
+	}{
+		{"used within the resolution", lastUsedResolution / 2, false},
+		{"used a resolution ago", lastUsedResolution, true},
+		{"used long ago", 24 * time.Hour, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := time.Now().Add(-tt.ago)
+			setLastUsed(before)
+			if _, _, err := s.Authenticate(plain); err != nil {
+				t.Fatal(err)
+			}
+			if updated := lastUsed().After(before); updated != tt.updated {
+				t.Errorf("last_used_at updated = %v, want %v", updated, tt.updated)
+			}
+		})
+	}
+}
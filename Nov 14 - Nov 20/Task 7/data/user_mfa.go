@@ -0,0 +1,355 @@
+package data
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"task_manager/models"
+	"task_manager/totp"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultMFAIssuer is the account issuer authenticator apps display.
+	DefaultMFAIssuer = "Task Manager"
+
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+	recoveryAlphabet   = "abcdefghjkmnpqrstuvwxyz23456789"
+
+	maxMFAFailures = 5
+	mfaLockout     = 15 * time.Minute
+
+	mfaRequiredRolesKey = "mfa_required_roles"
+)
+
+var (
+	ErrMFANotEnrolled     = errors.New("two-factor authentication is not set up")
+	ErrMFAAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled      = errors.New("two-factor authentication is not enabled")
+	ErrInvalidMFACode     = errors.New("invalid verification code")
+	ErrMFALocked          = errors.New("too many failed verification attempts")
+	ErrUnknownRole        = errors.New("unknown role")
+	ErrMFARequiredForRole = errors.New("two-factor authentication is required for this role")
+)
+
+// SetMFAIssuer sets the issuer shown in authenticator apps.
+func (s *UserService) SetMFAIssuer(issuer string) {
+	s.mfaIssuer = issuer
+}
+
+// BeginMFAEnrollment generates a new TOTP secret for user and returns it
+// with its otpauth URI. Two-factor authentication stays off until the
+// secret is confirmed with ConfirmMFAEnrollment.
+func (s *UserService) BeginMFAEnrollment(user *models.User) (secret, uri string, err error) {
+	if user.MFAEnabled {
+		return "", "", ErrMFAAlreadyEnabled
+	}
+
+	secret, err = totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	err = s.db.Model(user).Updates(map[string]interface{}{
+		"totp_secret":    secret,
+		"totp_last_step": 0,
+	}).Error
+	if err != nil {
+		return "", "", err
+	}
+
+	return secret, totp.URI(s.mfaIssuer, user.Username, secret), nil
+}
+
+// ConfirmMFAEnrollment enables two-factor authentication if code matches the
+// pending secret and returns a fresh set of recovery codes. The codes are
+// not stored in plain text, so this is the only time they can be shown.
+func (s *UserService) ConfirmMFAEnrollment(user *models.User, code string) ([]string, error) {
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+	if err := s.VerifyTOTP(user, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("mfa_enabled", true).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	user.MFAEnabled = true
+	return codes, nil
+}
+
+// VerifyMFA checks a second factor for user, either a TOTP code or, if
+// code is empty, a recovery code, which is used up.
+func (s *UserService) VerifyMFA(user *models.User, code, recoveryCode string) error {
+	if !user.MFAEnabled {
+		return ErrMFANotEnabled
+	}
+	if code == "" {
+		return s.UseRecoveryCode(user, recoveryCode)
+	}
+	return s.VerifyTOTP(user, code)
+}
+
+// VerifyTOTP checks code against user's secret. Each code is accepted only
+// once, and repeated failures lock verification for a while.
+func (s *UserService) VerifyTOTP(user *models.User, code string) error {
+	if user.TOTPSecret == "" {
+		return ErrMFANotEnrolled
+	}
+	if err := s.checkMFALock(user); err != nil {
+		return err
+	}
+
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return s.recordMFAFailure(user)
+	}
+
+	// Conditional update so concurrent logins cannot replay the same code
+	result := s.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", user.ID, step).
+		Updates(map[string]interface{}{"totp_last_step": step, "mfa_failures": 0})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return s.recordMFAFailure(user)
+	}
+	user.TOTPLastStep = step
+	user.MFAFailures = 0
+	return nil
+}
+
+// UseRecoveryCode consumes one of user's unused recovery codes.
+func (s *UserService) UseRecoveryCode(user *models.User, code string) error {
+	if err := s.checkMFALock(user); err != nil {
+		return err
+	}
+
+	result := s.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return s.recordMFAFailure(user)
+	}
+	return s.db.Model(user).Update("mfa_failures", 0).Error
+}
+
+// RegenerateRecoveryCodes invalidates user's recovery codes and returns new
+// ones.
+func (s *UserService) RegenerateRecoveryCodes(user *models.User) ([]string, error) {
+	if !user.MFAEnabled {
+		return nil, ErrMFANotEnabled
+	}
+
+	var codes []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	return codes, err
+}
+
+// CountRecoveryCodes returns how many unused recovery codes user has left.
+func (s *UserService) CountRecoveryCodes(user *models.User) (int64, error) {
+	var count int64
+	err := s.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", user.ID).
+		Count(&count).Error
+	return count, err
+}
+
+// DisableMFA turns two-factor authentication off and removes the secret and
+// recovery codes. It fails if user's role requires two-factor
+// authentication.
+func (s *UserService) DisableMFA(user *models.User) error {
+	required, err := s.MFARequired(user.Role)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrMFARequiredForRole
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(user).Updates(map[string]interface{}{
+			"mfa_enabled":    false,
+			"totp_secret":    "",
+			"totp_last_step": 0,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+	user.MFAEnabled = false
+	user.TOTPSecret = ""
+	return nil
+}
+
+// MFARequiredRoles returns the roles whose users must use two-factor
+// authentication.
+func (s *UserService) MFARequiredRoles() ([]models.Role, error) {
+	var setting models.Setting
+	err := s.db.Where("key = ?", mfaRequiredRolesKey).First(&setting).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return []models.Role{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	roles := []models.Role{}
+	for _, r := range strings.Split(setting.Value, ",") {
+		if r != "" {
+			roles = append(roles, models.Role(r))
+		}
+	}
+	return roles, nil
+}
+
+// SetMFARequiredRoles replaces the roles whose users must use two-factor
+// authentication.
+func (s *UserService) SetMFARequiredRoles(roles []models.Role) error {
+	names := make([]string, 0, len(roles))
+	seen := make(map[models.Role]bool)
+	for _, r := range roles {
+		if r != models.AdminRole && r != models.UserRole {
+			return ErrUnknownRole
+		}
+		if !seen[r] {
+			seen[r] = true
+			names = append(names, string(r))
+		}
+	}
+
+	setting := models.Setting{Key: mfaRequiredRolesKey, Value: strings.Join(names, ",")}
+	return s.db.Save(&setting).Error
+}
+
+// MFARequired reports whether users with role must use two-factor
+// authentication.
+func (s *UserService) MFARequired(role models.Role) (bool, error) {
+	roles, err := s.MFARequiredRoles()
+	if err != nil {
+		return false, err
+	}
+	for _, r := range roles {
+		if r == role {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// checkMFALock reads the lock from the database, since user may have been
+// loaded before a concurrent request locked it.
+func (s *UserService) checkMFALock(user *models.User) error {
+	var current models.User
+	if err := s.db.Select("mfa_locked_until").First(&current, user.ID).Error; err != nil {
+		return err
+	}
+	user.MFALockedUntil = current.MFALockedUntil
+	if current.MFALockedUntil != nil && time.Now().Before(*current.MFALockedUntil) {
+		return ErrMFALocked
+	}
+	return nil
+}
+
+// recordMFAFailure counts a failed attempt, locks verification once there
+// are too many and returns the error to report. Both steps are single
+// statements, so concurrent failures are all counted and only one of them
+// sets the lock.
+func (s *UserService) recordMFAFailure(user *models.User) error {
+	err := s.db.Model(&models.User{}).Where("id = ?", user.ID).
+		UpdateColumn("mfa_failures", gorm.Expr("mfa_failures + 1")).Error
+	if err != nil {
+		return err
+	}
+
+	until := time.Now().Add(mfaLockout)
+	result := s.db.Model(&models.User{}).
+		Where("id = ? AND mfa_failures >= ?", user.ID, maxMFAFailures).
+		UpdateColumns(map[string]interface{}{"mfa_failures": 0, "mfa_locked_until": until})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		user.MFAFailures = 0
+		user.MFALockedUntil = &until
+	} else {
+		user.MFAFailures++
+	}
+	return ErrInvalidMFACode
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	records := make([]models.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(code)}
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// newRecoveryCode returns a code like "k7mq2-xr9vd". Ambiguous characters
+// are left out of the alphabet so codes are easy to copy from paper.
+func newRecoveryCode() (string, error) {
+	b := make([]byte, recoveryCodeLength)
+	max := big.NewInt(int64(len(recoveryAlphabet)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = recoveryAlphabet[n.Int64()]
+	}
+	half := recoveryCodeLength / 2
+	return string(b[:half]) + "-" + string(b[half:]), nil
+}
+
+// hashRecoveryCode normalizes case, spaces and dashes before hashing. The
+// codes are random, so a plain SHA-256 is enough.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,173 @@
+package data
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"task_manager/models"
+	"task_manager/totp"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.RecoveryCode{}, &models.Setting{}, &models.APIKey{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// newMFAUser stores a user with two-factor authentication enabled and
+// returns it with its recovery codes.
+func newMFAUser(t *testing.T, s *UserService, username string) (*models.User, []string) {
+	t.Helper()
+	user := &models.User{Username: username, Password: "x", Role: models.UserRole}
+	if err := s.db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	secret, _, err := s.BeginMFAEnrollment(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.TOTPSecret = secret
+	codes, err := s.ConfirmMFAEnrollment(user, codeAt(t, secret, totp.Step(time.Now())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user, codes
+}
+
+func codeAt(t *testing.T, secret string, step int64) string {
+	t.Helper()
+	code, err := totp.Code(secret, step)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func reload(t *testing.T, s *UserService, id uint) *models.User {
+	t.Helper()
+	var user models.User
+	if err := s.db.First(&user, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &user
+}
+
+func TestVerifyTOTPRejectsReplay(t *testing.T) {
+	s := NewUserService(newDB(t))
+	user, _ := newMFAUser(t, s, "ada")
+
+	// The enrollment code cannot be used again, only a later one
+	enrolled := user.TOTPLastStep
+	if err := s.VerifyTOTP(user, codeAt(t, user.TOTPSecret, enrolled)); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("enrollment code replayed: error = %v, want %v", err, ErrInvalidMFACode)
+	}
+	next := codeAt(t, user.TOTPSecret, enrolled+1)
+	if err := s.VerifyTOTP(user, next); err != nil {
+		t.Fatalf("next code: %v", err)
+	}
+
+	// A stale copy of the user must not let the same code through again
+	if err := s.VerifyTOTP(reload(t, s, user.ID), next); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("replayed code: error = %v, want %v", err, ErrInvalidMFACode)
+	}
+	if err := s.VerifyTOTP(user, codeAt(t, user.TOTPSecret, enrolled)); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("earlier step after a later one: error = %v, want %v", err, ErrInvalidMFACode)
+	}
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	s := NewUserService(newDB(t))
+	user, codes := newMFAUser(t, s, "ada")
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(codes), recoveryCodeCount)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		err   error
+	}{
+		{"as shown", codes[0], nil},
+		{"used twice", codes[0], ErrInvalidMFACode},
+		{"upper case without dash", strings.ToUpper(strings.ReplaceAll(codes[1], "-", "")), nil},
+		{"spaces instead of dash", " " + strings.ReplaceAll(codes[2], "-", " ") + " ", nil},
+		{"normalized form used twice", strings.ToUpper(codes[1]), ErrInvalidMFACode},
+		{"unknown code", "aaaaa-aaaaa", ErrInvalidMFACode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.VerifyMFA(user, "", tt.input); !errors.Is(err, tt.err) {
+				t.Errorf("VerifyMFA(%q) error = %v, want %v", tt.input, err, tt.err)
+			}
+		})
+	}
+
+	if left, err := s.CountRecoveryCodes(user); err != nil || left != recoveryCodeCount-3 {
+		t.Errorf("CountRecoveryCodes = %d, %v, want %d", left, err, recoveryCodeCount-3)
+	}
+
+	// Another user's codes do not work
+	other, _ := newMFAUser(t, s, "grace")
+	if err := s.UseRecoveryCode(other, codes[3]); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("another user's code: error = %v, want %v", err, ErrInvalidMFACode)
+	}
+}
+
+func TestMFALockout(t *testing.T) {
+	s := NewUserService(newDB(t))
+	user, codes := newMFAUser(t, s, "ada")
+
+	// Failures through separately loaded copies of the user all count
+	stale := reload(t, s, user.ID)
+	for i := 0; i < maxMFAFailures; i++ {
+		u := user
+		if i%2 == 1 {
+			u = stale
+		}
+		if err := s.VerifyTOTP(u, "000000"); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("attempt %d: error = %v, want %v", i+1, err, ErrInvalidMFACode)
+		}
+	}
+
+	// Locked for every copy and for both kinds of code, even correct ones
+	fresh := reload(t, s, user.ID)
+	if fresh.MFALockedUntil == nil || fresh.MFAFailures != 0 {
+		t.Fatalf("after %d failures: locked until %v with %d failures, want a lock and the count reset",
+			maxMFAFailures, fresh.MFALockedUntil, fresh.MFAFailures)
+	}
+	for name, u := range map[string]*models.User{"same copy": user, "stale copy": stale, "fresh copy": fresh} {
+		if err := s.VerifyTOTP(u, codeAt(t, u.TOTPSecret, totp.Step(time.Now())+1)); !errors.Is(err, ErrMFALocked) {
+			t.Errorf("%s, TOTP: error = %v, want %v", name, err, ErrMFALocked)
+		}
+		if err := s.UseRecoveryCode(u, codes[0]); !errors.Is(err, ErrMFALocked) {
+			t.Errorf("%s, recovery code: error = %v, want %v", name, err, ErrMFALocked)
+		}
+	}
+
+	// Once the lock expires, a correct code works and resets the count
+	if err := s.db.Model(&models.User{}).Where("id = ?", user.ID).
+		Update("mfa_locked_until", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.VerifyTOTP(stale, "000000"); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("after the lock: error = %v, want %v", err, ErrInvalidMFACode)
+	}
+	if err := s.UseRecoveryCode(user, codes[0]); err != nil {
+		t.Fatalf("after the lock: %v", err)
+	}
+	if failures := reload(t, s, user.ID).MFAFailures; failures != 0 {
+		t.Errorf("failures after a correct code = %d, want 0", failures)
+	}
+}
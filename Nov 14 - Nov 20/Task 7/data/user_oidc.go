@@ -0,0 +1,206 @@
+package data
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"task_manager/models"
+
+	"gorm.io/gorm"
+)
+
+// maxUsernameBase leaves room for a suffix when a username is taken.
+const maxUsernameBase = 20
+
+var ErrIdentityLinked = errors.New("this account is already linked to another user")
+
+// ExternalIdentity is a user as asserted by an identity provider.
+type ExternalIdentity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	// TrustEmail is whether the provider is trusted for linking accounts by
+	// email. Without it, EmailVerified is recorded but not acted on.
+	TrustEmail        bool
+	PreferredUsername string
+	// Role, if set, replaces the user's role at every sign-in, so the
+	// provider's groups stay authoritative.
+	Role models.Role
+}
+
+// SignInExternal returns the local user for an external identity. It uses
+// the user the identity is linked to, or else links it to the user of
+// another identity with the same verified email, if both providers are
+// trusted for that, or else creates a user.
+func (s *UserService) SignInExternal(identity ExternalIdentity) (*models.User, error) {
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var link models.Identity
+		err := tx.Where("issuer = ? AND subject = ?", identity.Issuer, identity.Subject).First(&link).Error
+		switch {
+		case err == nil:
+			if err := tx.First(&user, link.UserID).Error; err != nil {
+				return err
+			}
+			err := tx.Model(&link).Updates(map[string]interface{}{
+				"email":          strings.ToLower(identity.Email),
+				"email_verified": identity.EmailVerified,
+				"email_trusted":  identity.TrustEmail,
+			}).Error
+			if err != nil {
+				return err
+			}
+
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			found, err := findUserByVerifiedEmail(tx, identity)
+			if err != nil {
+				return err
+			}
+			if found != nil {
+				user = *found
+			} else if err := provisionUser(tx, identity, &user); err != nil {
+				return err
+			}
+			if err := createIdentity(tx, user.ID, identity); err != nil {
+				return err
+			}
+
+		default:
+			return err
+		}
+
+		if identity.Role != "" && identity.Role != user.Role {
+			return tx.Model(&user).Update("role", identity.Role).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// LinkExternal links an external identity to an existing user, so they can
+// sign in with either.
+func (s *UserService) LinkExternal(userID uint, identity ExternalIdentity) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var link models.Identity
+		err := tx.Where("issuer = ? AND subject = ?", identity.Issuer, identity.Subject).First(&link).Error
+		if err == nil {
+			if link.UserID != userID {
+				return ErrIdentityLinked
+			}
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if err := tx.First(&models.User{}, userID).Error; err != nil {
+			return err
+		}
+		return createIdentity(tx, userID, identity)
+	})
+}
+
+// findUserByVerifiedEmail returns the user linked to another identity with
+// the same verified email, or nil. Unverified emails are never matched, or
+// anyone could take over an account by claiming its email at a provider
+// that does not check. Nor are emails from untrusted providers, on either
+// side, since any provider can claim that an email is verified.
+func findUserByVerifiedEmail(tx *gorm.DB, identity ExternalIdentity) (*models.User, error) {
+	if !identity.EmailVerified || !identity.TrustEmail || identity.Email == "" {
+		return nil, nil
+	}
+
+	var link models.Identity
+	err := tx.Where("email = ? AND email_verified = ? AND email_trusted = ?", strings.ToLower(identity.Email), true, true).
+		Order("id").First(&link).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := tx.First(&user, link.UserID).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// provisionUser creates a user without a password for identity.
+func provisionUser(tx *gorm.DB, identity ExternalIdentity, user *models.User) error {
+	username, err := availableUsername(tx, identity)
+	if err != nil {
+		return err
+	}
+
+	*user = models.User{Username: username, Role: identity.Role}
+	if user.Role == "" {
+		user.Role = models.UserRole
+	}
+	return tx.Create(user).Error
+}
+
+func createIdentity(tx *gorm.DB, userID uint, identity ExternalIdentity) error {
+	return tx.Create(&models.Identity{
+		UserID:        userID,
+		Issuer:        identity.Issuer,
+		Subject:       identity.Subject,
+		Email:         strings.ToLower(identity.Email),
+		EmailVerified: identity.EmailVerified,
+		EmailTrusted:  identity.TrustEmail,
+	}).Error
+}
+
+// availableUsername derives a username from the identity's preferred
+// username or email, adding a number if it is taken.
+func availableUsername(tx *gorm.DB, identity ExternalIdentity) (string, error) {
+	base := sanitizeUsername(identity.PreferredUsername)
+	if base == "" {
+		local, _, _ := strings.Cut(identity.Email, "@")
+		base = sanitizeUsername(local)
+	}
+	if base == "" {
+		base = "user"
+	}
+
+	for i := 1; i <= 100; i++ {
+		candidate := base
+		if i > 1 {
+			candidate = fmt.Sprintf("%s%d", base, i)
+		}
+		var count int64
+		if err := tx.Model(&models.User{}).Unscoped().Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return base + "-" + hex.EncodeToString(suffix), nil
+}
+
+func sanitizeUsername(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '.' || r == '_' || r == '-' {
+			b.WriteRune(r)
+		}
+		if b.Len() == maxUsernameBase {
+			break
+		}
+	}
+	return b.String()
+}
@@ -2,22 +2,35 @@ package data
 
 import (
 	"task_manager/models"
+	"task_manager/password"
 
 	"gorm.io/gorm"
 )
 
 type UserService struct {
-	db *gorm.DB
+	db        *gorm.DB
+	policy    password.Policy
+	mfaIssuer string
 }
 
 func NewUserService(db *gorm.DB) *UserService {
-	return &UserService{db: db}
+	return &UserService{db: db, policy: password.DefaultPolicy, mfaIssuer: DefaultMFAIssuer}
 }
 
-func (s *UserService) CreateUser(username, password string, role models.Role) (*models.User, error) {
+// SetPasswordPolicy replaces the rules new passwords are checked against.
+func (s *UserService) SetPasswordPolicy(policy password.Policy) {
+	s.policy = policy
+}
+
+// CreateUser returns a *password.PolicyError if the password is too weak.
+func (s *UserService) CreateUser(username, plainPassword string, role models.Role) (*models.User, error) {
+	if err := s.policy.Validate(plainPassword, username); err != nil {
+		return nil, err
+	}
+
 	user := &models.User{
 		Username: username,
-		Password: password,
+		Password: plainPassword,
 		Role:     role,
 	}
 
@@ -61,3 +74,17 @@ func (s *UserService) CountUsers() (int64, error) {
 	err := s.db.Model(&models.User{}).Count(&count).Error
 	return count, err
 }
+
+// RehashPassword re-hashes the password of user, which must be the correct
+// one, if the stored hash is outdated.
+func (s *UserService) RehashPassword(user *models.User, plainPassword string) error {
+	if !user.NeedsRehash() {
+		return nil
+	}
+
+	user.Password = plainPassword
+	if err := user.HashPassword(); err != nil {
+		return err
+	}
+	return s.db.Model(user).Update("password", user.Password).Error
+}
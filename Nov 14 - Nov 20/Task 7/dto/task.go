@@ -0,0 +1,74 @@
+// Package dto defines the wire format of the versioned API and maps it to
+// and from the persistence models, so a change to a model does not change
+// what clients see and clients cannot write fields they do not own.
+package dto
+
+import (
+	"time"
+
+	"task_manager/models"
+)
+
+// Task is a task as returned by /api/v1.
+type Task struct {
+	ID          uint      `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Status      string    `json:"status"`
+	OwnerID     uint      `json:"owner_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CreateTaskRequest is the body for creating a task. The owner is always
+// the authenticated user.
+type CreateTaskRequest struct {
+	Title       string `json:"title" binding:"required"`
+	Description string `json:"description"`
+}
+
+// UpdateTaskRequest is the body for updating a task. It replaces the title
+// and description, and the status unless it is empty.
+type UpdateTaskRequest struct {
+	Title       string `json:"title" binding:"required"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+}
+
+func FromTask(task *models.Task) Task {
+	return Task{
+		ID:          task.ID,
+		Title:       task.Title,
+		Description: task.Description,
+		Status:      task.Status,
+		OwnerID:     task.UserID,
+		CreatedAt:   task.CreatedAt,
+		UpdatedAt:   task.UpdatedAt,
+	}
+}
+
+func FromTasks(tasks []models.Task) []Task {
+	result := make([]Task, len(tasks))
+	for i := range tasks {
+		result[i] = FromTask(&tasks[i])
+	}
+	return result
+}
+
+// ToTask returns a new task owned by userID.
+func (r CreateTaskRequest) ToTask(userID uint) *models.Task {
+	return &models.Task{
+		Title:       r.Title,
+		Description: r.Description,
+		UserID:      userID,
+	}
+}
+
+// ApplyTo copies the request's fields onto an existing task.
+func (r UpdateTaskRequest) ApplyTo(task *models.Task) {
+	task.Title = r.Title
+	task.Description = r.Description
+	if r.Status != "" {
+		task.Status = r.Status
+	}
+}
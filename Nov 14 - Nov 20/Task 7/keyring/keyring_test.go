@@ -0,0 +1,212 @@
+package keyring
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newRing returns a ring whose clock only moves when the returned function
+// is called.
+func newRing(t *testing.T, cfg Config) (*Ring, func(time.Duration)) {
+	t.Helper()
+	r, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := r.Current().ActiveAt
+	r.now = func() time.Time { return now }
+	return r, func(d time.Duration) {
+		now = now.Add(d)
+		if err := r.maintain(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func ids(set Set) []string {
+	out := make([]string, len(set.Keys))
+	for i, k := range set.Keys {
+		out[i] = k.KeyID
+	}
+	return out
+}
+
+func TestNewRejectsConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"unknown algorithm", Config{Algorithm: "HS256", RotateEvery: 10 * time.Hour, Overlap: 2 * time.Hour}},
+		{"no overlap", Config{Algorithm: AlgorithmEdDSA, RotateEvery: 10 * time.Hour}},
+		{"overlap as long as rotation", Config{Algorithm: AlgorithmEdDSA, RotateEvery: 2 * time.Hour, Overlap: 2 * time.Hour}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg); err == nil {
+				t.Error("New accepted the config")
+			}
+		})
+	}
+}
+
+func TestRotation(t *testing.T) {
+	r, advance := newRing(t, Config{Algorithm: AlgorithmEdDSA, RotateEvery: 10 * time.Hour, Overlap: 2 * time.Hour})
+	first := r.Current()
+
+	// Nothing happens until Overlap before the key is due
+	advance(8*time.Hour - time.Minute)
+	if got := ids(r.JWKS()); len(got) != 1 {
+		t.Fatalf("keys before the next one is due = %v, want only %s", got, first.ID)
+	}
+
+	// The next key is published Overlap early but not used yet
+	advance(time.Minute)
+	if got := ids(r.JWKS()); len(got) != 2 {
+		t.Fatalf("keys once the next one is due = %v, want two", got)
+	}
+	next := r.JWKS().Keys[1].KeyID
+	if r.Current() != first {
+		t.Error("next key used before it became active")
+	}
+	if _, ok := r.Lookup(next); !ok {
+		t.Error("published key is not accepted")
+	}
+	advance(time.Hour)
+	if got := ids(r.JWKS()); len(got) != 2 {
+		t.Errorf("keys while the next one is pending = %v, want two", got)
+	}
+
+	// It takes over RotateEvery after the first, and the first key is still
+	// accepted for Overlap
+	advance(time.Hour)
+	if r.Current().ID != next {
+		t.Fatalf("current key = %s, want %s", r.Current().ID, next)
+	}
+	advance(2*time.Hour - time.Minute)
+	if _, ok := r.Lookup(first.ID); !ok {
+		t.Error("replaced key rejected within the overlap")
+	}
+	advance(time.Minute)
+	if _, ok := r.Lookup(first.ID); ok {
+		t.Error("replaced key still accepted after the overlap")
+	}
+	if got := ids(r.JWKS()); len(got) != 1 || got[0] != next {
+		t.Errorf("keys after the overlap = %v, want only %s", got, next)
+	}
+}
+
+func TestRotateNow(t *testing.T) {
+	r, _ := newRing(t, Config{Algorithm: AlgorithmEdDSA, RotateEvery: 10 * time.Hour, Overlap: 2 * time.Hour})
+	old := r.Current()
+
+	key, err := r.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Current() != key || key.ID == old.ID {
+		t.Errorf("current key = %s after Rotate, want the new key %s", r.Current().ID, key.ID)
+	}
+	if _, ok := r.Lookup(old.ID); !ok {
+		t.Error("Rotate stopped accepting the previous key")
+	}
+}
+
+func TestAlgorithmChange(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{Algorithm: AlgorithmEdDSA, Dir: dir, RotateEvery: 10 * time.Hour, Overlap: 2 * time.Hour}
+	first, _ := newRing(t, cfg)
+	old := first.Current()
+
+	cfg.Algorithm = AlgorithmRS256
+	r, advance := newRing(t, cfg)
+	if r.Current().ID != old.ID {
+		t.Fatalf("current key = %s after changing the algorithm, want %s until the overlap ends", r.Current().ID, old.ID)
+	}
+	set := r.JWKS()
+	if len(set.Keys) != 2 || set.Keys[1].Algorithm != AlgorithmRS256 {
+		t.Fatalf("JWKS = %+v, want the old key and a published RS256 key", set)
+	}
+
+	// The clock started when the old key became active, a little before
+	// the RS256 key was published
+	advance(2*time.Hour + time.Minute)
+	if got := r.Current(); got.Algorithm != AlgorithmRS256 {
+		t.Errorf("current key algorithm = %s, want %s", got.Algorithm, AlgorithmRS256)
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{Algorithm: AlgorithmEdDSA, Dir: dir, RotateEvery: 10 * time.Hour, Overlap: 2 * time.Hour}
+	first, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := first.Current()
+
+	info, err := os.Stat(filepath.Join(dir, key.ID+".pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("key file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// Stray files are ignored
+	for name, data := range map[string]string{"notes.txt": "x", "bad id.pem": "x"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	again, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := again.Current()
+	if loaded.ID != key.ID || loaded.Algorithm != key.Algorithm || !loaded.ActiveAt.Equal(info.ModTime()) {
+		t.Errorf("loaded key %s (%s, active %v), want %s (%s, active %v)",
+			loaded.ID, loaded.Algorithm, loaded.ActiveAt, key.ID, key.Algorithm, info.ModTime())
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "broken.pem"), []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(cfg); err == nil {
+		t.Error("New accepted an unreadable key file")
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	for _, tt := range []struct {
+		algorithm string
+		keyType   string
+	}{
+		{AlgorithmEdDSA, "OKP"},
+		{AlgorithmRS256, "RSA"},
+	} {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			r, _ := newRing(t, Config{Algorithm: tt.algorithm, RotateEvery: 10 * time.Hour, Overlap: 2 * time.Hour})
+			set := r.JWKS()
+			if len(set.Keys) != 1 {
+				t.Fatalf("JWKS has %d keys, want 1", len(set.Keys))
+			}
+			jwk := set.Keys[0]
+			if jwk.KeyID != r.Current().ID || jwk.KeyType != tt.keyType || jwk.Algorithm != tt.algorithm || jwk.Use != "sig" {
+				t.Errorf("JWK = %+v", jwk)
+			}
+			switch tt.keyType {
+			case "OKP":
+				if jwk.Curve != "Ed25519" || jwk.X == "" {
+					t.Errorf("Ed25519 JWK = %+v", jwk)
+				}
+			case "RSA":
+				if jwk.E != "AQAB" || jwk.N == "" {
+					t.Errorf("RSA JWK = %+v", jwk)
+				}
+			}
+		})
+	}
+}
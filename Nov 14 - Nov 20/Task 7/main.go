@@ -1,24 +1,28 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 
 	"task_manager/controllers"
 	"task_manager/data"
+	"task_manager/keyring"
+	"task_manager/middleware"
 	"task_manager/models"
+	"task_manager/oidc"
+	"task_manager/password"
 	"task_manager/router"
 )
 
 func main() {
-	// Set JWT secret key
-	if os.Getenv("JWT_SECRET") == "" {
-		os.Setenv("JWT_SECRET", "your-secret-key") // Change this in production
-	}
-
 	// Initialize database
 	db, err := gorm.Open(sqlite.Open("task_manager.db"), &gorm.Config{})
 	if err != nil {
@@ -26,30 +30,59 @@ func main() {
 	}
 
 	// Auto-migrate the schema
-	if err := db.AutoMigrate(&models.User{}, &models.Task{}); err != nil {
+	if err := db.AutoMigrate(&models.User{}, &models.Task{}, &models.RecoveryCode{}, &models.Setting{}, &models.APIKey{}, &models.Identity{}); err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
 	// Initialize services
 	userService := data.NewUserService(db)
 	taskService := data.NewTaskService(db)
+	apiKeyService := data.NewAPIKeyService(db)
+	if issuer := os.Getenv("MFA_ISSUER"); issuer != "" {
+		userService.SetMFAIssuer(issuer)
+	}
+
+	// Load the token signing keys and rotate them in the background
+	keys, err := keyring.New(keyring.Config{
+		Algorithm:   getEnv("JWT_ALGORITHM", keyring.AlgorithmRS256),
+		Dir:         os.Getenv("JWT_KEYS_DIR"),
+		RotateEvery: getDurationEnv("JWT_KEY_ROTATION", 30*24*time.Hour),
+		Overlap:     getDurationEnv("JWT_KEY_OVERLAP", 25*time.Hour),
+	})
+	if err != nil {
+		log.Fatal("Failed to load signing keys:", err)
+	}
+	if os.Getenv("JWT_KEYS_DIR") == "" {
+		log.Println("Warning: JWT_KEYS_DIR not set; signing keys will not survive a restart")
+	}
+	keys.Start()
+	defer keys.Close()
+
+	tokens, err := middleware.NewTokenService(keys, getEnv("JWT_ISSUER", "task_manager"), getEnv("JWT_AUDIENCE", "task_manager"))
+	if err != nil {
+		log.Fatal("Invalid JWT configuration:", err)
+	}
 
 	// Initialize controllers
-	authController := controllers.NewAuthController(userService)
+	authController := controllers.NewAuthController(userService, tokens)
 	taskController := controllers.NewTaskController(taskService)
+	apiKeyController := controllers.NewAPIKeyController(apiKeyService)
+	for _, provider := range oidcProviders() {
+		authController.AddOIDCProvider(provider)
+	}
 
 	// Create admin user if not exists
 	createAdminIfNotExists(userService)
 
 	// Initialize router
-	r := router.SetupRouter(authController, taskController)
+	r := router.SetupRouter(authController, taskController, apiKeyController, middleware.AuthMiddleware(tokens, apiKeyService))
 
 	// Start server
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
-	
+
 	log.Printf("Server running on port %s\n", port)
 	if err := r.Run(":" + port); err != nil {
 		log.Fatal("Failed to start server:", err)
@@ -63,10 +96,116 @@ func createAdminIfNotExists(us *data.UserService) {
 		return // Admin already exists
 	}
 
+	// Use the configured password, or a random one that is logged once
+	adminPassword := os.Getenv("ADMIN_PASSWORD")
+	generated := adminPassword == ""
+	if generated {
+		adminPassword, err = randomPassword()
+		if err != nil {
+			log.Println("Warning: Failed to generate admin password:", err)
+			return
+		}
+	}
+
 	// Create admin user
-	if _, err := us.CreateUser("admin", "admin123", models.AdminRole); err != nil {
+	if _, err := us.CreateUser("admin", adminPassword, models.AdminRole); err != nil {
 		log.Println("Warning: Failed to create admin user:", err)
+	} else if generated {
+		log.Printf("Created default admin user (username: admin, password: %s)\n", adminPassword)
 	} else {
-		log.Println("Created default admin user (username: admin, password: admin123)")
+		log.Println("Created default admin user (username: admin, password from ADMIN_PASSWORD)")
+	}
+}
+
+// randomPassword returns a random password that satisfies the default policy
+func randomPassword() (string, error) {
+	for {
+		b := make([]byte, 18)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		p := base64.RawURLEncoding.EncodeToString(b)
+		if password.DefaultPolicy.Validate(p) == nil {
+			return p, nil
+		}
+	}
+}
+
+// oidcProviders reads the identity providers named in OIDC_PROVIDERS, e.g.
+// "corp,google", each configured by OIDC_<NAME>_* variables.
+func oidcProviders() []*oidc.Provider {
+	var providers []*oidc.Provider
+	for _, name := range splitList(os.Getenv("OIDC_PROVIDERS")) {
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		cfg := oidc.Config{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+			GroupsClaim:  os.Getenv(prefix + "GROUPS_CLAIM"),
+			GroupRoles:   make(map[string]string),
+			TrustEmail:   getBoolEnv(prefix+"TRUST_EMAIL", false),
+		}
+		if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+			log.Fatalf("OIDC provider %s needs %sISSUER, %sCLIENT_ID and %sREDIRECT_URL", name, prefix, prefix, prefix)
+		}
+
+		// e.g. "task-admins=admin,staff=user"
+		for _, mapping := range splitList(os.Getenv(prefix + "GROUP_ROLES")) {
+			group, role, ok := strings.Cut(mapping, "=")
+			if !ok || (models.Role(role) != models.AdminRole && models.Role(role) != models.UserRole) {
+				log.Fatalf("Invalid %sGROUP_ROLES entry %q", prefix, mapping)
+			}
+			cfg.GroupRoles[group] = role
+		}
+
+		providers = append(providers, oidc.NewProvider(cfg, nil))
+		log.Printf("Single sign-on enabled with %s (%s)\n", name, cfg.Issuer)
+	}
+	return providers
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// getBoolEnv reads a boolean such as "true" or "1" from the environment.
+func getBoolEnv(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return b
+}
+
+// getDurationEnv reads a duration such as "720h" from the environment.
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
 	}
+	return d
 }
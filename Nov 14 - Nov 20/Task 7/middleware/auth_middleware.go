@@ -1,42 +1,25 @@
 package middleware
 
 import (
+	"errors"
+	"log"
 	"net/http"
-	"os"
 	"strings"
+	"task_manager/data"
 	"task_manager/models"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtKey = []byte(os.Getenv("JWT_SECRET"))
-
-type Claims struct {
-	UserID   uint        `json:"user_id"`
-	Username string      `json:"username"`
-	Role     models.Role `json:"role"`
-	jwt.RegisteredClaims
-}
-
-func GenerateToken(user *models.User) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
-	claims := &Claims{
-		UserID:   user.ID,
-		Username: user.Username,
-		Role:     user.Role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtKey)
-}
-
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware accepts either a JWT in the Authorization header or an API
+// key in the X-API-Key header.
+func AuthMiddleware(tokens *TokenService, apiKeys *data.APIKeyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+			authenticateAPIKey(c, apiKeys, apiKey)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
@@ -45,13 +28,10 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		claims := &Claims{}
-
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
+		claims, err := tokens.Parse(tokenString)
 
-		if err != nil || !token.Valid {
+		// Challenge tokens only prove the password, not the second factor
+		if err != nil || claims.Purpose != "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
@@ -59,6 +39,66 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		c.Set("userID", claims.UserID)
 		c.Set("userRole", claims.Role)
+		c.Set("mfa", claims.MFA)
+		c.Next()
+	}
+}
+
+func authenticateAPIKey(c *gin.Context, apiKeys *data.APIKeyService, apiKey string) {
+	key, user, err := apiKeys.Authenticate(apiKey)
+	if errors.Is(err, data.ErrInvalidAPIKey) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired API key"})
+		c.Abort()
+		return
+	}
+	if err != nil {
+		log.Println("Warning: failed to check API key:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check API key"})
+		c.Abort()
+		return
+	}
+
+	// The role comes from the database, so demoting a user also limits
+	// their keys. A key only counts as a second factor if the session that
+	// created it used one, so a password-only login cannot mint keys that
+	// get past RequireMFA.
+	c.Set("userID", user.ID)
+	c.Set("userRole", user.Role)
+	c.Set("mfa", key.MFA)
+	c.Set("apiKeyID", key.ID)
+	c.Set("apiKeyScopes", key.ScopeList())
+	c.Next()
+}
+
+// RequireScope lets API keys through only if they have scope. Requests
+// authenticated with a JWT are not limited by scopes.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get("apiKeyScopes")
+		if !ok {
+			c.Next()
+			return
+		}
+		for _, s := range value.([]string) {
+			if s == scope {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "API key lacks scope " + scope})
+		c.Abort()
+	}
+}
+
+// RejectAPIKeys keeps API keys away from account management, so a leaked
+// key cannot be used to create more keys or change how the user signs in.
+func RejectAPIKeys() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("apiKeyID"); ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API keys cannot be used here"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
@@ -74,3 +114,32 @@ func AdminOnly() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequireMFA rejects tokens that were issued without two-factor
+// authentication when required reports that the user's role must use it.
+// Routes for setting up two-factor authentication must not use it, or
+// users could never comply.
+func RequireMFA(required func(models.Role) (bool, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool("mfa") {
+			c.Next()
+			return
+		}
+
+		role, _ := c.Get("userRole")
+		r, _ := role.(models.Role)
+		mustUseMFA, err := required(r)
+		if err != nil {
+			log.Println("Warning: failed to load MFA policy:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check MFA policy"})
+			c.Abort()
+			return
+		}
+		if mustUseMFA {
+			c.JSON(http.StatusForbidden, gin.H{"error": "two-factor authentication required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
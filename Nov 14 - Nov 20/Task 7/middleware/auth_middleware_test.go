@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"task_manager/data"
+	"task_manager/keyring"
+	"task_manager/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRequireMFA(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	adminsOnly := func(role models.Role) (bool, error) { return role == models.AdminRole, nil }
+	broken := func(models.Role) (bool, error) { return false, errors.New("database down") }
+
+	tests := []struct {
+		name     string
+		role     models.Role
+		mfa      bool
+		required func(models.Role) (bool, error)
+		status   int
+	}{
+		{"required, token without MFA", models.AdminRole, false, adminsOnly, http.StatusForbidden},
+		{"required, token with MFA", models.AdminRole, true, adminsOnly, http.StatusOK},
+		{"not required for the role", models.UserRole, false, adminsOnly, http.StatusOK},
+		{"policy cannot be loaded", models.UserRole, false, broken, http.StatusInternalServerError},
+		{"MFA token skips the policy", models.UserRole, true, broken, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/", func(c *gin.Context) {
+				c.Set("userRole", tt.role)
+				c.Set("mfa", tt.mfa)
+			}, RequireMFA(tt.required), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		scopes []string
+		status int
+	}{
+		{"login token", nil, http.StatusOK},
+		{"key with the scope", []string{models.ScopeTasksRead, models.ScopeTasksWrite}, http.StatusOK},
+		{"key without the scope", []string{models.ScopeTasksRead}, http.StatusForbidden},
+		{"key without scopes", []string{}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/", func(c *gin.Context) {
+				if tt.scopes != nil {
+					c.Set("apiKeyScopes", tt.scopes)
+				}
+			}, RequireScope(models.ScopeTasksWrite), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+// API keys count as two-factor authenticated only if the session that
+// created them was.
+func TestAPIKeyMFA(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.APIKey{}); err != nil {
+		t.Fatal(err)
+	}
+	admin := &models.User{Username: "ada", Password: "x", Role: models.AdminRole}
+	if err := db.Create(admin).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	apiKeys := data.NewAPIKeyService(db)
+	tokens := newTokenService(t, newKeyRing(t, keyring.AlgorithmEdDSA))
+	adminsNeedMFA := func(role models.Role) (bool, error) { return role == models.AdminRole, nil }
+	r := gin.New()
+	r.GET("/", AuthMiddleware(tokens, apiKeys), RequireMFA(adminsNeedMFA), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for _, tt := range []struct {
+		name   string
+		mfa    bool
+		status int
+	}{
+		{"created with MFA", true, http.StatusOK},
+		{"created without MFA", false, http.StatusForbidden},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, plain, err := apiKeys.CreateAPIKey(admin.ID, tt.name, nil, nil, tt.mfa)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-API-Key", plain)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
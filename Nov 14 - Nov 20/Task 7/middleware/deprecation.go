@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecation describes an API version that has been replaced.
+type Deprecation struct {
+	// Since is when the version was deprecated.
+	Since time.Time
+	// Sunset is when the version will be removed, or zero if that has not
+	// been decided.
+	Sunset time.Time
+	// Prefix is the path prefix of the deprecated version and Successor
+	// that of the version replacing it, e.g. "/api" and "/api/v1".
+	Prefix    string
+	Successor string
+}
+
+// Deprecated tells clients that they are using a deprecated API version,
+// with the Deprecation (RFC 9745) and Sunset (RFC 8594) headers and a link
+// to the same route in the successor version. To retire a version, put it
+// behind this middleware and register its routes as before.
+func Deprecated(d Deprecation) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(d.Since.Unix(), 10)
+	var sunset string
+	if !d.Sunset.IsZero() {
+		sunset = d.Sunset.UTC().Format(http.TimeFormat)
+	}
+
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		header.Set("Deprecation", deprecation)
+		if sunset != "" {
+			header.Set("Sunset", sunset)
+		}
+		if d.Successor != "" {
+			successor := d.Successor + strings.TrimPrefix(c.Request.URL.Path, d.Prefix)
+			header.Add("Link", "<"+successor+`>; rel="successor-version"`)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"task_manager/keyring"
+	"task_manager/models"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// PurposeMFAChallenge marks a token that only proves the password was
+// correct. It can be exchanged for a real token with a second factor but
+// is not accepted by AuthMiddleware.
+const PurposeMFAChallenge = "mfa_challenge"
+
+const (
+	tokenTTL        = 24 * time.Hour
+	mfaChallengeTTL = 5 * time.Minute
+	// clockSkew is how far apart server clocks may be.
+	clockSkew = 30 * time.Second
+)
+
+var ErrInvalidChallenge = errors.New("invalid or expired MFA challenge")
+
+type Claims struct {
+	UserID   uint        `json:"user_id"`
+	Username string      `json:"username"`
+	Role     models.Role `json:"role"`
+	// MFA is set when the user passed a second factor to get the token.
+	MFA     bool   `json:"mfa,omitempty"`
+	Purpose string `json:"purpose,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// TokenService issues and checks access tokens, signed with the current key
+// of a key ring.
+type TokenService struct {
+	keys     *keyring.Ring
+	issuer   string
+	audience string
+}
+
+// NewTokenService returns a TokenService for the given issuer and audience.
+// Replaced keys must stay valid for as long as the tokens they signed, so
+// the key overlap must be at least the token lifetime.
+func NewTokenService(keys *keyring.Ring, issuer, audience string) (*TokenService, error) {
+	if keys.Overlap() < tokenTTL {
+		return nil, fmt.Errorf("key overlap must be at least the token lifetime (%s)", tokenTTL)
+	}
+	return &TokenService{keys: keys, issuer: issuer, audience: audience}, nil
+}
+
+// GenerateToken issues an access token for user. mfa records whether the
+// user passed two-factor authentication.
+func (s *TokenService) GenerateToken(user *models.User, mfa bool) (string, error) {
+	return s.sign(&Claims{
+		UserID:   user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+		MFA:      mfa,
+	}, tokenTTL)
+}
+
+// GenerateMFAChallenge issues a short-lived token for a user who has
+// entered the right password but still has to pass two-factor
+// authentication.
+func (s *TokenService) GenerateMFAChallenge(user *models.User) (string, error) {
+	return s.sign(&Claims{
+		UserID:  user.ID,
+		Purpose: PurposeMFAChallenge,
+	}, mfaChallengeTTL)
+}
+
+// ParseMFAChallenge returns the user ID of a token from
+// GenerateMFAChallenge.
+func (s *TokenService) ParseMFAChallenge(tokenString string) (uint, error) {
+	claims, err := s.Parse(tokenString)
+	if err != nil || claims.Purpose != PurposeMFAChallenge {
+		return 0, ErrInvalidChallenge
+	}
+	return claims.UserID, nil
+}
+
+// Parse checks the signature, issuer, audience and validity period of a
+// token and returns its claims.
+func (s *TokenService) Parse(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.verificationKey,
+		jwt.WithValidMethods([]string{keyring.AlgorithmRS256, keyring.AlgorithmEdDSA}),
+		jwt.WithIssuer(s.issuer),
+		jwt.WithAudience(s.audience),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+
+	// exp and nbf are only checked when present, and all tokens we issue
+	// have them
+	if claims.ExpiresAt == nil || claims.NotBefore == nil {
+		return nil, jwt.ErrTokenRequiredClaimMissing
+	}
+	return claims, nil
+}
+
+// JWKS returns the public keys tokens can be verified with.
+func (s *TokenService) JWKS() keyring.Set {
+	return s.keys.JWKS()
+}
+
+func (s *TokenService) sign(claims *Claims, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Issuer:    s.issuer,
+		Subject:   strconv.FormatUint(uint64(claims.UserID), 10),
+		Audience:  jwt.ClaimStrings{s.audience},
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		NotBefore: jwt.NewNumericDate(now),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}
+
+	key := s.keys.Current()
+	token := jwt.NewWithClaims(key.Method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Signer())
+}
+
+// verificationKey picks the key named by the token's kid header, and only
+// if the token claims the algorithm that key is for.
+func (s *TokenService) verificationKey(token *jwt.Token) (interface{}, error) {
+	id, _ := token.Header["kid"].(string)
+	key, ok := s.keys.Lookup(id)
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, errors.New("signing algorithm does not match key")
+	}
+	return key.Public(), nil
+}
@@ -0,0 +1,220 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"task_manager/keyring"
+	"task_manager/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newKeyRing(t *testing.T, algorithm string) *keyring.Ring {
+	t.Helper()
+	keys, err := keyring.New(keyring.Config{
+		Algorithm:   algorithm,
+		RotateEvery: 30 * 24 * time.Hour,
+		Overlap:     25 * time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func newTokenService(t *testing.T, keys *keyring.Ring) *TokenService {
+	t.Helper()
+	tokens, err := NewTokenService(keys, "test-issuer", "test-audience")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens
+}
+
+// signed signs claims with key, naming kid in the header.
+func signed(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestParse(t *testing.T) {
+	keys := newKeyRing(t, keyring.AlgorithmEdDSA)
+	tokens := newTokenService(t, keys)
+	other := newKeyRing(t, keyring.AlgorithmRS256)
+	current := keys.Current()
+
+	now := time.Now()
+	claims := func(change func(c *jwt.RegisteredClaims)) *Claims {
+		c := &Claims{UserID: 7, Role: models.UserRole, RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "test-issuer",
+			Audience:  jwt.ClaimStrings{"test-audience"},
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+		}}
+		if change != nil {
+			change(&c.RegisteredClaims)
+		}
+		return c
+	}
+	sign := func(c *Claims) string {
+		return signed(t, current.Method(), current.Signer(), current.ID, c)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"valid", sign(claims(nil)), true},
+		{"nbf within clock skew", sign(claims(func(c *jwt.RegisteredClaims) {
+			c.NotBefore = jwt.NewNumericDate(now.Add(20 * time.Second))
+			c.IssuedAt = c.NotBefore
+		})), true},
+		{"one of several audiences", sign(claims(func(c *jwt.RegisteredClaims) {
+			c.Audience = jwt.ClaimStrings{"other", "test-audience"}
+		})), true},
+
+		{"wrong issuer", sign(claims(func(c *jwt.RegisteredClaims) { c.Issuer = "someone-else" })), false},
+		{"no issuer", sign(claims(func(c *jwt.RegisteredClaims) { c.Issuer = "" })), false},
+		{"wrong audience", sign(claims(func(c *jwt.RegisteredClaims) { c.Audience = jwt.ClaimStrings{"other"} })), false},
+		{"no audience", sign(claims(func(c *jwt.RegisteredClaims) { c.Audience = nil })), false},
+		{"expired", sign(claims(func(c *jwt.RegisteredClaims) {
+			c.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Minute))
+		})), false},
+		{"no expiry", sign(claims(func(c *jwt.RegisteredClaims) { c.ExpiresAt = nil })), false},
+		{"not yet valid", sign(claims(func(c *jwt.RegisteredClaims) {
+			c.NotBefore = jwt.NewNumericDate(now.Add(time.Minute))
+		})), false},
+		{"no nbf", sign(claims(func(c *jwt.RegisteredClaims) { c.NotBefore = nil })), false},
+		{"issued in the future", sign(claims(func(c *jwt.RegisteredClaims) {
+			c.IssuedAt = jwt.NewNumericDate(now.Add(time.Hour))
+		})), false},
+
+		{"no kid", signed(t, current.Method(), current.Signer(), "", claims(nil)), false},
+		{"unknown kid", signed(t, current.Method(), current.Signer(), "0123456789abcdef", claims(nil)), false},
+		{"signed by a key from another ring", signed(t, other.Current().Method(), other.Current().Signer(), current.ID, claims(nil)), false},
+		{"HS256 with the public key as secret", signed(t, jwt.SigningMethodHS256, []byte(current.Public().(ed25519.PublicKey)), current.ID, claims(nil)), false},
+		{"alg none", signed(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, current.ID, claims(nil)), false},
+		{"tampered", sign(claims(nil))[:20] + "x" + sign(claims(nil))[21:], false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := tokens.Parse(tt.token)
+			if tt.valid {
+				if err != nil {
+					t.Fatalf("Parse rejected a valid token: %v", err)
+				}
+				if parsed.UserID != 7 {
+					t.Errorf("UserID = %d, want 7", parsed.UserID)
+				}
+			} else if err == nil {
+				t.Error("Parse accepted the token")
+			}
+		})
+	}
+}
+
+// A key of one algorithm must not verify a token claiming another, even
+// when the kid matches.
+func TestParseAlgorithmMustMatchKey(t *testing.T) {
+	keys := newKeyRing(t, keyring.AlgorithmRS256)
+	tokens := newTokenService(t, keys)
+	current := keys.Current()
+
+	// Signed correctly, but the header claims a different algorithm than
+	// the RSA key is for
+	token := jwt.NewWithClaims(jwt.SigningMethodRS512, &Claims{UserID: 7, RegisteredClaims: jwt.RegisteredClaims{
+		Issuer:    "test-issuer",
+		Audience:  jwt.ClaimStrings{"test-audience"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		NotBefore: jwt.NewNumericDate(time.Now()),
+	}})
+	token.Header["kid"] = current.ID
+	s, err := token.SignedString(current.Signer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokens.Parse(s); err == nil {
+		t.Error("Parse accepted RS512 for an RS256 key")
+	}
+}
+
+func TestTokensAcrossRotation(t *testing.T) {
+	keys := newKeyRing(t, keyring.AlgorithmEdDSA)
+	tokens := newTokenService(t, keys)
+	user := &models.User{Username: "ada", Role: models.AdminRole}
+	user.ID = 3
+
+	before, err := tokens.GenerateToken(user, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keys.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	after, err := tokens.GenerateToken(user, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, token := range map[string]string{"old key": before, "new key": after} {
+		claims, err := tokens.Parse(token)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if claims.UserID != 3 || claims.Role != models.AdminRole || claims.Subject != "3" {
+			t.Errorf("%s: claims = %+v", name, claims)
+		}
+	}
+	if claims, _ := tokens.Parse(before); claims == nil || !claims.MFA {
+		t.Error("MFA claim lost")
+	}
+}
+
+func TestMFAChallenge(t *testing.T) {
+	tokens := newTokenService(t, newKeyRing(t, keyring.AlgorithmEdDSA))
+	user := &models.User{Username: "grace", Role: models.UserRole}
+	user.ID = 5
+
+	challenge, err := tokens.GenerateMFAChallenge(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := tokens.ParseMFAChallenge(challenge); err != nil || id != 5 {
+		t.Errorf("ParseMFAChallenge = %d, %v, want 5", id, err)
+	}
+
+	access, err := tokens.GenerateToken(user, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokens.ParseMFAChallenge(access); err != ErrInvalidChallenge {
+		t.Errorf("ParseMFAChallenge(access token) error = %v, want %v", err, ErrInvalidChallenge)
+	}
+}
+
+func TestNewTokenServiceNeedsOverlap(t *testing.T) {
+	keys, err := keyring.New(keyring.Config{
+		Algorithm:   keyring.AlgorithmEdDSA,
+		RotateEvery: 30 * 24 * time.Hour,
+		Overlap:     time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTokenService(keys, "test-issuer", "test-audience"); err == nil {
+		t.Error("NewTokenService accepted an overlap shorter than the token lifetime")
+	}
+}
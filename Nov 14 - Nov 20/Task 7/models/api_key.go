@@ -0,0 +1,55 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Scopes an API key can be limited to.
+const (
+	ScopeTasksRead  = "tasks:read"
+	ScopeTasksWrite = "tasks:write"
+)
+
+// AllScopes lists every scope, which is what a key gets if none are asked
+// for.
+var AllScopes = []string{ScopeTasksRead, ScopeTasksWrite}
+
+// APIKey lets scripts act as a user without their password. The key itself
+// is only shown when it is created; Prefix identifies it afterwards and
+// KeyHash verifies it. MFA records whether the session that created the key
+// used two-factor authentication, which requests with the key then count
+// as. Revoked keys are soft-deleted.
+type APIKey struct {
+	gorm.Model
+	UserID     uint   `gorm:"not null;index"`
+	Name       string `gorm:"not null"`
+	Prefix     string `gorm:"not null;uniqueIndex"`
+	KeyHash    string `gorm:"not null"`
+	Scopes     string `gorm:"not null"`
+	MFA        bool   `gorm:"not null;default:false"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+}
+
+func (k *APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
@@ -0,0 +1,19 @@
+package models
+
+import "gorm.io/gorm"
+
+// Identity links a user to an account at an OpenID Connect provider. The
+// issuer and subject together identify the account; the email can change.
+// EmailTrusted is whether the provider was trusted for linking accounts by
+// email when the email was last seen.
+// Users created through single sign-on have an empty Password and cannot
+// log in with one.
+type Identity struct {
+	gorm.Model
+	UserID        uint   `gorm:"not null;index"`
+	Issuer        string `gorm:"not null;uniqueIndex:idx_identity_issuer_subject"`
+	Subject       string `gorm:"not null;uniqueIndex:idx_identity_issuer_subject"`
+	Email         string `gorm:"index"`
+	EmailVerified bool   `gorm:"not null;default:false"`
+	EmailTrusted  bool   `gorm:"not null;default:false"`
+}
@@ -1,7 +1,10 @@
 package models
 
 import (
-	"golang.org/x/crypto/bcrypt"
+	"time"
+
+	"task_manager/password"
+
 	"gorm.io/gorm"
 )
 
@@ -17,18 +20,31 @@ type User struct {
 	Username string `gorm:"unique;not null"`
 	Password string `gorm:"not null"`
 	Role     Role   `gorm:"type:varchar(20);default:'user'"`
+
+	// Two-factor authentication. TOTPSecret is set on enrollment and
+	// MFAEnabled once the user has confirmed it with a code.
+	TOTPSecret     string     `json:"-"`
+	MFAEnabled     bool       `gorm:"not null;default:false"`
+	TOTPLastStep   int64      `json:"-" gorm:"not null;default:0"`
+	MFAFailures    int        `json:"-" gorm:"not null;default:0"`
+	MFALockedUntil *time.Time `json:"-"`
 }
 
 func (u *User) HashPassword() error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+	hashedPassword, err := password.Hash(u.Password)
 	if err != nil {
 		return err
 	}
-	u.Password = string(hashedPassword)
+	u.Password = hashedPassword
 	return nil
 }
 
-func (u *User) CheckPassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
-	return err == nil
+func (u *User) CheckPassword(plain string) bool {
+	return password.Verify(u.Password, plain)
+}
+
+// NeedsRehash reports whether the stored hash uses an outdated algorithm or
+// parameters and should be replaced the next time the password is known.
+func (u *User) NeedsRehash() bool {
+	return password.NeedsRehash(u.Password)
 }
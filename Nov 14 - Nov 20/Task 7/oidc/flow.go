@@ -0,0 +1,124 @@
+package oidc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+// LoginTTL is how long a user has to finish signing in at the provider.
+const LoginTTL = 10 * time.Minute
+
+// RandomString returns a URL-safe string with 256 random bits, for use as
+// a state, nonce or PKCE verifier.
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Challenge returns the S256 PKCE challenge for verifier (RFC 7636).
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// PendingLogin is what has to be remembered between sending the user to the
+// provider and the provider sending them back.
+type PendingLogin struct {
+	Provider string
+	Verifier string
+	Nonce    string
+	// LinkUserID is set when a signed-in user is linking the provider
+	// account to their own, rather than signing in with it.
+	LinkUserID uint
+	ExpiresAt  time.Time
+}
+
+// sealedLogin is a pending login together with the hash of its state, which
+// binds the login to the browser that holds the cookie.
+type sealedLogin struct {
+	PendingLogin
+	StateHash []byte
+}
+
+// PendingLogins seals pending logins into cookies the browser brings back
+// to the callback, so unauthenticated logins take up no memory on the
+// server. The key is random per PendingLogins, so every login has to finish
+// on the instance that started it.
+type PendingLogins struct {
+	aead cipher.AEAD
+}
+
+func NewPendingLogins() *PendingLogins {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return &PendingLogins{aead: aead}
+}
+
+// Start seals login and returns the state to send to the provider and the
+// cookie value the callback needs along with it.
+func (s *PendingLogins) Start(login PendingLogin) (state, cookie string, err error) {
+	state, err = RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	login.ExpiresAt = time.Now().Add(LoginTTL)
+
+	plaintext, err := json.Marshal(sealedLogin{PendingLogin: login, StateHash: hashState(state)})
+	if err != nil {
+		return "", "", err
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", "", err
+	}
+	sealed := s.aead.Seal(nonce, nonce, plaintext, nil)
+	return state, base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Finish returns the login sealed in cookie if it was started with state
+// and has not expired. A callback from a browser other than the one that
+// started the login has no matching cookie and is rejected.
+func (s *PendingLogins) Finish(state, cookie string) (PendingLogin, bool) {
+	sealed, err := base64.RawURLEncoding.DecodeString(cookie)
+	if err != nil || len(sealed) < s.aead.NonceSize() {
+		return PendingLogin{}, false
+	}
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	plaintext, err := s.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return PendingLogin{}, false
+	}
+
+	var login sealedLogin
+	if err := json.Unmarshal(plaintext, &login); err != nil {
+		return PendingLogin{}, false
+	}
+	if subtle.ConstantTimeCompare(login.StateHash, hashState(state)) != 1 || time.Now().After(login.ExpiresAt) {
+		return PendingLogin{}, false
+	}
+	return login.PendingLogin, true
+}
+
+func hashState(state string) []byte {
+	sum := sha256.Sum256([]byte(state))
+	return sum[:]
+}
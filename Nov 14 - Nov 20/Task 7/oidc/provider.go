@@ -0,0 +1,495 @@
+// Package oidc signs users in with an OpenID Connect provider using the
+// authorization code flow with PKCE.
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	maxResponseBytes = 1 << 20
+	// jwksRefreshInterval limits how often an unknown kid makes us fetch
+	// the provider's keys again.
+	jwksRefreshInterval = time.Minute
+	clockSkew           = 30 * time.Second
+)
+
+var (
+	ErrDiscovery    = errors.New("oidc: provider discovery failed")
+	ErrExchange     = errors.New("oidc: code exchange failed")
+	ErrInvalidToken = errors.New("oidc: invalid ID token")
+)
+
+var idTokenAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// Config describes one identity provider.
+type Config struct {
+	// Name identifies the provider in URLs, e.g. /api/v1/auth/oidc/{Name}/login.
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Scopes defaults to openid, email and profile.
+	Scopes []string
+	// GroupsClaim is the ID token claim that lists the user's groups.
+	// Defaults to "groups".
+	GroupsClaim string
+	// GroupRoles maps provider groups to local role names.
+	GroupRoles map[string]string
+	// TrustEmail lets a verified email from this provider sign in to the
+	// user of another trusted provider's account with the same email. Only
+	// set it for providers that control which emails their users can claim.
+	TrustEmail bool
+}
+
+// Identity is what the provider asserts about a signed-in user.
+type Identity struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Name              string
+	Groups            []string
+	// AMR lists the authentication methods the provider used, e.g. "mfa".
+	AMR []string
+}
+
+// Provider talks to one OpenID Connect provider. Discovery happens on first
+// use, so a provider that is down at startup does not stop the server.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+
+	mu          sync.Mutex
+	meta        *metadata
+	keys        map[string]jwk
+	keysFetched time.Time
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type jwk struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+}
+
+// NewProvider returns a Provider for cfg. A nil client uses one with a 10
+// second timeout.
+func NewProvider(cfg Config, client *http.Client) *Provider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Provider{cfg: cfg, client: client}
+}
+
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *Provider) Issuer() string {
+	return p.cfg.Issuer
+}
+
+func (p *Provider) RedirectURL() string {
+	return p.cfg.RedirectURL
+}
+
+// TrustsEmail reports whether the provider is trusted for linking accounts
+// by email.
+func (p *Provider) TrustsEmail() bool {
+	return p.cfg.TrustEmail
+}
+
+// AuthCodeURL returns the URL to send the user to. state and nonce must be
+// random and remembered for the callback, as must the PKCE verifier that
+// challenge was derived from.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, challenge string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+	query := u.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.cfg.ClientID)
+	query.Set("redirect_uri", p.cfg.RedirectURL)
+	query.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", challenge)
+	query.Set("code_challenge_method", "S256")
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// Exchange redeems an authorization code and returns the identity in the
+// verified ID token.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	var resp struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.doJSON(req, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrExchange, err)
+	}
+	if status != http.StatusOK || resp.IDToken == "" {
+		return nil, fmt.Errorf("%w: %s %s", ErrExchange, resp.Error, resp.ErrorDescription)
+	}
+
+	return p.verifyIDToken(ctx, resp.IDToken, nonce)
+}
+
+// Roles returns the local roles the identity's groups map to.
+func (p *Provider) Roles(identity *Identity) []string {
+	var roles []string
+	for _, group := range identity.Groups {
+		if role, ok := p.cfg.GroupRoles[group]; ok {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// MapsGroups reports whether group-to-role mapping is configured, in which
+// case the provider decides users' roles.
+func (p *Provider) MapsGroups() bool {
+	return len(p.cfg.GroupRoles) > 0
+}
+
+func (p *Provider) verifyIDToken(ctx context.Context, raw, nonce string) (*Identity, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		return p.verificationKey(ctx, token)
+	},
+		jwt.WithValidMethods(idTokenAlgorithms),
+		jwt.WithIssuer(p.cfg.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	// Decode the registered and standard claims into a struct, leaving
+	// the groups claim, whose name is configurable, in the map
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	var std struct {
+		Subject           string           `json:"sub"`
+		Expiry            *jwt.NumericDate `json:"exp"`
+		Audience          jwt.ClaimStrings `json:"aud"`
+		AuthorizedParty   string           `json:"azp"`
+		Nonce             string           `json:"nonce"`
+		Email             string           `json:"email"`
+		EmailVerified     interface{}      `json:"email_verified"`
+		PreferredUsername string           `json:"preferred_username"`
+		Name              string           `json:"name"`
+		AMR               []string         `json:"amr"`
+	}
+	if err := json.Unmarshal(data, &std); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	switch {
+	case std.Subject == "":
+		return nil, fmt.Errorf("%w: missing sub", ErrInvalidToken)
+	case std.Expiry == nil:
+		return nil, fmt.Errorf("%w: missing exp", ErrInvalidToken)
+	case std.Nonce != nonce:
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidToken)
+	case len(std.Audience) > 1 && std.AuthorizedParty != p.cfg.ClientID:
+		return nil, fmt.Errorf("%w: token was issued to another client", ErrInvalidToken)
+	}
+
+	return &Identity{
+		Issuer:            p.cfg.Issuer,
+		Subject:           std.Subject,
+		Email:             std.Email,
+		EmailVerified:     isTrue(std.EmailVerified),
+		PreferredUsername: std.PreferredUsername,
+		Name:              std.Name,
+		Groups:            stringList(claims[p.cfg.GroupsClaim]),
+		AMR:               std.AMR,
+	}, nil
+}
+
+// verificationKey finds the provider key a token was signed with,
+// fetching the provider's keys again if the kid is new.
+func (p *Provider) verificationKey(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key, ok := p.lookupKey(kid)
+	if !ok && time.Since(p.keysFetched) >= jwksRefreshInterval {
+		if err := p.fetchKeys(ctx); err != nil {
+			return nil, err
+		}
+		key, ok = p.lookupKey(kid)
+	}
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	if key.Algorithm != "" && key.Algorithm != token.Method.Alg() {
+		return nil, errors.New("signing algorithm does not match key")
+	}
+	return key.publicKey()
+}
+
+// lookupKey finds a key by kid. Tokens without a kid are accepted only if
+// the provider has a single key.
+func (p *Provider) lookupKey(kid string) (jwk, bool) {
+	if kid == "" {
+		if len(p.keys) == 1 {
+			for _, k := range p.keys {
+				return k, true
+			}
+		}
+		return jwk{}, false
+	}
+	k, ok := p.keys[kid]
+	return k, ok
+}
+
+func (p *Provider) fetchKeys(ctx context.Context) error {
+	meta, err := p.discoverLocked(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, meta.JWKSURI, nil)
+	if err != nil {
+		return err
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	status, err := p.doJSON(req, &set)
+	if err != nil || status != http.StatusOK {
+		return fmt.Errorf("%w: fetching keys: status %d %v", ErrDiscovery, status, err)
+	}
+
+	keys := make(map[string]jwk, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use == "" || k.Use == "sig" {
+			keys[k.KeyID] = k
+		}
+	}
+	p.keys = keys
+	p.keysFetched = time.Now()
+	return nil
+}
+
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.discoverLocked(ctx)
+}
+
+func (p *Provider) discoverLocked(ctx context.Context) (*metadata, error) {
+	if p.meta != nil {
+		return p.meta, nil
+	}
+
+	wellKnown := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, err
+	}
+	var meta metadata
+	status, err := p.doJSON(req, &meta)
+	if err != nil || status != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d %v", ErrDiscovery, status, err)
+	}
+
+	// The issuer in the document must be exactly the configured one, or
+	// ID tokens could be accepted from a different issuer (RFC 8414)
+	if meta.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("%w: issuer %q does not match %q", ErrDiscovery, meta.Issuer, p.cfg.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: incomplete provider metadata", ErrDiscovery)
+	}
+
+	p.meta = &meta
+	return p.meta, nil
+}
+
+func (p *Provider) doJSON(req *http.Request, v interface{}) (int, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return resp.StatusCode, err
+	}
+	return resp.StatusCode, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// isTrue accepts both true and "true"; some providers send booleans as
+// strings.
+func isTrue(v interface{}) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case string:
+		return b == "true"
+	}
+	return false
+}
+
+// stringList reads a claim that holds either a list of strings or a single
+// string.
+func stringList(v interface{}) []string {
+	switch list := v.(type) {
+	case string:
+		return []string{list}
+	case []interface{}:
+		var result []string
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
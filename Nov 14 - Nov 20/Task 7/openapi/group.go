@@ -0,0 +1,90 @@
+package openapi
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Group registers routes with a gin router group and documents them in a
+// Spec at the same time. Tags and security set on a group apply to every
+// route and subgroup registered through it, as does deprecation.
+type Group struct {
+	gin        *gin.RouterGroup
+	spec       *Spec
+	tags       []string
+	security   []string
+	deprecated bool
+}
+
+// NewGroup wraps group.
+func NewGroup(group *gin.RouterGroup, spec *Spec) *Group {
+	return &Group{gin: group, spec: spec}
+}
+
+// Group creates a subgroup, as gin.RouterGroup.Group does.
+func (g *Group) Group(relativePath string, handlers ...gin.HandlerFunc) *Group {
+	sub := *g
+	sub.gin = g.gin.Group(relativePath, handlers...)
+	return &sub
+}
+
+// Use adds middleware, as gin.RouterGroup.Use does.
+func (g *Group) Use(middleware ...gin.HandlerFunc) {
+	g.gin.Use(middleware...)
+}
+
+// Tags returns a copy of the group whose routes get tags.
+func (g *Group) Tags(tags ...string) *Group {
+	sub := *g
+	sub.tags = tags
+	return &sub
+}
+
+// Security returns a copy of the group whose routes need one of the
+// security schemes.
+func (g *Group) Security(schemes ...string) *Group {
+	sub := *g
+	sub.security = schemes
+	return &sub
+}
+
+// Deprecated returns a copy of the group whose routes are documented as
+// deprecated.
+func (g *Group) Deprecated() *Group {
+	sub := *g
+	sub.deprecated = true
+	return &sub
+}
+
+// Handle registers and documents a route.
+func (g *Group) Handle(method, relativePath string, op Operation, handlers ...gin.HandlerFunc) {
+	g.gin.Handle(method, relativePath, handlers...)
+
+	op.Deprecated = op.Deprecated || g.deprecated
+	if op.Tags == nil {
+		op.Tags = g.tags
+	}
+	if op.Security == nil {
+		op.Security = g.security
+	}
+	fullPath := path.Join(g.gin.BasePath(), relativePath)
+	g.spec.Add(method, fullPath, op)
+}
+
+func (g *Group) GET(relativePath string, op Operation, handlers ...gin.HandlerFunc) {
+	g.Handle(http.MethodGet, relativePath, op, handlers...)
+}
+
+func (g *Group) POST(relativePath string, op Operation, handlers ...gin.HandlerFunc) {
+	g.Handle(http.MethodPost, relativePath, op, handlers...)
+}
+
+func (g *Group) PUT(relativePath string, op Operation, handlers ...gin.HandlerFunc) {
+	g.Handle(http.MethodPut, relativePath, op, handlers...)
+}
+
+func (g *Group) DELETE(relativePath string, op Operation, handlers ...gin.HandlerFunc) {
+	g.Handle(http.MethodDelete, relativePath, op, handlers...)
+}
@@ -0,0 +1,27 @@
+package openapi
+
+import (
+	_ "embed"
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed swagger.html
+var swaggerHTML string
+
+var swaggerTemplate = template.Must(template.New("swagger").Parse(swaggerHTML))
+
+// SwaggerUI returns a handler serving a Swagger UI page for the document at
+// specURL. The page loads Swagger UI itself from a CDN.
+func SwaggerUI(title, specURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		swaggerTemplate.Execute(c.Writer, map[string]string{
+			"Title":   title,
+			"SpecURL": specURL,
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package password
+
+import "strings"
+
+// CommonPasswords are passwords, and bases of passwords such as
+// "password" in "Password123!", that show up at the top of every leak.
+var CommonPasswords = strings.Fields(`
+123456 1234567 12345678 123456789 1234567890 0123456789 111111 000000
+121212 123123 654321 666666 696969 112233 123321 987654321 11111111
+password passw0rd p@ssw0rd p@ssword pass password1 qwerty qwertyuiop
+qwerty123 asdfgh asdfghjkl zxcvbnm zxcvbn 1q2w3e4r 1q2w3e4r5t qazwsx
+1qaz2wsx abc123 abcd1234 abcdef aa123456 a123456 iloveyou letmein
+welcome welcome1 admin administrator root toor changeme default guest
+login master secret trustno1 monkey dragon football baseball soccer
+hockey basketball superman batman starwars pokemon princess sunshine
+shadow michael jordan jennifer hunter killer charlie freedom whatever
+computer internet samsung google apple summer winter spring autumn
+flower cookie chocolate cheese pepper ginger butterfly mustang ferrari
+liverpool chelsea arsenal barcelona madrid matrix hello hello123
+loveme lovely love123 mypass mypassword passpass test test123 testing
+temp temp123 user user123 demo demo123 service support qwer1234 azerty
+solo access blahblah ninja tigger buster thomas robert daniel andrew
+joshua ashley jessica nicole amanda taylor austin harley ranger
+`)
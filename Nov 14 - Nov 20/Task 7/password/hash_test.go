@@ -0,0 +1,111 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheap keeps the tests fast; only the parameters differ from DefaultParams.
+var cheap = Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestHashAndVerify(t *testing.T) {
+	hash, err := cheap.Hash("Correct-Horse-42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("hash = %q, want the PHC argon2id format", hash)
+	}
+	if !Verify(hash, "Correct-Horse-42") {
+		t.Error("Verify rejected the right password")
+	}
+	if Verify(hash, "correct-horse-42") {
+		t.Error("Verify accepted the wrong password")
+	}
+
+	again, err := cheap.Hash("Correct-Horse-42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == hash {
+		t.Error("two hashes of the same password are equal; the salt is not random")
+	}
+}
+
+func TestVerifyBcrypt(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(string(hash), "old-password") {
+		t.Error("Verify rejected a bcrypt hash of the right password")
+	}
+	if Verify(string(hash), "new-password") {
+		t.Error("Verify accepted the wrong password for a bcrypt hash")
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	current, err := cheap.Hash("Correct-Horse-42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("Correct-Horse-42"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashWith := func(p Params) string {
+		hash, err := p.Hash("Correct-Horse-42")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+	with := func(change func(p *Params)) Params {
+		p := cheap
+		change(&p)
+		return p
+	}
+
+	tests := []struct {
+		name string
+		hash string
+		want bool
+	}{
+		{"current parameters", current, false},
+		{"bcrypt", string(bcryptHash), true},
+		{"less memory", hashWith(with(func(p *Params) { p.Memory = 32 })), true},
+		{"fewer iterations", strings.Replace(current, "t=1", "t=2", 1), true},
+		{"other parallelism", hashWith(with(func(p *Params) { p.Parallelism = 2 })), true},
+		{"shorter salt", hashWith(with(func(p *Params) { p.SaltLength = 8 })), true},
+		{"shorter key", hashWith(with(func(p *Params) { p.KeyLength = 16 })), true},
+		{"not a hash", "plaintext", true},
+		{"other algorithm", strings.Replace(current, "argon2id", "argon2i", 1), true},
+		{"other version", strings.Replace(current, "v=19", "v=16", 1), true},
+		{"zero cost", strings.Replace(current, "t=1", "t=0", 1), true},
+		{"bad salt", current[:strings.LastIndex(current, "$")-1] + "!" + current[strings.LastIndex(current, "$"):], true},
+		{"missing key", current[:strings.LastIndex(current, "$")+1], true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cheap.NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("NeedsRehash(%q) = %v, want %v", tt.hash, got, tt.want)
+			}
+		})
+	}
+
+	// Hashes with outdated parameters still verify until they are replaced
+	if !Verify(hashWith(with(func(p *Params) { p.Memory = 32 })), "Correct-Horse-42") {
+		t.Error("Verify rejected a hash with other parameters")
+	}
+	if !NeedsRehash(current) {
+		t.Error("package NeedsRehash accepted parameters other than DefaultParams")
+	}
+	for _, malformed := range []string{"plaintext", current[:strings.LastIndex(current, "$")+1]} {
+		if Verify(malformed, "Correct-Horse-42") {
+			t.Errorf("Verify accepted malformed hash %q", malformed)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package password
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type Policy struct {
+	MinLength int
+	// MaxLength of 0 means no limit.
+	MaxLength int
+	// MinClasses is how many of lower case, upper case, digits and symbols
+	// a password must mix.
+	MinClasses int
+	// Blocklist entries are matched case-insensitively against the password
+	// and against the password with trailing digits and symbols removed.
+	Blocklist []string
+	// RejectIdentity refuses passwords containing the username or email
+	// passed to Validate.
+	RejectIdentity bool
+}
+
+var DefaultPolicy = Policy{
+	MinLength:      10,
+	MaxLength:      128,
+	MinClasses:     3,
+	Blocklist:      CommonPasswords,
+	RejectIdentity: true,
+}
+
+// PolicyError lists every rule a password breaks.
+type PolicyError struct {
+	Violations []string
+}
+
+func (e *PolicyError) Error() string {
+	return "password does not meet the policy: " + strings.Join(e.Violations, "; ")
+}
+
+// Validate returns a *PolicyError if password breaks the policy. identities
+// are the user's own names, such as username and email, which the password
+// may not contain.
+func (p Policy) Validate(password string, identities ...string) error {
+	var violations []string
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, "must be at least "+strconv.Itoa(p.MinLength)+" characters")
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, "must be at most "+strconv.Itoa(p.MaxLength)+" characters")
+	}
+	if classes := characterClasses(password); classes < p.MinClasses {
+		violations = append(violations, "must mix at least "+strconv.Itoa(p.MinClasses)+" of lower case, upper case, digits and symbols")
+	}
+
+	lower := strings.ToLower(password)
+	if isBlocked(lower, p.Blocklist) {
+		violations = append(violations, "is too common")
+	}
+
+	if p.RejectIdentity {
+		for _, identity := range identityParts(identities) {
+			if strings.Contains(lower, identity) {
+				violations = append(violations, "must not contain your username or email")
+				break
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	classes := 0
+	for _, has := range []bool{lower, upper, digit, symbol} {
+		if has {
+			classes++
+		}
+	}
+	return classes
+}
+
+func isBlocked(lower string, blocklist []string) bool {
+	base := strings.TrimRightFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, blocked := range blocklist {
+		blocked = strings.ToLower(blocked)
+		if lower == blocked || base == blocked {
+			return true
+		}
+	}
+	return false
+}
+
+// identityParts lower-cases identities and adds the local part of emails.
+// Parts shorter than three characters are too likely to occur by chance.
+func identityParts(identities []string) []string {
+	var parts []string
+	for _, identity := range identities {
+		identity = strings.ToLower(strings.TrimSpace(identity))
+		candidates := []string{identity}
+		if local, _, found := strings.Cut(identity, "@"); found {
+			candidates = append(candidates, local)
+		}
+		for _, part := range candidates {
+			if utf8.RuneCountInString(part) >= 3 {
+				parts = append(parts, part)
+			}
+		}
+	}
+	return parts
+}
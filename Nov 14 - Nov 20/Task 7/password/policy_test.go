@@ -0,0 +1,71 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	identities := []string{"alice", "jdoe@example.com"}
+
+	tests := []struct {
+		name       string
+		password   string
+		violations []string
+	}{
+		{"strong", "Correct-Horse-42", nil},
+		{"three classes without symbols", "CorrectHorse42", nil},
+		{"too short", "Sh0rt-pw", []string{"at least 10 characters"}},
+		{"too long", "Aa1-" + strings.Repeat("x", 125), []string{"at most 128 characters"}},
+		{"length counts characters, not bytes", "Ünïcödé-1", []string{"at least 10 characters"}},
+		{"too few classes", "correcthorsebattery", []string{"mix at least 3"}},
+		{"common password", "Password123!", []string{"too common"}},
+		{"common password in other case", "QWERTYUIOP", []string{"mix at least 3", "too common"}},
+		{"contains the username", "Alice-Secure-42", []string{"username or email"}},
+		{"contains the email local part", "My-jdoe-Pass-9", []string{"username or email"}},
+		{"contains the email", "x-JDoe@Example.com-1", []string{"username or email"}},
+		{"every violation reported", "alice", []string{"at least 10 characters", "mix at least 3", "username or email"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DefaultPolicy.Validate(tt.password, identities...)
+			if tt.violations == nil {
+				if err != nil {
+					t.Fatalf("Validate(%q) = %v, want nil", tt.password, err)
+				}
+				return
+			}
+
+			var policyErr *PolicyError
+			if !errors.As(err, &policyErr) {
+				t.Fatalf("Validate(%q) = %v, want a *PolicyError", tt.password, err)
+			}
+			if len(policyErr.Violations) != len(tt.violations) {
+				t.Fatalf("violations = %q, want %d matching %q", policyErr.Violations, len(tt.violations), tt.violations)
+			}
+			for i, want := range tt.violations {
+				if !strings.Contains(policyErr.Violations[i], want) {
+					t.Errorf("violation %d = %q, want it to mention %q", i, policyErr.Violations[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateIdentityParts(t *testing.T) {
+	// Identities shorter than three characters would match by chance
+	if err := DefaultPolicy.Validate("Al-Strong-Pass-9", "al", "al@example.com"); err != nil {
+		t.Errorf("short identity rejected the password: %v", err)
+	}
+
+	lenient := DefaultPolicy
+	lenient.RejectIdentity = false
+	if err := lenient.Validate("Alice-Secure-42", "alice"); err != nil {
+		t.Errorf("identity checked with RejectIdentity off: %v", err)
+	}
+
+	if err := (Policy{}).Validate("a"); err != nil {
+		t.Errorf("zero Policy rejected a password: %v", err)
+	}
+}
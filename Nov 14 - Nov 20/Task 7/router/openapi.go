@@ -0,0 +1,61 @@
+package router
+
+import (
+	"net/http"
+
+	"task_manager/controllers"
+	"task_manager/models"
+	"task_manager/openapi"
+
+	"gorm.io/gorm"
+)
+
+// Security schemes protected routes accept.
+const (
+	bearerAuth = "bearerAuth"
+	apiKeyAuth = "apiKeyAuth"
+)
+
+func newSpec() *openapi.Spec {
+	spec := openapi.New(openapi.Info{
+		Title:   "Task Manager API",
+		Version: "1.0.0",
+	})
+
+	spec.AddSecurityScheme(bearerAuth, openapi.SecurityScheme{
+		Type:         "http",
+		Scheme:       "bearer",
+		BearerFormat: "JWT",
+		Description:  "An access token from login.",
+	})
+	spec.AddSecurityScheme(apiKeyAuth, openapi.SecurityScheme{
+		Type:        "apiKey",
+		In:          "header",
+		Name:        "X-API-Key",
+		Description: "A personal API key. Only accepted by the task endpoints.",
+	})
+
+	// Types that do not marshal as their fields
+	spec.SetSchema(gorm.DeletedAt{}, &openapi.Schema{Type: []string{"string", "null"}, Format: "date-time"})
+	spec.SetSchema(models.Role(""), &openapi.Schema{Type: "string", Enum: []interface{}{models.AdminRole, models.UserRole}})
+
+	return spec
+}
+
+// idParam is the numeric ID in routes like /tasks/:id.
+var idParam = openapi.Parameter{Name: "id", In: "path", Schema: uint(0)}
+
+func errorResponse(status int, description string) openapi.Response {
+	return openapi.Response{Status: status, Description: description, Body: controllers.ErrorResponse{}}
+}
+
+// protected lists the responses of an authenticated route: the successful
+// one with body, the given errors, and those of the auth middleware.
+func protected(status int, body interface{}, errors ...openapi.Response) []openapi.Response {
+	responses := []openapi.Response{{Status: status, Body: body}}
+	responses = append(responses, errors...)
+	return append(responses,
+		errorResponse(http.StatusUnauthorized, "Missing or invalid credentials"),
+		errorResponse(http.StatusForbidden, "Not allowed, e.g. missing scope, role or two-factor authentication"),
+	)
+}
@@ -1,42 +1,333 @@
 package router
 
 import (
+	"net/http"
+	"time"
+
 	"task_manager/controllers"
+	"task_manager/dto"
+	"task_manager/keyring"
 	"task_manager/middleware"
+	"task_manager/models"
+	"task_manager/openapi"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRouter(authController *controllers.AuthController, taskController *controllers.TaskController) *gin.Engine {
+// SetupRouter registers all routes. The API is served under /api/v1, and
+// under /api as it was before versioning, with deprecation headers.
+//
+// Routes are registered through openapi.Group, which documents them in the
+// OpenAPI document served at /openapi.json. Routes registered with gin
+// directly are not documented; the router tests fail if there are any, so
+// the document cannot fall behind the code.
+func SetupRouter(authController *controllers.AuthController, taskController *controllers.TaskController, apiKeyController *controllers.APIKeyController, authMiddleware gin.HandlerFunc) *gin.Engine {
+	r, _ := setupRouter(authController, taskController, apiKeyController, authMiddleware)
+	return r
+}
+
+// undocumentedRoutes are left out of the OpenAPI document on purpose.
+var undocumentedRoutes = []string{"GET /openapi.json", "GET /docs"}
+
+// setupRouter is SetupRouter, also returning the OpenAPI document.
+func setupRouter(authController *controllers.AuthController, taskController *controllers.TaskController, apiKeyController *controllers.APIKeyController, authMiddleware gin.HandlerFunc) (*gin.Engine, *openapi.Spec) {
 	r := gin.Default()
+	spec := newSpec()
+	root := openapi.NewGroup(&r.RouterGroup, spec)
+
+	root.Tags("Auth").GET("/.well-known/jwks.json", openapi.Operation{
+		Summary:     "Token signing keys",
+		Description: "The public keys access tokens can be verified with, as a JSON Web Key Set.",
+		Responses:   []openapi.Response{{Status: http.StatusOK, Body: keyring.Set{}}},
+	}, authController.JWKS)
+
+	rt := &routes{
+		auth:           authController,
+		tasks:          taskController,
+		apiKeys:        apiKeyController,
+		authMiddleware: authMiddleware,
+	}
+
+	// The current version
+	rt.register(root.Group("/api/v1"), rt.tasksV1)
+
+	// The unversioned API from before /api/v1, kept for existing clients.
+	// Everything but tasks is the same as in v1.
+	rt.register(root.Group("/api", middleware.Deprecated(legacyAPI)).Deprecated(), rt.legacyTasks)
+
+	// API documentation, which is in undocumentedRoutes
+	r.GET("/openapi.json", spec.ServeJSON)
+	r.GET("/docs", openapi.SwaggerUI(spec.Document().Info.Title, "/openapi.json"))
+
+	return r, spec
+}
+
+// legacyAPI is the deprecation of the unversioned /api routes.
+var legacyAPI = middleware.Deprecation{
+	Since:     time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+	Prefix:    "/api",
+	Successor: "/api/v1",
+}
+
+// routes registers a version of the API. Versions share every route except
+// the task routes, which each version registers with its own handlers so
+// they can differ in wire format.
+type routes struct {
+	auth           *controllers.AuthController
+	tasks          *controllers.TaskController
+	apiKeys        *controllers.APIKeyController
+	authMiddleware gin.HandlerFunc
+}
+
+// taskRoutes registers the task routes of a version on tasks. read and
+// write check the API key scopes.
+type taskRoutes func(tasks *openapi.Group, read, write gin.HandlerFunc)
 
+// register registers a version of the API on api. authMiddleware
+// authenticates everything except registration and login.
+func (rt *routes) register(api *openapi.Group, registerTasks taskRoutes) {
 	// Auth routes
-	auth := r.Group("/api/auth")
+	auth := api.Group("/auth").Tags("Auth")
 	{
-		auth.POST("/register", authController.Register)
-		auth.POST("/login", authController.Login)
+		auth.POST("/register", openapi.Operation{
+			Summary:     "Register",
+			Description: "Creates a user. The first user becomes an admin.",
+			Request:     controllers.RegisterRequest{},
+			Responses: []openapi.Response{
+				{Status: http.StatusCreated, Body: controllers.RegisterResponse{}},
+				errorResponse(http.StatusBadRequest, "Invalid request, username taken or password too weak"),
+			},
+		}, rt.auth.Register)
+		auth.POST("/login", openapi.Operation{
+			Summary:     "Log in",
+			Description: "Users with two-factor authentication enabled get an MFA challenge instead of a token.",
+			Request:     controllers.LoginRequest{},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: openapi.OneOf(controllers.LoginResponse{}, controllers.MFAChallengeResponse{})},
+				errorResponse(http.StatusBadRequest, "Invalid request"),
+				errorResponse(http.StatusUnauthorized, "Invalid credentials"),
+			},
+		}, rt.auth.Login)
+		auth.POST("/login/mfa", openapi.Operation{
+			Summary:     "Complete an MFA login",
+			Description: "Exchanges an MFA challenge and exactly one of code or recovery_code for a token.",
+			Request:     controllers.MFALoginRequest{},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: controllers.LoginResponse{}},
+				errorResponse(http.StatusBadRequest, "Invalid request"),
+				errorResponse(http.StatusUnauthorized, "Invalid challenge or code"),
+				errorResponse(http.StatusTooManyRequests, "Too many failed attempts"),
+			},
+		}, rt.auth.LoginMFA)
+	}
+
+	// Single sign-on
+	sso := auth.Tags("Single sign-on")
+	{
+		sso.GET("/oidc", openapi.Operation{
+			Summary:   "List identity providers",
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: []controllers.OIDCProviderResponse{}}},
+		}, rt.auth.ListOIDCProviders)
+		sso.GET("/oidc/:provider/login", openapi.Operation{
+			Summary:     "Sign in with an identity provider",
+			Description: "Redirects the browser to the provider.",
+			Responses: []openapi.Response{
+				{Status: http.StatusFound, Description: "Redirect to the provider", Headers: map[string]string{"Location": "The provider's authorization URL"}},
+				errorResponse(http.StatusNotFound, "Unknown provider"),
+				errorResponse(http.StatusBadGateway, "Provider unavailable"),
+			},
+		}, rt.auth.OIDCLogin)
+		sso.GET("/oidc/:provider/callback", openapi.Operation{
+			Summary:     "Identity provider callback",
+			Description: "Where the provider sends the browser back to. Signs the user in like a login, or finishes linking.",
+			Parameters: []openapi.Parameter{
+				{Name: "state", In: "query", Required: true},
+				{Name: "code", In: "query"},
+				{Name: "error", In: "query", Description: "Set by the provider if sign-in failed"},
+			},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: openapi.OneOf(controllers.LoginResponse{}, controllers.MFAChallengeResponse{}, controllers.MessageResponse{})},
+				errorResponse(http.StatusBadRequest, "Invalid or expired state"),
+				{Status: http.StatusUnauthorized, Description: "Sign-in failed", Body: openapi.OneOf(controllers.OIDCErrorResponse{}, controllers.ErrorResponse{})},
+				errorResponse(http.StatusNotFound, "Unknown provider"),
+				errorResponse(http.StatusConflict, "Account linked to another user"),
+			},
+		}, rt.auth.OIDCCallback)
 	}
 
 	// Protected routes
-	api := r.Group("/api")
-	api.Use(middleware.AuthMiddleware())
+	authed := api.Group("").Security(bearerAuth, apiKeyAuth)
+	authed.Use(rt.authMiddleware)
 	{
+		// Two-factor setup stays reachable for users who are required to
+		// enable it but have not yet
+		mfa := authed.Group("/auth/mfa", middleware.RejectAPIKeys()).Tags("Two-factor authentication").Security(bearerAuth)
+		{
+			mfa.POST("/enroll", openapi.Operation{
+				Summary:   "Start MFA enrollment",
+				Responses: protected(http.StatusOK, controllers.MFAEnrollmentResponse{}, errorResponse(http.StatusConflict, "MFA already enabled")),
+			}, rt.auth.EnrollMFA)
+			mfa.POST("/confirm", openapi.Operation{
+				Summary:     "Confirm MFA enrollment",
+				Description: "Enables two-factor authentication and returns recovery codes and a token with the mfa claim.",
+				Request:     controllers.MFACodeRequest{},
+				Responses: protected(http.StatusOK, controllers.RecoveryCodesResponse{},
+					errorResponse(http.StatusBadRequest, "Code missing"),
+					errorResponse(http.StatusConflict, "Not enrolled or already enabled"),
+					errorResponse(http.StatusTooManyRequests, "Too many failed attempts")),
+			}, rt.auth.ConfirmMFA)
+			mfa.POST("/recovery-codes", openapi.Operation{
+				Summary:     "Regenerate recovery codes",
+				Description: "Needs exactly one of code or recovery_code. Replaces all recovery codes.",
+				Request:     controllers.MFACodeRequest{},
+				Responses: protected(http.StatusOK, controllers.RecoveryCodesResponse{},
+					errorResponse(http.StatusBadRequest, "Code missing"),
+					errorResponse(http.StatusConflict, "MFA not enabled"),
+					errorResponse(http.StatusTooManyRequests, "Too many failed attempts")),
+			}, rt.auth.RegenerateRecoveryCodes)
+			mfa.POST("/disable", openapi.Operation{
+				Summary:     "Disable MFA",
+				Description: "Needs exactly one of code or recovery_code.",
+				Request:     controllers.MFACodeRequest{},
+				Responses: protected(http.StatusNoContent, nil,
+					errorResponse(http.StatusBadRequest, "Code missing"),
+					errorResponse(http.StatusConflict, "MFA not enabled"),
+					errorResponse(http.StatusTooManyRequests, "Too many failed attempts")),
+			}, rt.auth.DisableMFA)
+		}
+
+		// Applies only to routes registered after this point
+		authed.Use(middleware.RequireMFA(rt.auth.MFARequired))
+
+		// Admin routes
+		admin := authed.Group("/admin", middleware.RejectAPIKeys(), middleware.AdminOnly()).Tags("Admin").Security(bearerAuth)
+		{
+			admin.GET("/mfa-policy", openapi.Operation{
+				Summary:   "Get the MFA policy",
+				Responses: protected(http.StatusOK, controllers.MFAPolicyResponse{}),
+			}, rt.auth.GetMFAPolicy)
+			admin.PUT("/mfa-policy", openapi.Operation{
+				Summary:     "Set the MFA policy",
+				Description: "Sets the roles that must use two-factor authentication.",
+				Request:     controllers.MFAPolicyRequest{},
+				Responses:   protected(http.StatusOK, controllers.MFAPolicyResponse{}, errorResponse(http.StatusBadRequest, "Unknown role")),
+			}, rt.auth.UpdateMFAPolicy)
+		}
+
 		// User routes
-		users := api.Group("/users")
+		users := authed.Group("/users", middleware.RejectAPIKeys()).Tags("Admin").Security(bearerAuth)
 		{
-			users.POST("/:id/promote", middleware.AdminOnly(), authController.PromoteUser)
+			users.POST("/:id/promote", openapi.Operation{
+				Summary:    "Promote a user to admin",
+				Parameters: []openapi.Parameter{idParam},
+				Responses:  protected(http.StatusOK, controllers.MessageResponse{}, errorResponse(http.StatusBadRequest, "Invalid user ID")),
+			}, middleware.AdminOnly(), rt.auth.PromoteUser)
 		}
 
-		// Task routes
-		tasks := api.Group("/tasks")
+		// Linking a provider account to the signed-in user
+		authed.Tags("Single sign-on").Security(bearerAuth).POST("/auth/oidc/:provider/link", openapi.Operation{
+			Summary:     "Link an identity provider account",
+			Description: "Returns the URL at which to sign in to the provider to link that account to the current user.",
+			Responses: protected(http.StatusOK, controllers.AuthorizationURLResponse{},
+				errorResponse(http.StatusNotFound, "Unknown provider"),
+				errorResponse(http.StatusBadGateway, "Provider unavailable")),
+		}, middleware.RejectAPIKeys(), rt.auth.LinkOIDC)
+
+		// API key routes
+		keys := authed.Group("/keys", middleware.RejectAPIKeys()).Tags("API keys").Security(bearerAuth)
 		{
-			tasks.GET("", taskController.GetAllTasks)
-			tasks.GET("/:id", taskController.GetTask)
-			tasks.POST("", taskController.CreateTask)
-			tasks.PUT("/:id", taskController.UpdateTask)
-			tasks.DELETE("/:id", taskController.DeleteTask)
+			keys.GET("", openapi.Operation{
+				Summary:   "List API keys",
+				Responses: protected(http.StatusOK, []controllers.APIKeyResponse{}),
+			}, rt.apiKeys.ListAPIKeys)
+			keys.POST("", openapi.Operation{
+				Summary:     "Create an API key",
+				Description: "The response is the only time the key itself is shown. Without scopes the key gets all of them.",
+				Request:     controllers.CreateAPIKeyRequest{},
+				Responses:   protected(http.StatusCreated, controllers.APIKeyResponse{}, errorResponse(http.StatusBadRequest, "Invalid request, unknown scope or expiry in the past")),
+			}, rt.apiKeys.CreateAPIKey)
+			keys.DELETE("/:id", openapi.Operation{
+				Summary:    "Revoke an API key",
+				Parameters: []openapi.Parameter{idParam},
+				Responses:  protected(http.StatusNoContent, nil, errorResponse(http.StatusNotFound, "API key not found")),
+			}, rt.apiKeys.RevokeAPIKey)
 		}
+		// Task routes
+		read := middleware.RequireScope(models.ScopeTasksRead)
+		write := middleware.RequireScope(models.ScopeTasksWrite)
+		registerTasks(authed.Group("/tasks").Tags("Tasks"), read, write)
 	}
+}
 
-	return r
+func (rt *routes) tasksV1(tasks *openapi.Group, read, write gin.HandlerFunc) {
+	tasks.GET("", openapi.Operation{
+		Summary:   "List tasks",
+		Scopes:    []string{models.ScopeTasksRead},
+		Responses: protected(http.StatusOK, []dto.Task{}),
+	}, read, rt.tasks.GetAllTasks)
+	tasks.GET("/:id", openapi.Operation{
+		Summary:    "Get a task",
+		Parameters: []openapi.Parameter{idParam},
+		Scopes:     []string{models.ScopeTasksRead},
+		Responses:  protected(http.StatusOK, dto.Task{}, errorResponse(http.StatusNotFound, "Task not found")),
+	}, read, rt.tasks.GetTask)
+	tasks.POST("", openapi.Operation{
+		Summary:   "Create a task",
+		Scopes:    []string{models.ScopeTasksWrite},
+		Request:   dto.CreateTaskRequest{},
+		Responses: protected(http.StatusCreated, dto.Task{}, errorResponse(http.StatusBadRequest, "Invalid request")),
+	}, write, rt.tasks.CreateTask)
+	tasks.PUT("/:id", openapi.Operation{
+		Summary:     "Update a task",
+		Description: "Replaces the title and description, and the status unless it is empty.",
+		Parameters:  []openapi.Parameter{idParam},
+		Scopes:      []string{models.ScopeTasksWrite},
+		Request:     dto.UpdateTaskRequest{},
+		Responses: protected(http.StatusOK, dto.Task{},
+			errorResponse(http.StatusBadRequest, "Invalid request"),
+			errorResponse(http.StatusNotFound, "Task not found")),
+	}, write, rt.tasks.UpdateTask)
+	tasks.DELETE("/:id", openapi.Operation{
+		Summary:    "Delete a task",
+		Parameters: []openapi.Parameter{idParam},
+		Scopes:     []string{models.ScopeTasksWrite},
+		Responses:  protected(http.StatusNoContent, nil, errorResponse(http.StatusNotFound, "Task not found")),
+	}, write, rt.tasks.DeleteTask)
+}
+
+// legacyTasks returns tasks as models.Task, as the API did before v1.
+func (rt *routes) legacyTasks(tasks *openapi.Group, read, write gin.HandlerFunc) {
+	tasks.GET("", openapi.Operation{
+		Summary:   "List tasks",
+		Scopes:    []string{models.ScopeTasksRead},
+		Responses: protected(http.StatusOK, []models.Task{}),
+	}, read, rt.tasks.LegacyGetAllTasks)
+	tasks.GET("/:id", openapi.Operation{
+		Summary:    "Get a task",
+		Parameters: []openapi.Parameter{idParam},
+		Scopes:     []string{models.ScopeTasksRead},
+		Responses:  protected(http.StatusOK, models.Task{}, errorResponse(http.StatusNotFound, "Task not found")),
+	}, read, rt.tasks.LegacyGetTask)
+	tasks.POST("", openapi.Operation{
+		Summary:   "Create a task",
+		Scopes:    []string{models.ScopeTasksWrite},
+		Request:   dto.CreateTaskRequest{},
+		Responses: protected(http.StatusCreated, models.Task{}, errorResponse(http.StatusBadRequest, "Invalid request")),
+	}, write, rt.tasks.LegacyCreateTask)
+	tasks.PUT("/:id", openapi.Operation{
+		Summary:     "Update a task",
+		Description: "Replaces the title and description, and the status unless it is empty.",
+		Parameters:  []openapi.Parameter{idParam},
+		Scopes:      []string{models.ScopeTasksWrite},
+		Request:     dto.UpdateTaskRequest{},
+		Responses: protected(http.StatusOK, models.Task{},
+			errorResponse(http.StatusBadRequest, "Invalid request"),
+			errorResponse(http.StatusNotFound, "Task not found")),
+	}, write, rt.tasks.LegacyUpdateTask)
+	tasks.DELETE("/:id", openapi.Operation{
+		Summary:    "Delete a task",
+		Parameters: []openapi.Parameter{idParam},
+		Scopes:     []string{models.ScopeTasksWrite},
+		Responses:  protected(http.StatusNoContent, nil, errorResponse(http.StatusNotFound, "Task not found")),
+	}, write, rt.tasks.DeleteTask)
 }
@@ -0,0 +1,27 @@
+package router
+
+import (
+	"net/http"
+	"testing"
+
+	"task_manager/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The handlers are never called, so the router is built without
+// dependencies.
+func TestRoutesAreDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r, spec := setupRouter(nil, nil, nil, nil)
+
+	if err := openapi.CheckRoutes(r.Routes(), spec, undocumentedRoutes...); err != nil {
+		t.Fatal(err)
+	}
+
+	// A route registered with gin directly is caught
+	r.GET("/api/v1/debug", func(c *gin.Context) { c.Status(http.StatusOK) })
+	if err := openapi.CheckRoutes(r.Routes(), spec, undocumentedRoutes...); err == nil {
+		t.Error("CheckRoutes accepted an undocumented route")
+	}
+}
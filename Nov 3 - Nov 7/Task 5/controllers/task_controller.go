@@ -1,7 +1,9 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -9,14 +11,57 @@ import (
 	"Nov 3 - Nov 7/Task 5/models"
 )
 
-func GetTasks(c *gin.Context) {
-	tasks := data.GetAllTasks()
+type TaskController struct {
+	store data.TaskStore
+}
+
+func NewTaskController(store data.TaskStore) *TaskController {
+	return &TaskController{store: store}
+}
+
+// GetTasks lists tasks oldest first, optionally filtered by ?status= and
+// ?due_before=, ordered by ?sort= and paged with ?limit= and ?offset=. The
+// number of matching tasks before paging is sent in X-Total-Count.
+func (tc *TaskController) GetTasks(c *gin.Context) {
+	var query data.TaskQuery
+	query.Status = c.Query("status")
+
+	if dueBefore := c.Query("due_before"); dueBefore != "" {
+		t, err := time.Parse(time.RFC3339, dueBefore)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "due_before must be an RFC 3339 date"})
+			return
+		}
+		query.DueBefore = t
+	}
+
+	var err error
+	query.SortBy, query.Descending, err = data.ParseSort(c.Query("sort"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of created_at, updated_at, due_date or title, optionally prefixed with -"})
+		return
+	}
+
+	var ok bool
+	if query.Limit, ok = queryInt(c, "limit"); !ok {
+		return
+	}
+	if query.Offset, ok = queryInt(c, "offset"); !ok {
+		return
+	}
+
+	tasks, total, err := tc.store.ListTasks(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("X-Total-Count", strconv.Itoa(total))
 	c.JSON(http.StatusOK, tasks)
 }
 
-func GetTask(c *gin.Context) {
+func (tc *TaskController) GetTask(c *gin.Context) {
 	id := c.Param("id")
-	task, err := data.GetTaskByID(id)
+	task, err := tc.store.GetTaskByID(id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
 		return
@@ -24,7 +69,7 @@ func GetTask(c *gin.Context) {
 	c.JSON(http.StatusOK, task)
 }
 
-func CreateTask(c *gin.Context) {
+func (tc *TaskController) CreateTask(c *gin.Context) {
 	var task models.Task
 	if err := c.ShouldBindJSON(&task); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
@@ -43,11 +88,15 @@ func CreateTask(c *gin.Context) {
 		return
 	}
 
-	createdTask := data.CreateTask(task)
+	createdTask, err := tc.store.CreateTask(task)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save task"})
+		return
+	}
 	c.JSON(http.StatusCreated, createdTask)
 }
 
-func UpdateTask(c *gin.Context) {
+func (tc *TaskController) UpdateTask(c *gin.Context) {
 	id := c.Param("id")
 	var task models.Task
 	if err := c.ShouldBindJSON(&task); err != nil {
@@ -67,20 +116,44 @@ func UpdateTask(c *gin.Context) {
 		return
 	}
 
-	updatedTask, err := data.UpdateTask(id, task)
+	updatedTask, err := tc.store.UpdateTask(id, task)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		respondDataError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, updatedTask)
 }
 
-func DeleteTask(c *gin.Context) {
+func (tc *TaskController) DeleteTask(c *gin.Context) {
 	id := c.Param("id")
-	err := data.DeleteTask(id)
+	err := tc.store.DeleteTask(id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		respondDataError(c, err)
 		return
 	}
 	c.Status(http.StatusNoContent)
 }
+
+// queryInt reads an optional non-negative integer query parameter.
+func queryInt(c *gin.Context, name string) (int, bool) {
+	param := c.Query(name)
+	if param == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(param)
+	if err != nil || n < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be a non-negative integer"})
+		return 0, false
+	}
+	return n, true
+}
+
+// respondDataError reports a missing task as 404 and anything else, such as
+// a failure to write the task log, as 500.
+func respondDataError(c *gin.Context, err error) {
+	if errors.Is(err, data.ErrTaskNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save task"})
+}
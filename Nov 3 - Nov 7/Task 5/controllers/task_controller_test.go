@@ -0,0 +1,304 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"Nov 3 - Nov 7/Task 5/controllers"
+	"Nov 3 - Nov 7/Task 5/data"
+	"Nov 3 - Nov 7/Task 5/models"
+	"Nov 3 - Nov 7/Task 5/router"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newRouter returns the application's routes over a store of their own, so
+// every test can run in parallel.
+func newRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	t.Parallel()
+	return router.SetupRouter(controllers.NewTaskController(data.NewTaskStore()))
+}
+
+func do(t *testing.T, r http.Handler, method, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	switch b := body.(type) {
+	case nil:
+	case string:
+		buf.WriteString(b)
+	default:
+		if err := json.NewEncoder(&buf).Encode(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %q: %v", w.Body, err)
+	}
+}
+
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status = %d, want %d; body %s", w.Code, status, w.Body)
+	}
+}
+
+func create(t *testing.T, r http.Handler, task models.Task) models.Task {
+	t.Helper()
+	w := do(t, r, http.MethodPost, "/tasks", task)
+	expectStatus(t, w, http.StatusCreated)
+	var created models.Task
+	decode(t, w, &created)
+	return created
+}
+
+func TestCreateTask(t *testing.T) {
+	r := newRouter(t)
+	due := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+
+	created := create(t, r, models.Task{
+		ID:          "chosen-by-client",
+		Title:       "Write tests",
+		Description: "httptest suite",
+		DueDate:     due,
+		Status:      "Pending",
+	})
+	if created.ID == "" || created.ID == "chosen-by-client" {
+		t.Errorf("ID = %q, want one assigned by the store", created.ID)
+	}
+	if created.Title != "Write tests" || created.Description != "httptest suite" || created.Status != "Pending" {
+		t.Errorf("created task = %+v", created)
+	}
+	if !created.DueDate.Equal(due) {
+		t.Errorf("DueDate = %v, want %v", created.DueDate, due)
+	}
+	if created.CreatedAt.IsZero() || !created.UpdatedAt.Equal(created.CreatedAt) {
+		t.Errorf("CreatedAt = %v, UpdatedAt = %v", created.CreatedAt, created.UpdatedAt)
+	}
+}
+
+func TestCreateTaskInvalid(t *testing.T) {
+	r := newRouter(t)
+
+	tests := []struct {
+		name string
+		body interface{}
+	}{
+		{"malformed JSON", `{"title": `},
+		{"wrong type", `{"title": 42, "status": "Pending"}`},
+		{"missing title", models.Task{Status: "Pending"}},
+		{"missing status", models.Task{Title: "No status"}},
+		{"due date in the past", models.Task{Title: "Late", Status: "Pending", DueDate: time.Now().Add(-time.Hour)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, do(t, r, http.MethodPost, "/tasks", tt.body), http.StatusBadRequest)
+		})
+	}
+
+	// Nothing invalid was stored
+	w := do(t, r, http.MethodGet, "/tasks", nil)
+	expectStatus(t, w, http.StatusOK)
+	if total := w.Header().Get("X-Total-Count"); total != "0" {
+		t.Errorf("X-Total-Count = %q after invalid creates, want 0", total)
+	}
+}
+
+func TestGetTask(t *testing.T) {
+	r := newRouter(t)
+	created := create(t, r, models.Task{Title: "Read", Status: "Pending"})
+
+	w := do(t, r, http.MethodGet, "/tasks/"+created.ID, nil)
+	expectStatus(t, w, http.StatusOK)
+	var got models.Task
+	decode(t, w, &got)
+	if got.ID != created.ID || got.Title != "Read" {
+		t.Errorf("GET returned %+v, want %+v", got, created)
+	}
+
+	expectStatus(t, do(t, r, http.MethodGet, "/tasks/missing", nil), http.StatusNotFound)
+}
+
+func TestUpdateTask(t *testing.T) {
+	r := newRouter(t)
+	created := create(t, r, models.Task{Title: "Draft", Status: "Pending"})
+
+	w := do(t, r, http.MethodPut, "/tasks/"+created.ID, models.Task{
+		ID:          "ignored",
+		Title:       "Final",
+		Description: "edited",
+		Status:      "Completed",
+	})
+	expectStatus(t, w, http.StatusOK)
+	var updated models.Task
+	decode(t, w, &updated)
+	if updated.ID != created.ID {
+		t.Errorf("ID = %q, want %q", updated.ID, created.ID)
+	}
+	if updated.Title != "Final" || updated.Description != "edited" || updated.Status != "Completed" {
+		t.Errorf("updated task = %+v", updated)
+	}
+	if !updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("CreatedAt = %v, want %v", updated.CreatedAt, created.CreatedAt)
+	}
+	if updated.UpdatedAt.Before(created.UpdatedAt) {
+		t.Errorf("UpdatedAt = %v, before %v", updated.UpdatedAt, created.UpdatedAt)
+	}
+
+	w = do(t, r, http.MethodGet, "/tasks/"+created.ID, nil)
+	var stored models.Task
+	decode(t, w, &stored)
+	if stored.Title != "Final" {
+		t.Errorf("stored title = %q, want %q", stored.Title, "Final")
+	}
+}
+
+func TestUpdateTaskErrors(t *testing.T) {
+	r := newRouter(t)
+	created := create(t, r, models.Task{Title: "Draft", Status: "Pending"})
+
+	tests := []struct {
+		name   string
+		id     string
+		body   interface{}
+		status int
+	}{
+		{"unknown task", "missing", models.Task{Title: "x", Status: "Pending"}, http.StatusNotFound},
+		{"malformed JSON", created.ID, `not json`, http.StatusBadRequest},
+		{"missing title", created.ID, models.Task{Status: "Pending"}, http.StatusBadRequest},
+		{"due date in the past", created.ID, models.Task{Title: "x", Status: "Pending", DueDate: time.Now().Add(-time.Hour)}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, do(t, r, http.MethodPut, "/tasks/"+tt.id, tt.body), tt.status)
+		})
+	}
+}
+
+func TestDeleteTask(t *testing.T) {
+	r := newRouter(t)
+	created := create(t, r, models.Task{Title: "Temporary", Status: "Pending"})
+
+	w := do(t, r, http.MethodDelete, "/tasks/"+created.ID, nil)
+	expectStatus(t, w, http.StatusNoContent)
+	if w.Body.Len() != 0 {
+		t.Errorf("DELETE body = %q, want empty", w.Body)
+	}
+
+	expectStatus(t, do(t, r, http.MethodGet, "/tasks/"+created.ID, nil), http.StatusNotFound)
+	expectStatus(t, do(t, r, http.MethodDelete, "/tasks/"+created.ID, nil), http.StatusNotFound)
+}
+
+func TestGetTasksQuery(t *testing.T) {
+	r := newRouter(t)
+	now := time.Now().UTC()
+	day := 24 * time.Hour
+	create(t, r, models.Task{Title: "charlie", Status: "Pending", DueDate: now.Add(3 * day)})
+	create(t, r, models.Task{Title: "Alpha", Status: "Completed", DueDate: now.Add(1 * day)})
+	create(t, r, models.Task{Title: "bravo", Status: "Pending"})
+	create(t, r, models.Task{Title: "delta", Status: "Pending", DueDate: now.Add(2 * day)})
+
+	tests := []struct {
+		name   string
+		query  string
+		titles []string
+		total  string
+	}{
+		{"everything oldest first", "", []string{"charlie", "Alpha", "bravo", "delta"}, "4"},
+		{"newest first", "?sort=-created_at", []string{"delta", "bravo", "Alpha", "charlie"}, "4"},
+		{"by status", "?status=Pending", []string{"charlie", "bravo", "delta"}, "3"},
+		{"unknown status", "?status=Archived", []string{}, "0"},
+		{"by title ignoring case", "?sort=title", []string{"Alpha", "bravo", "charlie", "delta"}, "4"},
+		{"by due date, undated last", "?sort=due_date", []string{"Alpha", "delta", "charlie", "bravo"}, "4"},
+		{"by due date descending, undated last", "?sort=-due_date", []string{"charlie", "delta", "Alpha", "bravo"}, "4"},
+		{"due before", "?due_before=" + now.Add(2*day+time.Hour).Format(time.RFC3339), []string{"Alpha", "delta"}, "2"},
+		{"filters combine", "?status=Pending&due_before=" + now.Add(10*day).Format(time.RFC3339), []string{"charlie", "delta"}, "2"},
+		{"limit", "?limit=2", []string{"charlie", "Alpha"}, "4"},
+		{"offset", "?offset=3", []string{"delta"}, "4"},
+		{"page", "?sort=title&offset=1&limit=2", []string{"bravo", "charlie"}, "4"},
+		{"offset past the end", "?offset=10", []string{}, "4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(t, r, http.MethodGet, "/tasks"+tt.query, nil)
+			expectStatus(t, w, http.StatusOK)
+			if total := w.Header().Get("X-Total-Count"); total != tt.total {
+				t.Errorf("X-Total-Count = %q, want %q", total, tt.total)
+			}
+			var tasks []models.Task
+			decode(t, w, &tasks)
+			titles := make([]string, len(tasks))
+			for i, task := range tasks {
+				titles[i] = task.Title
+			}
+			if len(titles) != len(tt.titles) {
+				t.Fatalf("titles = %q, want %q", titles, tt.titles)
+			}
+			for i := range titles {
+				if titles[i] != tt.titles[i] {
+					t.Fatalf("titles = %q, want %q", titles, tt.titles)
+				}
+			}
+		})
+	}
+}
+
+func TestGetTasksInvalidQuery(t *testing.T) {
+	r := newRouter(t)
+
+	for _, query := range []string{
+		"?due_before=tomorrow",
+		"?due_before=2030-01-02",
+		"?sort=priority",
+		"?sort=--title",
+		"?limit=-1",
+		"?limit=ten",
+		"?offset=-5",
+		"?offset=1.5",
+	} {
+		t.Run(query, func(t *testing.T) {
+			w := do(t, r, http.MethodGet, "/tasks"+query, nil)
+			expectStatus(t, w, http.StatusBadRequest)
+			var body struct {
+				Error string `json:"error"`
+			}
+			decode(t, w, &body)
+			if body.Error == "" {
+				t.Error("400 response has no error message")
+			}
+		})
+	}
+}
+
+// Each controller works on the store it was given, so separate instances
+// never see each other's tasks.
+func TestStoresAreIndependent(t *testing.T) {
+	first := newRouter(t)
+	second := router.SetupRouter(controllers.NewTaskController(data.NewTaskStore()))
+
+	created := create(t, first, models.Task{Title: "Only in first", Status: "Pending"})
+
+	expectStatus(t, do(t, second, http.MethodGet, "/tasks/"+created.ID, nil), http.StatusNotFound)
+	w := do(t, second, http.MethodGet, "/tasks", nil)
+	expectStatus(t, w, http.StatusOK)
+	if total := w.Header().Get("X-Total-Count"); total != "0" {
+		t.Errorf("second store X-Total-Count = %q, want 0", total)
+	}
+}
@@ -0,0 +1,199 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"Nov 3 - Nov 7/Task 5/models"
+)
+
+const (
+	snapshotFile = "tasks.snapshot.json"
+	logFile      = "tasks.wal"
+
+	opPut    = "put"
+	opDelete = "delete"
+)
+
+// logEntry is one line of the write-ahead log. Entries are idempotent, so
+// replaying a log over a snapshot that already contains some of them is
+// harmless.
+type logEntry struct {
+	Op   string       `json:"op"`
+	ID   string       `json:"id"`
+	Task *models.Task `json:"task,omitempty"`
+}
+
+// journal persists tasks as a JSON snapshot plus a JSON-lines log of the
+// mutations made since. Its owner serializes calls to it.
+type journal struct {
+	dir     string
+	log     *os.File
+	entries int
+}
+
+// openJournal loads the tasks saved in dir and opens its log for appending.
+func openJournal(dir string) (map[string]models.Task, *journal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, nil, err
+	}
+
+	tasks, err := loadSnapshot(filepath.Join(dir, snapshotFile))
+	if err != nil {
+		return nil, nil, err
+	}
+	entries, size, err := replayLog(filepath.Join(dir, logFile), tasks)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log, err := os.OpenFile(filepath.Join(dir, logFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Drop a torn final line so new entries do not get appended to it
+	if err := log.Truncate(size); err != nil {
+		log.Close()
+		return nil, nil, err
+	}
+	return tasks, &journal{dir: dir, log: log, entries: entries}, nil
+}
+
+func (j *journal) append(entry logEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	// One write per entry, so a crash leaves at most a torn last line
+	if _, err := j.log.Write(line); err != nil {
+		return fmt.Errorf("write task log: %w", err)
+	}
+	j.entries++
+	return nil
+}
+
+// compact replaces the snapshot atomically and then truncates the log. A
+// crash between the two steps leaves a log that replays cleanly over the
+// new snapshot.
+func (j *journal) compact(tasks map[string]models.Task) error {
+	data, err := json.MarshalIndent(tasks, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(j.dir, snapshotFile), data); err != nil {
+		return fmt.Errorf("write task snapshot: %w", err)
+	}
+
+	if err := j.log.Truncate(0); err != nil {
+		return fmt.Errorf("truncate task log: %w", err)
+	}
+	if err := j.log.Sync(); err != nil {
+		return err
+	}
+	j.entries = 0
+	return nil
+}
+
+// close writes a final snapshot and closes the log.
+func (j *journal) close(tasks map[string]models.Task) error {
+	err := j.compact(tasks)
+	if closeErr := j.log.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func loadSnapshot(path string) (map[string]models.Task, error) {
+	loaded := make(map[string]models.Task)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return loaded, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return loaded, nil
+}
+
+// replayLog applies the log at path to loaded and returns how many entries
+// it held and the size of its complete lines. An incomplete final line, left
+// by a crash mid-write, is ignored; a corrupt line anywhere else is an error.
+func replayLog(path string, loaded map[string]models.Task) (int, int64, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	entries := 0
+	var size int64
+	for lineNo := 1; ; lineNo++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// A line without its newline was never completely written
+			return entries, size, nil
+		}
+		if err != nil {
+			return entries, size, err
+		}
+		size += int64(len(line))
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var entry logEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return entries, size, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+		switch entry.Op {
+		case opPut:
+			if entry.Task == nil {
+				return entries, size, fmt.Errorf("%s:%d: put without task", path, lineNo)
+			}
+			loaded[entry.ID] = *entry.Task
+		case opDelete:
+			delete(loaded, entry.ID)
+		default:
+			return entries, size, fmt.Errorf("%s:%d: unknown operation %q", path, lineNo, entry.Op)
+		}
+		entries++
+	}
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
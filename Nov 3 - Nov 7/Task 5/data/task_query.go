@@ -0,0 +1,132 @@
+package data
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"Nov 3 - Nov 7/Task 5/models"
+)
+
+// Fields tasks can be sorted by.
+const (
+	SortByCreatedAt = "created_at"
+	SortByUpdatedAt = "updated_at"
+	SortByDueDate   = "due_date"
+	SortByTitle     = "title"
+)
+
+var ErrInvalidSort = errors.New("invalid sort field")
+
+// TaskQuery filters and pages the task list. Zero values match everything.
+type TaskQuery struct {
+	Status string
+	// DueBefore keeps tasks with a due date strictly before it. Tasks
+	// without a due date never match.
+	DueBefore  time.Time
+	SortBy     string
+	Descending bool
+	Offset     int
+	// Limit caps the number of tasks returned; 0 means no limit.
+	Limit int
+}
+
+// ParseSort reads a sort parameter such as "due_date" or "-created_at",
+// where a leading "-" means descending.
+func ParseSort(param string) (string, bool, error) {
+	field := strings.TrimPrefix(param, "-")
+	descending := field != param
+	switch field {
+	case "":
+		return SortByCreatedAt, descending, nil
+	case SortByCreatedAt, SortByUpdatedAt, SortByDueDate, SortByTitle:
+		return field, descending, nil
+	}
+	return "", false, ErrInvalidSort
+}
+
+// ListTasks returns the page of tasks matching query and the number of
+// matching tasks before paging. Ties are broken by creation order, so the
+// result is the same on every call.
+func (s *MemoryTaskStore) ListTasks(query TaskQuery) ([]models.Task, int, error) {
+	compare, err := taskCompare(query.SortBy)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	s.mu.RLock()
+	matched := make([]models.Task, 0, len(s.order))
+	for _, id := range s.order {
+		task := s.tasks[id]
+		if query.matches(task) {
+			matched = append(matched, task)
+		}
+	}
+	s.mu.RUnlock()
+
+	// matched is in creation order already, which also breaks ties
+	if compare == nil {
+		if query.Descending {
+			for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+				matched[i], matched[j] = matched[j], matched[i]
+			}
+		}
+	} else {
+		sort.SliceStable(matched, func(i, j int) bool {
+			a, b := matched[i], matched[j]
+			// Tasks without a due date come last in either direction
+			if query.SortBy == SortByDueDate && a.DueDate.IsZero() != b.DueDate.IsZero() {
+				return b.DueDate.IsZero()
+			}
+			if query.Descending {
+				return compare(a, b) > 0
+			}
+			return compare(a, b) < 0
+		})
+	}
+
+	total := len(matched)
+	start := min(max(query.Offset, 0), total)
+	end := total
+	if query.Limit > 0 {
+		end = min(start+query.Limit, total)
+	}
+	return matched[start:end], total, nil
+}
+
+func (q TaskQuery) matches(task models.Task) bool {
+	if q.Status != "" && task.Status != q.Status {
+		return false
+	}
+	if !q.DueBefore.IsZero() && (task.DueDate.IsZero() || !task.DueDate.Before(q.DueBefore)) {
+		return false
+	}
+	return true
+}
+
+// taskCompare returns the comparison for field, or nil for creation order.
+func taskCompare(field string) (func(a, b models.Task) int, error) {
+	switch field {
+	case "", SortByCreatedAt:
+		return nil, nil
+	case SortByUpdatedAt:
+		return func(a, b models.Task) int { return a.UpdatedAt.Compare(b.UpdatedAt) }, nil
+	case SortByDueDate:
+		return func(a, b models.Task) int { return a.DueDate.Compare(b.DueDate) }, nil
+	case SortByTitle:
+		return func(a, b models.Task) int {
+			return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+		}, nil
+	}
+	return nil, ErrInvalidSort
+}
+
+// createdBefore orders tasks by creation time, then ID for tasks created at
+// the same instant or saved before CreatedAt was recorded.
+func createdBefore(a, b models.Task) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
@@ -2,6 +2,8 @@ package data
 
 import (
 	"errors"
+	"log"
+	"sort"
 	"sync"
 	"time"
 
@@ -9,64 +11,228 @@ import (
 	"Nov 3 - Nov 7/Task 5/models"
 )
 
-var (
-	tasks = make(map[string]models.Task)
-	mutex = &sync.Mutex{}
-)
+var ErrTaskNotFound = errors.New("task not found")
+
+// TaskStore is the storage the task handlers depend on.
+type TaskStore interface {
+	GetAllTasks() []models.Task
+	ListTasks(query TaskQuery) ([]models.Task, int, error)
+	GetTaskByID(id string) (models.Task, error)
+	CreateTask(task models.Task) (models.Task, error)
+	UpdateTask(id string, task models.Task) (models.Task, error)
+	DeleteTask(id string) error
+}
+
+// MemoryTaskStore keeps tasks in memory, optionally persisting them to disk
+// (see OpenTaskStore). It is safe for concurrent use.
+type MemoryTaskStore struct {
+	mu    sync.RWMutex
+	tasks map[string]models.Task
+	// order holds task IDs sorted by createdBefore, so listings are stable
+	order   []string
+	journal *journal
+	now     func() time.Time
 
-func GetAllTasks() []models.Task {
-	mutex.Lock()
-	defer mutex.Unlock()
+	// Stops and waits for periodic compaction, if it is running
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewTaskStore returns an empty store that is never persisted.
+func NewTaskStore() *MemoryTaskStore {
+	return newMemoryTaskStore(make(map[string]models.Task), nil)
+}
 
-	taskList := make([]models.Task, 0, len(tasks))
-	for _, task := range tasks {
-		taskList = append(taskList, task)
+// OpenTaskStore loads the tasks saved in dir, replaying the log over the last
+// snapshot, and records every later mutation there before applying it. When
+// compactEvery is positive the log is folded into a new snapshot at that
+// interval. Close must be called before exit.
+func OpenTaskStore(dir string, compactEvery time.Duration) (*MemoryTaskStore, error) {
+	tasks, j, err := openJournal(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newMemoryTaskStore(tasks, j)
+	if compactEvery > 0 {
+		s.stop = make(chan struct{})
+		s.done = make(chan struct{})
+		go s.compactLoop(compactEvery)
+	}
+	return s, nil
+}
+
+func newMemoryTaskStore(tasks map[string]models.Task, j *journal) *MemoryTaskStore {
+	s := &MemoryTaskStore{tasks: tasks, journal: j, now: time.Now}
+	s.order = make([]string, 0, len(tasks))
+	for id := range tasks {
+		s.order = append(s.order, id)
+	}
+	sort.Slice(s.order, func(i, j int) bool {
+		return createdBefore(tasks[s.order[i]], tasks[s.order[j]])
+	})
+	return s
+}
+
+// GetAllTasks returns every task, oldest first.
+func (s *MemoryTaskStore) GetAllTasks() []models.Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	taskList := make([]models.Task, 0, len(s.order))
+	for _, id := range s.order {
+		taskList = append(taskList, s.tasks[id])
 	}
 	return taskList
 }
 
-func GetTaskByID(id string) (models.Task, error) {
-	mutex.Lock()
-	defer mutex.Unlock()
+func (s *MemoryTaskStore) GetTaskByID(id string) (models.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	task, exists := tasks[id]
+	task, exists := s.tasks[id]
 	if !exists {
-		return models.Task{}, errors.New("task not found")
+		return models.Task{}, ErrTaskNotFound
 	}
 	return task, nil
 }
 
-func CreateTask(task models.Task) models.Task {
-	mutex.Lock()
-	defer mutex.Unlock()
+func (s *MemoryTaskStore) CreateTask(task models.Task) (models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	task.ID = uuid.New().String()
-	tasks[task.ID] = task
-	return task
+	task.CreatedAt = s.now().UTC()
+	task.UpdatedAt = task.CreatedAt
+	if err := s.record(logEntry{Op: opPut, ID: task.ID, Task: &task}); err != nil {
+		return models.Task{}, err
+	}
+	s.tasks[task.ID] = task
+	s.insertOrder(task)
+	return task, nil
 }
 
-func UpdateTask(id string, updatedTask models.Task) (models.Task, error) {
-	mutex.Lock()
-	defer mutex.Unlock()
+func (s *MemoryTaskStore) UpdateTask(id string, updatedTask models.Task) (models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	_, exists := tasks[id]
+	existing, exists := s.tasks[id]
 	if !exists {
-		return models.Task{}, errors.New("task not found")
+		return models.Task{}, ErrTaskNotFound
 	}
 
 	updatedTask.ID = id
-	tasks[id] = updatedTask
+	updatedTask.CreatedAt = existing.CreatedAt
+	updatedTask.UpdatedAt = s.now().UTC()
+	if err := s.record(logEntry{Op: opPut, ID: id, Task: &updatedTask}); err != nil {
+		return models.Task{}, err
+	}
+	s.tasks[id] = updatedTask
 	return updatedTask, nil
 }
 
-func DeleteTask(id string) error {
-	mutex.Lock()
-	defer mutex.Unlock()
+func (s *MemoryTaskStore) DeleteTask(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	_, exists := tasks[id]
+	task, exists := s.tasks[id]
 	if !exists {
-		return errors.New("task not found")
+		return ErrTaskNotFound
 	}
-	delete(tasks, id)
+	if err := s.record(logEntry{Op: opDelete, ID: id}); err != nil {
+		return err
+	}
+	s.removeOrder(task)
+	delete(s.tasks, id)
 	return nil
 }
+
+func (s *MemoryTaskStore) insertOrder(task models.Task) {
+	i := s.orderIndex(task)
+	s.order = append(s.order, "")
+	copy(s.order[i+1:], s.order[i:])
+	s.order[i] = task.ID
+}
+
+func (s *MemoryTaskStore) removeOrder(task models.Task) {
+	i := s.orderIndex(task)
+	if i < len(s.order) && s.order[i] == task.ID {
+		s.order = append(s.order[:i], s.order[i+1:]...)
+	}
+}
+
+// orderIndex returns the position of task in order, or where it belongs.
+func (s *MemoryTaskStore) orderIndex(task models.Task) int {
+	return sort.Search(len(s.order), func(i int) bool {
+		return !createdBefore(s.tasks[s.order[i]], task)
+	})
+}
+
+// record appends a mutation to the log, if the store is persisted. Callers
+// apply the mutation only if record succeeds.
+func (s *MemoryTaskStore) record(entry logEntry) error {
+	if s.journal == nil {
+		return nil
+	}
+	return s.journal.append(entry)
+}
+
+// Compact writes a snapshot of every task and empties the log.
+func (s *MemoryTaskStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.journal == nil {
+		return nil
+	}
+	return s.journal.compact(s.tasks)
+}
+
+// Close stops periodic compaction, folds the log into a final snapshot and
+// flushes everything to disk. Mutations after Close are kept in memory only.
+func (s *MemoryTaskStore) Close() error {
+	// Wait for the compaction goroutine outside the lock it may be waiting on
+	if s.stop != nil {
+		close(s.stop)
+		<-s.done
+		s.stop = nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.journal == nil {
+		return nil
+	}
+	err := s.journal.close(s.tasks)
+	s.journal = nil
+	return err
+}
+
+func (s *MemoryTaskStore) compactLoop(every time.Duration) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.compactIfDirty(); err != nil {
+				log.Println("task store: compaction failed:", err)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *MemoryTaskStore) compactIfDirty() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.journal == nil || s.journal.entries == 0 {
+		return nil
+	}
+	return s.journal.compact(s.tasks)
+}
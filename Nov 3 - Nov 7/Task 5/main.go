@@ -1,10 +1,66 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"Nov 3 - Nov 7/Task 5/controllers"
+	"Nov 3 - Nov 7/Task 5/data"
 	"Nov 3 - Nov 7/Task 5/router"
 )
 
 func main() {
-	r := router.SetupRouter()
-	r.Run(":8080")
+	// Load saved tasks and log every change from now on
+	compactEvery, err := time.ParseDuration(getEnv("COMPACT_INTERVAL", "5m"))
+	if err != nil {
+		log.Fatal("Invalid COMPACT_INTERVAL:", err)
+	}
+	store, err := data.OpenTaskStore(getEnv("DATA_DIR", "task_data"), compactEvery)
+	if err != nil {
+		log.Fatal("Failed to load tasks:", err)
+	}
+
+	taskController := controllers.NewTaskController(store)
+	r := router.SetupRouter(taskController)
+	srv := &http.Server{
+		Addr:    ":" + getEnv("PORT", "8080"),
+		Handler: r,
+	}
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server:", err)
+		}
+	}()
+
+	// Wait for an interrupt, then let in-flight requests finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	log.Println("Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Println("Server forced to shutdown:", err)
+	}
+
+	// Fold the log into a final snapshot
+	if err := store.Close(); err != nil {
+		log.Fatal("Failed to save tasks:", err)
+	}
+	log.Println("Server exited")
+}
+
+func getEnv(key, defaultValue string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	return defaultValue
 }
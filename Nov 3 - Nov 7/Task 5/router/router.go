@@ -5,16 +5,16 @@ import (
 	"Nov 3 - Nov 7/Task 5/controllers"
 )
 
-func SetupRouter() *gin.Engine {
+func SetupRouter(taskController *controllers.TaskController) *gin.Engine {
 	r := gin.Default()
 
 	tasks := r.Group("/tasks")
 	{
-		tasks.GET("", controllers.GetTasks)
-		tasks.GET(":id", controllers.GetTask)
-		tasks.POST("", controllers.CreateTask)
-		tasks.PUT(":id", controllers.UpdateTask)
-		tasks.DELETE(":id", controllers.DeleteTask)
+		tasks.GET("", taskController.GetTasks)
+		tasks.GET(":id", taskController.GetTask)
+		tasks.POST("", taskController.CreateTask)
+		tasks.PUT(":id", taskController.UpdateTask)
+		tasks.DELETE(":id", taskController.DeleteTask)
 	}
 
 	return r
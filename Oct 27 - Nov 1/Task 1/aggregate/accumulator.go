@@ -0,0 +1,220 @@
+package aggregate
+
+import "math"
+
+// Accumulator computes aggregates over values added one at a time, in
+// constant memory, so it can consume channels and iterators of any length.
+// The zero value is ready to use. Accumulators from separate goroutines can
+// be combined with Merge.
+//
+// Float inputs follow IEEE 754: a NaN makes every aggregate NaN, and an
+// infinite input makes the sum and mean infinite and the variance NaN.
+type Accumulator[T Number] struct {
+	count        int
+	sum          T
+	compensation T
+	overflowed   bool
+	nonFinite    bool
+	min, max     T
+	mean, m2     float64
+}
+
+func (a *Accumulator[T]) Add(x T) {
+	if a.count == 0 || x < a.min || isNaN(x) {
+		a.min = x
+	}
+	if a.count == 0 || x > a.max || isNaN(x) {
+		a.max = x
+	}
+	a.count++
+
+	a.addToSum(x)
+	if !isFinite(x) {
+		a.nonFinite = true
+	}
+
+	n := float64(a.count)
+	delta := float64(x) - a.mean
+	if math.IsInf(delta, 0) && isFinite(x) {
+		// x and the mean are too far apart to subtract; scale first
+		a.mean += float64(x)/n - a.mean/n
+	} else {
+		a.mean += delta / n
+	}
+	a.m2 += delta * (float64(x) - a.mean)
+}
+
+func (a *Accumulator[T]) AddAll(values ...T) {
+	for _, x := range values {
+		a.Add(x)
+	}
+}
+
+func (a *Accumulator[T]) addToSum(x T) {
+	if !isFloat[T]() {
+		sum, ok := addChecked(a.sum, x)
+		if !ok {
+			a.overflowed = true
+		}
+		a.sum = sum
+		return
+	}
+
+	// Kahan-Babuska: carry the low-order bits lost by each addition. Once
+	// the sum saturates to ±Inf (or becomes NaN) there are no low-order
+	// bits left, and compensating would turn Inf-Inf into NaN.
+	t := a.sum + x
+	if !isFinite(t) {
+		a.sum = t
+		return
+	}
+	if abs(a.sum) >= abs(x) {
+		a.compensation += (a.sum - t) + x
+	} else {
+		a.compensation += (x - t) + a.sum
+	}
+	a.sum = t
+}
+
+// Merge adds every value seen by other to a.
+func (a *Accumulator[T]) Merge(other *Accumulator[T]) {
+	if other.count == 0 {
+		return
+	}
+	if a.count == 0 {
+		*a = *other
+		return
+	}
+
+	if other.min < a.min || isNaN(other.min) {
+		a.min = other.min
+	}
+	if other.max > a.max || isNaN(other.max) {
+		a.max = other.max
+	}
+
+	a.addToSum(other.sum)
+	a.compensation += other.compensation
+	a.overflowed = a.overflowed || other.overflowed
+	a.nonFinite = a.nonFinite || other.nonFinite
+
+	n := float64(a.count + other.count)
+	delta := other.mean - a.mean
+	a.m2 += other.m2 + delta*delta*float64(a.count)*float64(other.count)/n
+	if math.IsInf(delta, 0) && !a.nonFinite {
+		a.mean = a.mean*(float64(a.count)/n) + other.mean*(float64(other.count)/n)
+	} else {
+		a.mean += delta * float64(other.count) / n
+	}
+	a.count += other.count
+}
+
+func (a *Accumulator[T]) Count() int {
+	return a.count
+}
+
+// Sum returns the total of the values added so far. For integer types it
+// returns ErrOverflow, along with the wrapped total, if any addition
+// overflowed. Float sums saturate to ±Inf.
+func (a *Accumulator[T]) Sum() (T, error) {
+	if a.overflowed {
+		return a.sum, ErrOverflow
+	}
+	return a.total(), nil
+}
+
+func (a *Accumulator[T]) total() T {
+	if !isFinite(a.sum) {
+		return a.sum
+	}
+	return a.sum + a.compensation
+}
+
+func (a *Accumulator[T]) Mean() (float64, error) {
+	if a.count == 0 {
+		return 0, ErrEmpty
+	}
+	if a.nonFinite {
+		// Welford's update turns Inf into NaN; the plain mean is exact here.
+		return float64(a.total()) / float64(a.count), nil
+	}
+	return a.mean, nil
+}
+
+func (a *Accumulator[T]) Min() (T, error) {
+	if a.count == 0 {
+		return 0, ErrEmpty
+	}
+	return a.min, nil
+}
+
+func (a *Accumulator[T]) Max() (T, error) {
+	if a.count == 0 {
+		return 0, ErrEmpty
+	}
+	return a.max, nil
+}
+
+// Variance returns the population variance, computed with Welford's method.
+func (a *Accumulator[T]) Variance() (float64, error) {
+	if a.count == 0 {
+		return 0, ErrEmpty
+	}
+	if a.nonFinite {
+		return math.NaN(), nil
+	}
+	return a.m2 / float64(a.count), nil
+}
+
+func (a *Accumulator[T]) SampleVariance() (float64, error) {
+	if a.count < 2 {
+		return 0, ErrEmpty
+	}
+	if a.nonFinite {
+		return math.NaN(), nil
+	}
+	return a.m2 / float64(a.count-1), nil
+}
+
+func (a *Accumulator[T]) StdDev() (float64, error) {
+	v, err := a.Variance()
+	return math.Sqrt(v), err
+}
+
+// FromChannel drains ch into a new Accumulator and returns it once ch is
+// closed.
+func FromChannel[T Number](ch <-chan T) *Accumulator[T] {
+	var acc Accumulator[T]
+	for x := range ch {
+		acc.Add(x)
+	}
+	return &acc
+}
+
+// FromSeq consumes an iterator in the push style used by range-over-func:
+// seq calls yield for each value and stops when yield returns false.
+func FromSeq[T Number](seq func(yield func(T) bool)) *Accumulator[T] {
+	var acc Accumulator[T]
+	seq(func(x T) bool {
+		acc.Add(x)
+		return true
+	})
+	return &acc
+}
+
+func isNaN[T Number](x T) bool {
+	return x != x
+}
+
+// isFinite reports whether x is neither ±Inf nor NaN; integers always are.
+func isFinite[T Number](x T) bool {
+	f := float64(x)
+	return !math.IsInf(f, 0) && !math.IsNaN(f)
+}
+
+func abs[T Number](x T) T {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
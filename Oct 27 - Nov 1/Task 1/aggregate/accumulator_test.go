@@ -0,0 +1,269 @@
+package aggregate
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestAccumulatorSum(t *testing.T) {
+	tests := []struct {
+		name    string
+		numbers []int16
+		want    int16
+		err     error
+	}{
+		{"empty", nil, 0, nil},
+		{"fits", []int16{30000, 2767}, 32767, nil},
+		{"overflow", []int16{30000, 2768}, -32768, ErrOverflow},
+		{"overflow stays reported", []int16{32767, 1, -1}, 32767, ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var acc Accumulator[int16]
+			acc.AddAll(tt.numbers...)
+			got, err := acc.Sum()
+			if got != tt.want || !errors.Is(err, tt.err) {
+				t.Errorf("Sum() = %d, %v, want %d, %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestAccumulatorFloatOverflow(t *testing.T) {
+	var acc Accumulator[float64]
+	acc.AddAll(1e308, 1e308, -1e308, 0.5)
+	sum, err := acc.Sum()
+	if err != nil || !math.IsInf(sum, 1) {
+		t.Errorf("Sum() = %v, %v, want +Inf, nil", sum, err)
+	}
+	// The mean of finite values is finite even when their sum is not
+	if mean, err := acc.Mean(); err != nil || !same(mean, 2.5e307) {
+		t.Errorf("Mean() = %v, %v, want 2.5e307", mean, err)
+	}
+	if variance, err := acc.Variance(); err != nil || !math.IsInf(variance, 1) {
+		t.Errorf("Variance() = %v, %v, want +Inf", variance, err)
+	}
+}
+
+func TestAccumulatorMerge(t *testing.T) {
+	tests := []struct {
+		name        string
+		left, right []float64
+	}{
+		{"both empty", nil, nil},
+		{"left empty", nil, []float64{1, 2}},
+		{"right empty", []float64{1, 2}, nil},
+		{"both", []float64{1, 5, 9}, []float64{-2, 0.5}},
+		{"cancellation", []float64{1, 1e100}, []float64{1, -1e100}},
+		{"overflow", []float64{1e308}, []float64{1e308}},
+		{"inf", []float64{1}, []float64{inf}},
+		{"opposite infs", []float64{inf}, []float64{-inf}},
+		{"nan", []float64{1, 2}, []float64{nan}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var left, right, all Accumulator[float64]
+			left.AddAll(tt.left...)
+			right.AddAll(tt.right...)
+			all.AddAll(append(append([]float64{}, tt.left...), tt.right...)...)
+			left.Merge(&right)
+
+			if left.Count() != all.Count() {
+				t.Fatalf("Count() = %d, want %d", left.Count(), all.Count())
+			}
+			compare := func(what string, merged func(*Accumulator[float64]) (float64, error)) {
+				t.Helper()
+				got, gotErr := merged(&left)
+				want, wantErr := merged(&all)
+				if !errors.Is(gotErr, wantErr) || !same(got, want) {
+					t.Errorf("merged %s = %v, %v, want %v, %v", what, got, gotErr, want, wantErr)
+				}
+			}
+			compare("Sum", (*Accumulator[float64]).Sum)
+			compare("Mean", (*Accumulator[float64]).Mean)
+			compare("Min", (*Accumulator[float64]).Min)
+			compare("Max", (*Accumulator[float64]).Max)
+			compare("Variance", (*Accumulator[float64]).Variance)
+		})
+	}
+
+	var a, b Accumulator[int8]
+	a.AddAll(100, 20)
+	b.AddAll(10)
+	a.Merge(&b)
+	if _, err := a.Sum(); !errors.Is(err, ErrOverflow) {
+		t.Errorf("merged int8 Sum() error = %v, want %v", err, ErrOverflow)
+	}
+}
+
+func TestFromChannel(t *testing.T) {
+	ch := make(chan int)
+	go func() {
+		for i := 1; i <= 100; i++ {
+			ch <- i
+		}
+		close(ch)
+	}()
+	acc := FromChannel(ch)
+	if sum, err := acc.Sum(); err != nil || sum != 5050 {
+		t.Errorf("Sum() = %d, %v, want 5050", sum, err)
+	}
+	if mean, err := acc.Mean(); err != nil || mean != 50.5 {
+		t.Errorf("Mean() = %v, %v, want 50.5", mean, err)
+	}
+}
+
+func TestFromSeq(t *testing.T) {
+	seq := func(yield func(float32) bool) {
+		for _, x := range []float32{3, 1, 2} {
+			if !yield(x) {
+				return
+			}
+		}
+	}
+	acc := FromSeq(seq)
+	if min, err := acc.Min(); err != nil || min != 1 {
+		t.Errorf("Min() = %v, %v, want 1", min, err)
+	}
+	if max, err := acc.Max(); err != nil || max != 3 {
+		t.Errorf("Max() = %v, %v, want 3", max, err)
+	}
+	if _, err := FromSeq(func(func(int) bool) {}).Mean(); !errors.Is(err, ErrEmpty) {
+		t.Errorf("Mean() of empty sequence error = %v, want %v", err, ErrEmpty)
+	}
+}
+
+// FuzzSumChecked compares checked int8 sums with arbitrary precision.
+func FuzzSumChecked(f *testing.F) {
+	f.Add([]byte{0x7f, 0x01})
+	f.Add([]byte{0x80, 0xff})
+	f.Add([]byte{0x10, 0x20, 0xe0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		numbers := make([]int8, len(data))
+		for i, b := range data {
+			numbers[i] = int8(b)
+		}
+
+		got, err := SumChecked(numbers)
+
+		// The first prefix that leaves int8 is where SumChecked must stop
+		exact := new(big.Int)
+		for i, n := range numbers {
+			exact.Add(exact, big.NewInt(int64(n)))
+			if !exact.IsInt64() || exact.Int64() < math.MinInt8 || exact.Int64() > math.MaxInt8 {
+				if !errors.Is(err, ErrOverflow) {
+					t.Fatalf("SumChecked(%v) = %d, %v; prefix %d overflows", numbers, got, err, i+1)
+				}
+				return
+			}
+		}
+		if err != nil || int64(got) != exact.Int64() {
+			t.Fatalf("SumChecked(%v) = %d, %v, want %d", numbers, got, err, exact.Int64())
+		}
+		if wrapped := Sum(numbers); wrapped != got {
+			t.Fatalf("Sum(%v) = %d, SumChecked = %d", numbers, wrapped, got)
+		}
+	})
+}
+
+// FuzzSumFloat checks compensated sums against the exact sum and that
+// finite inputs never produce NaN, even when the sum overflows.
+func FuzzSumFloat(f *testing.F) {
+	f.Add(0.1, 0.2, 0.3, 0.0)
+	f.Add(1.0, 1e100, 1.0, -1e100)
+	f.Add(1e308, 1e308, -1e308, 1.0)
+	f.Add(math.MaxFloat64, 1e292, -math.MaxFloat64, 0.0)
+	f.Add(math.Inf(1), 1.0, 2.0, 3.0)
+	f.Add(math.Inf(1), math.Inf(-1), 0.0, 0.0)
+	f.Add(math.NaN(), 1.0, 0.0, 0.0)
+	f.Fuzz(func(t *testing.T, a, b, c, d float64) {
+		numbers := []float64{a, b, c, d}
+		got := Sum(numbers)
+
+		finite := true
+		for _, x := range numbers {
+			finite = finite && !math.IsInf(x, 0) && !math.IsNaN(x)
+		}
+		if !finite {
+			naive := a + b + c + d
+			if !same(got, naive) {
+				t.Fatalf("Sum(%v) = %v, want %v", numbers, got, naive)
+			}
+			return
+		}
+		if math.IsNaN(got) {
+			t.Fatalf("Sum(%v) = NaN from finite inputs", numbers)
+		}
+		if math.IsInf(got, 0) {
+			return
+		}
+
+		exact := new(big.Float).SetPrec(2048)
+		for _, x := range numbers {
+			exact.Add(exact, new(big.Float).SetFloat64(x))
+		}
+		want, _ := exact.Float64()
+		// Kahan-Babuska is within a couple of ulps of the largest input
+		scale := math.Max(math.Max(math.Abs(a), math.Abs(b)), math.Max(math.Abs(c), math.Abs(d)))
+		if math.Abs(got-want) > 4*scale*0x1p-52 {
+			t.Fatalf("Sum(%v) = %v, want %v", numbers, got, want)
+		}
+	})
+}
+
+// FuzzAccumulatorMerge checks that splitting values across two merged
+// accumulators gives the same aggregates as adding them to one.
+func FuzzAccumulatorMerge(f *testing.F) {
+	f.Add([]byte{1, 2, 3, 4, 5}, uint8(2))
+	f.Add([]byte{0x80, 0x7f, 0xff}, uint8(0))
+	f.Add([]byte{}, uint8(3))
+	f.Fuzz(func(t *testing.T, data []byte, split uint8) {
+		numbers := make([]int32, len(data))
+		for i, b := range data {
+			numbers[i] = int32(int8(b)) << 20
+		}
+		cut := 0
+		if len(numbers) > 0 {
+			cut = int(split) % (len(numbers) + 1)
+		}
+
+		var left, right, all Accumulator[int32]
+		left.AddAll(numbers[:cut]...)
+		right.AddAll(numbers[cut:]...)
+		all.AddAll(numbers...)
+		left.Merge(&right)
+
+		gotSum, gotErr := left.Sum()
+		wantSum, wantErr := all.Sum()
+		if gotSum != wantSum || !errors.Is(gotErr, wantErr) {
+			t.Fatalf("merged Sum() = %d, %v, want %d, %v", gotSum, gotErr, wantSum, wantErr)
+		}
+		if len(numbers) == 0 {
+			return
+		}
+		// Rounding error scales with the inputs, not with the result
+		scale := 1.0
+		for _, n := range numbers {
+			scale = math.Max(scale, math.Abs(float64(n)))
+		}
+		gotMean, _ := left.Mean()
+		wantMean, _ := all.Mean()
+		if math.Abs(gotMean-wantMean) > 1e-12*scale {
+			t.Fatalf("merged Mean() = %v, want %v", gotMean, wantMean)
+		}
+		gotVar, _ := left.Variance()
+		wantVar, _ := all.Variance()
+		if math.Abs(gotVar-wantVar) > 1e-12*scale*scale {
+			t.Fatalf("merged Variance() = %v, want %v", gotVar, wantVar)
+		}
+		gotMin, _ := left.Min()
+		wantMin, _ := all.Min()
+		gotMax, _ := left.Max()
+		wantMax, _ := all.Max()
+		if gotMin != wantMin || gotMax != wantMax {
+			t.Fatalf("merged Min/Max = %d/%d, want %d/%d", gotMin, gotMax, wantMin, wantMax)
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package aggregate
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+var (
+	ErrEmpty             = errors.New("no values")
+	ErrOverflow          = errors.New("integer overflow")
+	ErrInvalidPercentile = errors.New("percentile must be between 0 and 100")
+)
+
+// Sum adds numbers. Integer sums wrap around on overflow exactly like the
+// built-in + operator; use SumChecked to detect that. Float sums use
+// compensated (Kahan-Babuska) summation to limit rounding error.
+func Sum[T Number](numbers []T) T {
+	var acc Accumulator[T]
+	acc.AddAll(numbers...)
+	return acc.total()
+}
+
+// SumChecked adds integers and reports ErrOverflow instead of wrapping.
+func SumChecked[T Integer](numbers []T) (T, error) {
+	var sum T
+	for _, n := range numbers {
+		next, ok := addChecked(sum, n)
+		if !ok {
+			return sum, ErrOverflow
+		}
+		sum = next
+	}
+	return sum, nil
+}
+
+// KahanSum adds floats with compensated summation.
+func KahanSum[T Float](numbers []T) T {
+	return Sum(numbers)
+}
+
+func Mean[T Number](numbers []T) (float64, error) {
+	var acc Accumulator[T]
+	acc.AddAll(numbers...)
+	return acc.Mean()
+}
+
+func Min[T Number](numbers []T) (T, error) {
+	var acc Accumulator[T]
+	acc.AddAll(numbers...)
+	return acc.Min()
+}
+
+func Max[T Number](numbers []T) (T, error) {
+	var acc Accumulator[T]
+	acc.AddAll(numbers...)
+	return acc.Max()
+}
+
+// Variance returns the population variance of numbers.
+func Variance[T Number](numbers []T) (float64, error) {
+	var acc Accumulator[T]
+	acc.AddAll(numbers...)
+	return acc.Variance()
+}
+
+// SampleVariance returns the unbiased sample variance of numbers, which
+// needs at least two values.
+func SampleVariance[T Number](numbers []T) (float64, error) {
+	var acc Accumulator[T]
+	acc.AddAll(numbers...)
+	return acc.SampleVariance()
+}
+
+func StdDev[T Number](numbers []T) (float64, error) {
+	v, err := Variance(numbers)
+	return math.Sqrt(v), err
+}
+
+func Median[T Number](numbers []T) (float64, error) {
+	return Percentile(numbers, 50)
+}
+
+// Percentile returns the p-th percentile (0 <= p <= 100) of numbers,
+// interpolating linearly between the two nearest ranks. numbers is not
+// modified. It is NaN if any number is NaN.
+func Percentile[T Number](numbers []T, p float64) (float64, error) {
+	if len(numbers) == 0 {
+		return 0, ErrEmpty
+	}
+	if p < 0 || p > 100 || math.IsNaN(p) {
+		return 0, ErrInvalidPercentile
+	}
+
+	sorted := make([]float64, len(numbers))
+	for i, n := range numbers {
+		sorted[i] = float64(n)
+	}
+	sort.Float64s(sorted)
+	return percentileOfSorted(sorted, p), nil
+}
+
+// Percentiles returns several percentiles of numbers, sorting them only once.
+func Percentiles[T Number](numbers []T, ps ...float64) ([]float64, error) {
+	if len(numbers) == 0 {
+		return nil, ErrEmpty
+	}
+
+	sorted := make([]float64, len(numbers))
+	for i, n := range numbers {
+		sorted[i] = float64(n)
+	}
+	sort.Float64s(sorted)
+
+	results := make([]float64, len(ps))
+	for i, p := range ps {
+		if p < 0 || p > 100 || math.IsNaN(p) {
+			return nil, ErrInvalidPercentile
+		}
+		results[i] = percentileOfSorted(sorted, p)
+	}
+	return results, nil
+}
+
+func percentileOfSorted(sorted []float64, p float64) float64 {
+	// sort.Float64s orders NaN first
+	if math.IsNaN(sorted[0]) {
+		return math.NaN()
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower]
+	}
+	weight := rank - float64(lower)
+	return sorted[lower]*(1-weight) + sorted[upper]*weight
+}
+
+// addChecked returns a+b and whether it fit in T. Floats never report
+// overflow; they saturate to ±Inf instead.
+func addChecked[T Number](a, b T) (T, bool) {
+	sum := a + b
+	if (b > 0 && sum < a) || (b < 0 && sum > a) {
+		return sum, false
+	}
+	return sum, true
+}
+
+func isFloat[T Number]() bool {
+	var zero T
+	zero = 1
+	zero /= 2
+	return zero != 0
+}
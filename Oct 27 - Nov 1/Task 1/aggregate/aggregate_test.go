@@ -0,0 +1,222 @@
+package aggregate
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+var (
+	inf = math.Inf(1)
+	nan = math.NaN()
+)
+
+// same reports whether got matches want, treating NaNs as equal and
+// allowing for rounding in the last few bits.
+func same(got, want float64) bool {
+	switch {
+	case math.IsNaN(want):
+		return math.IsNaN(got)
+	case math.IsInf(want, 0):
+		return got == want
+	}
+	return math.Abs(got-want) <= 1e-9*math.Max(1, math.Abs(want))
+}
+
+func TestSumInt(t *testing.T) {
+	tests := []struct {
+		name    string
+		numbers []int
+		want    int
+	}{
+		{"empty", nil, 0},
+		{"single", []int{7}, 7},
+		{"positive", []int{1, 2, 3, 4, 5}, 15},
+		{"mixed signs", []int{-4, 10, -6}, 0},
+		{"wraps on overflow", []int{math.MaxInt, 1}, math.MinInt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sum(tt.numbers); got != tt.want {
+				t.Errorf("Sum(%v) = %d, want %d", tt.numbers, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSumFloat(t *testing.T) {
+	tests := []struct {
+		name    string
+		numbers []float64
+		want    float64
+	}{
+		{"empty", nil, 0},
+		{"tenths", []float64{0.1, 0.2, 0.3}, 0.6},
+		{"cancellation", []float64{1, 1e100, 1, -1e100}, 2},
+		{"many small", []float64{1e16, 1, 1, 1, 1}, 1e16 + 4},
+		{"overflow saturates", []float64{1e308, 1e308}, inf},
+		{"overflow then finite", []float64{1e308, 1e308, -1e308, 1}, inf},
+		{"negative overflow", []float64{-1e308, -1e308, 5}, -inf},
+		{"inf input", []float64{1, inf, 2}, inf},
+		{"inf minus inf", []float64{inf, -inf}, nan},
+		{"nan input", []float64{1, nan, 2}, nan},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sum(tt.numbers); !same(got, tt.want) {
+				t.Errorf("Sum(%v) = %v, want %v", tt.numbers, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSumFloat32(t *testing.T) {
+	if got := Sum([]float32{math.MaxFloat32, math.MaxFloat32, 1}); !math.IsInf(float64(got), 1) {
+		t.Errorf("Sum overflowing float32 = %v, want +Inf", got)
+	}
+	if got := KahanSum([]float32{1e8, 1, 1, 1, 1}); got != 1e8+4 {
+		t.Errorf("KahanSum = %v, want %v", got, float32(1e8+4))
+	}
+}
+
+func TestSumChecked(t *testing.T) {
+	tests := []struct {
+		name    string
+		numbers []int8
+		want    int8
+		err     error
+	}{
+		{"empty", nil, 0, nil},
+		{"fits", []int8{100, 27}, 127, nil},
+		{"negative fits", []int8{-100, -28}, -128, nil},
+		{"overflow", []int8{100, 28}, 100, ErrOverflow},
+		{"underflow", []int8{-100, -29}, -100, ErrOverflow},
+		{"recovers before overflow", []int8{127, -1, 1}, 127, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SumChecked(tt.numbers)
+			if got != tt.want || !errors.Is(err, tt.err) {
+				t.Errorf("SumChecked(%v) = %d, %v, want %d, %v", tt.numbers, got, err, tt.want, tt.err)
+			}
+		})
+	}
+
+	if _, err := SumChecked([]uint8{200, 56}); !errors.Is(err, ErrOverflow) {
+		t.Errorf("SumChecked(uint8 overflow) error = %v, want %v", err, ErrOverflow)
+	}
+	if got, err := SumChecked([]uint64{math.MaxUint64 - 1, 1}); err != nil || got != math.MaxUint64 {
+		t.Errorf("SumChecked(uint64) = %d, %v, want %d, nil", got, err, uint64(math.MaxUint64))
+	}
+}
+
+func TestStatistics(t *testing.T) {
+	tests := []struct {
+		name     string
+		numbers  []float64
+		mean     float64
+		min, max float64
+		median   float64
+		variance float64
+	}{
+		{"single", []float64{4}, 4, 4, 4, 4, 0},
+		{"odd", []float64{3, 1, 2}, 2, 1, 3, 2, 2.0 / 3},
+		{"even", []float64{2, 4, 4, 4, 5, 5, 7, 9}, 5, 2, 9, 4.5, 4},
+		{"negative", []float64{-1, -3}, -2, -3, -1, -2, 1},
+		{"huge values", []float64{math.MaxFloat64, math.MaxFloat64}, math.MaxFloat64, math.MaxFloat64, math.MaxFloat64, math.MaxFloat64, 0},
+		{"inf", []float64{1, inf}, inf, 1, inf, inf, nan},
+		{"both infs", []float64{-inf, inf}, nan, -inf, inf, nan, nan},
+		{"nan", []float64{1, nan, 3}, nan, nan, nan, nan, nan},
+		{"nan first", []float64{nan, 1, 3}, nan, nan, nan, nan, nan},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := func(what string, got, want float64, err error) {
+				t.Helper()
+				if err != nil || !same(got, want) {
+					t.Errorf("%s(%v) = %v, %v, want %v", what, tt.numbers, got, err, want)
+				}
+			}
+			mean, err := Mean(tt.numbers)
+			check("Mean", mean, tt.mean, err)
+			min, err := Min(tt.numbers)
+			check("Min", min, tt.min, err)
+			max, err := Max(tt.numbers)
+			check("Max", max, tt.max, err)
+			median, err := Median(tt.numbers)
+			check("Median", median, tt.median, err)
+			variance, err := Variance(tt.numbers)
+			check("Variance", variance, tt.variance, err)
+		})
+	}
+}
+
+func TestStatisticsIntegers(t *testing.T) {
+	numbers := []uint8{250, 250, 250, 10}
+	if mean, err := Mean(numbers); err != nil || mean != 190 {
+		t.Errorf("Mean(%v) = %v, %v, want 190", numbers, mean, err)
+	}
+	if v, err := SampleVariance([]int{2, 4, 4, 4, 5, 5, 7, 9}); err != nil || !same(v, 32.0/7) {
+		t.Errorf("SampleVariance = %v, %v, want %v", v, err, 32.0/7)
+	}
+	if sd, err := StdDev([]int{2, 4, 4, 4, 5, 5, 7, 9}); err != nil || sd != 2 {
+		t.Errorf("StdDev = %v, %v, want 2", sd, err)
+	}
+	if min, err := Min([]int64{math.MinInt64, 0}); err != nil || min != math.MinInt64 {
+		t.Errorf("Min = %v, %v, want %v", min, err, int64(math.MinInt64))
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	var empty []int
+	checks := map[string]error{}
+	_, checks["Mean"] = Mean(empty)
+	_, checks["Min"] = Min(empty)
+	_, checks["Max"] = Max(empty)
+	_, checks["Median"] = Median(empty)
+	_, checks["Variance"] = Variance(empty)
+	_, checks["SampleVariance"] = SampleVariance([]int{1})
+	_, checks["StdDev"] = StdDev(empty)
+	_, checks["Percentile"] = Percentile(empty, 50)
+	_, checks["Percentiles"] = Percentiles(empty, 50)
+	for name, err := range checks {
+		if !errors.Is(err, ErrEmpty) {
+			t.Errorf("%s of too few values: error = %v, want %v", name, err, ErrEmpty)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	numbers := []int{50, 10, 40, 20, 30}
+	tests := []struct {
+		p    float64
+		want float64
+		err  error
+	}{
+		{0, 10, nil},
+		{25, 20, nil},
+		{50, 30, nil},
+		{90, 46, nil},
+		{100, 50, nil},
+		{-1, 0, ErrInvalidPercentile},
+		{101, 0, ErrInvalidPercentile},
+		{nan, 0, ErrInvalidPercentile},
+	}
+	for _, tt := range tests {
+		got, err := Percentile(numbers, tt.p)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("Percentile(%v, %v) = %v, %v, want %v, %v", numbers, tt.p, got, err, tt.want, tt.err)
+		}
+	}
+
+	got, err := Percentiles(numbers, 0, 50, 100)
+	if err != nil || len(got) != 3 || got[0] != 10 || got[1] != 30 || got[2] != 50 {
+		t.Errorf("Percentiles = %v, %v, want [10 30 50]", got, err)
+	}
+	if _, err := Percentiles(numbers, 50, 200); !errors.Is(err, ErrInvalidPercentile) {
+		t.Errorf("Percentiles with 200: error = %v, want %v", err, ErrInvalidPercentile)
+	}
+	if numbers[0] != 50 {
+		t.Errorf("Percentile modified its input: %v", numbers)
+	}
+}
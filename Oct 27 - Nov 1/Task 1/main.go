@@ -1,16 +1,22 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+
+	"sum/aggregate"
+)
 
 func Sum(numbers []int) int {
-	sum := 0
-	for _, num := range numbers {
-		sum += num
-	}
-	return sum
+	return aggregate.Sum(numbers)
 }
 
 func main() {
 	fmt.Println(Sum([]int{1, 2, 3, 4, 5}))
 	fmt.Println(Sum([]int{}))
+
+	fmt.Println(aggregate.SumChecked([]int64{math.MaxInt64, 1}))
+	fmt.Println(aggregate.Sum([]float64{0.1, 0.2, 0.3}))
+	fmt.Println(aggregate.Median([]int{7, 1, 3, 5}))
+	fmt.Println(aggregate.Percentile([]uint8{10, 20, 30, 40, 50}, 90))
 }
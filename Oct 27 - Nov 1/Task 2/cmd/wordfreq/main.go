@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"word_frequency/wordfreq"
+)
+
+const usage = `Usage: wordfreq [flags] [FILE...]
+
+Counts words (or n-grams) in the given files, or in standard input when no
+FILE or "-" is given, and prints them from most to least frequent.
+
+Flags:
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("wordfreq", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+
+	top := fs.Int("top", 0, "only print the N most frequent entries (0 for all)")
+	ngram := fs.Int("ngram", 1, "count runs of 1, 2 or 3 words")
+	minCount := fs.Int("min-count", 0, "skip entries seen fewer times")
+	minLength := fs.Int("min-length", 0, "skip entries with a word shorter than this")
+	format := fs.String("format", "text", "output format: text, csv or json")
+	unicodeWords := fs.Bool("unicode", false, "Unicode-aware tokenization (keeps accents, contractions, hyphens)")
+	stopwords := fs.String("stopwords", "", "comma-separated stopword languages, e.g. en,am ("+strings.Join(wordfreq.StopwordLanguages(), ", ")+")")
+	workers := fs.Int("workers", runtime.NumCPU(), "files counted in parallel")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	if *ngram < 1 || *ngram > 3 {
+		fmt.Fprintln(stderr, "wordfreq: --ngram must be 1, 2 or 3")
+		return 2
+	}
+	if *format != "text" && *format != "csv" && *format != "json" {
+		fmt.Fprintln(stderr, "wordfreq: --format must be text, csv or json")
+		return 2
+	}
+
+	opts := wordfreq.DefaultOptions
+	if *unicodeWords {
+		opts = wordfreq.UnicodeOptions
+	}
+	if *stopwords != "" {
+		opts.StopwordLanguages = strings.Split(*stopwords, ",")
+	}
+	opts.NGram = *ngram
+
+	var files []string
+	readStdin := fs.NArg() == 0
+	for _, arg := range fs.Args() {
+		if arg == "-" {
+			readStdin = true
+		} else {
+			files = append(files, arg)
+		}
+	}
+
+	counter, err := wordfreq.NewWordFrequencyCounterWithOptions(opts)
+	if err != nil {
+		fmt.Fprintln(stderr, "wordfreq:", err)
+		return 2
+	}
+
+	status := 0
+	if len(files) > 0 {
+		fileCounts, err := wordfreq.CountFiles(files, *workers, opts)
+		if err != nil {
+			fmt.Fprintln(stderr, "wordfreq:", err)
+			status = 1
+		}
+		counter.Merge(fileCounts)
+	}
+	if readStdin {
+		if err := counter.Count(stdin); err != nil {
+			fmt.Fprintln(stderr, "wordfreq: stdin:", err)
+			status = 1
+		}
+	}
+
+	ranked := wordfreq.Rank(counter.Counts(), wordfreq.RankOptions{
+		Top:       *top,
+		MinCount:  *minCount,
+		MinLength: *minLength,
+	})
+	if err := write(stdout, *format, counter, ranked); err != nil {
+		fmt.Fprintln(stderr, "wordfreq:", err)
+		return 1
+	}
+	return status
+}
+
+func write(w io.Writer, format string, counter *wordfreq.WordFrequencyCounter, ranked []wordfreq.WordCount) error {
+	switch format {
+	case "csv":
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"word", "count"})
+		for _, wc := range ranked {
+			writer.Write([]string{wc.Word, strconv.Itoa(wc.Count)})
+		}
+		writer.Flush()
+		return writer.Error()
+
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			Total  int                  `json:"total"`
+			Unique int                  `json:"unique"`
+			Words  []wordfreq.WordCount `json:"words"`
+		}{counter.Total(), counter.Unique(), ranked})
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, wc := range ranked {
+		fmt.Fprintf(tw, "%s\t%d\n", wc.Word, wc.Count)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	if err := os.WriteFile(first, []byte("the cat saw the dog"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("The dog ran"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.txt")
+
+	tests := []struct {
+		name   string
+		args   []string
+		stdin  string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{"stdin", nil, "b a b", 0, "b  2\na  1\n", ""},
+		{"files", []string{first, second}, "", 0, "the  3\ndog  2\ncat  1\nran  1\nsaw  1\n", ""},
+		{"files and stdin", []string{first, "-"}, "cat", 0, "cat  2\nthe  2\ndog  1\nsaw  1\n", ""},
+		{"top", []string{"-top", "1", first}, "", 0, "the  2\n", ""},
+		{"thresholds", []string{"-min-count", "2", "-min-length", "3", first, second}, "", 0, "the  3\ndog  2\n", ""},
+		{"bigrams", []string{"-ngram", "2", "-top", "2"}, "a b a b", 0, "a b  2\nb a  1\n", ""},
+		{"stopwords", []string{"-stopwords", "en", second}, "", 0, "dog  1\nran  1\n", ""},
+		{"unicode", []string{"-unicode"}, "Don’t don't", 0, "don't  2\n", ""},
+		{"csv", []string{"-format", "csv"}, "b, a b", 0, "word,count\nb,2\na,1\n", ""},
+		{"help", []string{"-h"}, "", 0, "", "Usage: wordfreq"},
+		{"unknown flag", []string{"-sideways"}, "", 2, "", "flag provided but not defined"},
+		{"bad n-gram", []string{"-ngram", "4"}, "", 2, "", "--ngram must be 1, 2 or 3"},
+		{"bad format", []string{"-format", "xml"}, "", 2, "", "--format must be"},
+		{"unknown stopwords", []string{"-stopwords", "en,xx"}, "", 2, "", `"xx"`},
+		{"unreadable file", []string{missing, second}, "", 1, "dog  1\nran  1\nthe  1\n", missing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d (stderr %q)", code, tt.code, stderr.String())
+			}
+			if stdout.String() != tt.stdout {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.stdout)
+			}
+			if !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("stderr = %q, want it to contain %q", stderr.String(), tt.stderr)
+			}
+			if tt.stderr == "" && stderr.Len() > 0 {
+				t.Errorf("unexpected stderr %q", stderr.String())
+			}
+		})
+	}
+}
+
+func TestRunJSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-format", "json", "-top", "2"}, strings.NewReader("c b a b c c"), &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code = %d: %s", code, stderr.String())
+	}
+
+	var got struct {
+		Total  int `json:"total"`
+		Unique int `json:"unique"`
+		Words  []struct {
+			Word  string `json:"word"`
+			Count int    `json:"count"`
+		} `json:"words"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("output %q: %v", stdout.String(), err)
+	}
+	if got.Total != 6 || got.Unique != 3 {
+		t.Errorf("total = %d, unique = %d, want 6 and 3", got.Total, got.Unique)
+	}
+	if len(got.Words) != 2 || got.Words[0].Word != "c" || got.Words[0].Count != 3 || got.Words[1].Word != "b" {
+		t.Errorf("words = %+v, want c 3 then b 2", got.Words)
+	}
+}
@@ -0,0 +1,5 @@
+module word_frequency
+
+go 1.21
+
+require golang.org/x/text v0.13.0
@@ -0,0 +1,226 @@
+package wordfreq
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxWordSize bounds the scanner buffer. Input is read a word at a time, so
+// memory use depends on the vocabulary rather than the size of the input.
+// Whitespace-separated runs of maxWordSize bytes or more are not words a
+// person would count; Count skips them instead of failing.
+const maxWordSize = 1 << 20
+
+// WordFrequencyCounter accumulates word counts from any number of readers.
+// Counters can be merged, so separate inputs can be counted independently
+// and combined afterwards. A counter is not safe for concurrent use.
+type WordFrequencyCounter struct {
+	tokenizer *Tokenizer
+	ngram     int
+	tokens    []string
+	counts    map[string]int
+	total     int
+}
+
+// NewWordFrequencyCounter returns a counter that splits words like
+// WordFrequencyCount.
+func NewWordFrequencyCounter() *WordFrequencyCounter {
+	counter, _ := NewWordFrequencyCounterWithOptions(DefaultOptions)
+	return counter
+}
+
+func NewWordFrequencyCounterWithOptions(opts Options) (*WordFrequencyCounter, error) {
+	tokenizer, err := NewTokenizer(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.NGram < 0 {
+		return nil, fmt.Errorf("invalid n-gram size %d", opts.NGram)
+	}
+	ngram := opts.NGram
+	if ngram == 0 {
+		ngram = 1
+	}
+	return &WordFrequencyCounter{tokenizer: tokenizer, ngram: ngram, counts: make(map[string]int)}, nil
+}
+
+// Count reads r to the end, adding every word, or n-gram, it contains.
+// A whitespace-separated run of maxWordSize bytes or more is skipped as if it
+// were not there.
+func (c *WordFrequencyCounter) Count(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxWordSize)
+	scanner.Split(scanWords(maxWordSize))
+
+	window := make([]string, 0, c.ngram)
+	for scanner.Scan() {
+		c.tokens = c.tokenizer.appendTokens(c.tokens[:0], scanner.Text())
+		for _, word := range c.tokens {
+			if c.ngram == 1 {
+				c.counts[word]++
+				c.total++
+				continue
+			}
+			if len(window) == c.ngram {
+				copy(window, window[1:])
+				window = window[:c.ngram-1]
+			}
+			window = append(window, word)
+			if len(window) == c.ngram {
+				c.counts[strings.Join(window, " ")]++
+				c.total++
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// scanWords is like bufio.ScanWords, except that a word of maxLen bytes or
+// more is dropped instead of stopping the scan with bufio.ErrTooLong. Once
+// maxLen bytes of a word are buffered they are discarded, and the rest of the
+// word is discarded as it arrives, up to the next space.
+func scanWords(maxLen int) bufio.SplitFunc {
+	skipping := false
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		start := 0
+		if skipping {
+			for start < len(data) {
+				r, width := utf8.DecodeRune(data[start:])
+				if unicode.IsSpace(r) {
+					skipping = false
+					break
+				}
+				start += width
+			}
+			if skipping {
+				return len(data), nil, nil
+			}
+		}
+
+		for start < len(data) {
+			r, width := utf8.DecodeRune(data[start:])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			start += width
+		}
+		for i := start; i < len(data); {
+			r, width := utf8.DecodeRune(data[i:])
+			if unicode.IsSpace(r) {
+				return i + width, data[start:i], nil
+			}
+			i += width
+		}
+
+		switch {
+		case len(data)-start >= maxLen:
+			skipping = !atEOF
+			return len(data), nil, nil
+		case atEOF && len(data) > start:
+			return len(data), data[start:], nil
+		}
+		return start, nil, nil
+	}
+}
+
+// CountFile counts the words of the file at path.
+func (c *WordFrequencyCounter) CountFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := c.Count(file); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Merge adds the counts of other to c.
+func (c *WordFrequencyCounter) Merge(other *WordFrequencyCounter) {
+	for word, n := range other.counts {
+		c.counts[word] += n
+	}
+	c.total += other.total
+}
+
+// Counts returns a copy of the word frequencies counted so far.
+func (c *WordFrequencyCounter) Counts() map[string]int {
+	counts := make(map[string]int, len(c.counts))
+	for word, n := range c.counts {
+		counts[word] = n
+	}
+	return counts
+}
+
+// Total returns the number of words counted, including repeats.
+func (c *WordFrequencyCounter) Total() int {
+	return c.total
+}
+
+// Unique returns the number of distinct words counted.
+func (c *WordFrequencyCounter) Unique() int {
+	return len(c.counts)
+}
+
+// CountFiles counts paths in parallel on a pool of workers, each with its own
+// counter, and merges the results. Files that cannot be read are reported in
+// the returned error; the counts of the others are still returned.
+func CountFiles(paths []string, workers int, opts Options) (*WordFrequencyCounter, error) {
+	total, err := NewWordFrequencyCounterWithOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+
+	jobs := make(chan string)
+	results := make(chan *WordFrequencyCounter, workers)
+	errs := make(chan error, len(paths))
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			counter, _ := NewWordFrequencyCounterWithOptions(opts)
+			for path := range jobs {
+				if err := counter.CountFile(path); err != nil {
+					errs <- err
+				}
+			}
+			results <- counter
+		}()
+	}
+
+	for _, path := range paths {
+		jobs <- path
+	}
+	close(jobs)
+	wg.Wait()
+	close(results)
+	close(errs)
+
+	for counter := range results {
+		total.Merge(counter)
+	}
+
+	var all []error
+	for err := range errs {
+		all = append(all, err)
+	}
+	return total, errors.Join(all...)
+}
@@ -0,0 +1,188 @@
+package wordfreq
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		inputs []string
+		want   map[string]int
+		total  int
+	}{
+		{"empty", []string{""}, map[string]int{}, 0},
+		{"words and case", []string{"The cat saw THE dog"}, map[string]int{"the": 2, "cat": 1, "saw": 1, "dog": 1}, 5},
+		{"punctuation and whitespace", []string{"  Hello,\tworld!\n\nhello...  "}, map[string]int{"hello": 2, "world": 1}, 3},
+		{"punctuation-only words", []string{"-- a -- b"}, map[string]int{"a": 1, "b": 1}, 2},
+		{"several inputs", []string{"one two", "two three", "three"}, map[string]int{"one": 1, "two": 2, "three": 2}, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := NewWordFrequencyCounter()
+			for _, input := range tt.inputs {
+				if err := counter.Count(strings.NewReader(input)); err != nil {
+					t.Fatalf("Count(%q): %v", input, err)
+				}
+			}
+			if got := counter.Counts(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Counts() = %v, want %v", got, tt.want)
+			}
+			if counter.Total() != tt.total {
+				t.Errorf("Total() = %d, want %d", counter.Total(), tt.total)
+			}
+			if counter.Unique() != len(tt.want) {
+				t.Errorf("Unique() = %d, want %d", counter.Unique(), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestCountSkipsOversizedWords(t *testing.T) {
+	huge := strings.Repeat("x", maxWordSize+100)
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]int
+	}{
+		{"between words", "a " + huge + " b a", map[string]int{"a": 2, "b": 1}},
+		{"at the start", huge + "\nb", map[string]int{"b": 1}},
+		{"at the end", "a " + huge, map[string]int{"a": 1}},
+		{"exactly the limit", "a " + huge[:maxWordSize] + " b", map[string]int{"a": 1, "b": 1}},
+		{"just under the limit", huge[:maxWordSize-1] + " b", map[string]int{huge[:maxWordSize-1]: 1, "b": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := NewWordFrequencyCounter()
+			if err := counter.Count(strings.NewReader(tt.input)); err != nil {
+				t.Fatalf("Count: %v", err)
+			}
+			if got := counter.Counts(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Counts() has %d words, want %v", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestScanWords(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"ASCII spaces", " a  bb\tccc\n", []string{"a", "bb", "ccc"}},
+		{"Unicode spaces", "a b c\u0085d", []string{"a", "b", "c", "d"}},
+		{"multi-byte words", "héllo wörld", []string{"héllo", "wörld"}},
+		{"long word skipped", "a 0123456789abcdef b", []string{"a", "b"}},
+		{"long word at the end", "a 0123456789abcdef", []string{"a"}},
+		{"long words in a row", "0123456789 0123456789 c", []string{"c"}},
+		{"word of maxLen-1", "1234567 b", []string{"1234567", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A tiny buffer makes the split function see partial words.
+			scanner := bufio.NewScanner(strings.NewReader(tt.input))
+			scanner.Buffer(make([]byte, 2), 8)
+			scanner.Split(scanWords(8))
+
+			var got []string
+			for scanner.Scan() {
+				got = append(got, scanner.Text())
+			}
+			if err := scanner.Err(); err != nil {
+				t.Fatalf("scan: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("words = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a := NewWordFrequencyCounter()
+	b := NewWordFrequencyCounter()
+	if err := a.Count(strings.NewReader("red green red")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Count(strings.NewReader("green blue")); err != nil {
+		t.Fatal(err)
+	}
+
+	a.Merge(b)
+	want := map[string]int{"red": 2, "green": 2, "blue": 1}
+	if got := a.Counts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("merged Counts() = %v, want %v", got, want)
+	}
+	if a.Total() != 5 || a.Unique() != 3 {
+		t.Errorf("merged Total() = %d, Unique() = %d, want 5 and 3", a.Total(), a.Unique())
+	}
+	if got := b.Counts(); !reflect.DeepEqual(got, map[string]int{"green": 1, "blue": 1}) {
+		t.Errorf("Merge changed its argument: %v", got)
+	}
+
+	// Counts returns a copy
+	a.Counts()["red"] = 100
+	if a.Counts()["red"] != 2 {
+		t.Error("changing the result of Counts() changed the counter")
+	}
+}
+
+func TestCountFiles(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	want := make(map[string]int)
+	for i, text := range []string{"a b c", "b c", "c", "", "a a a a", "d"} {
+		path := filepath.Join(dir, "file"+string(rune('0'+i))+".txt")
+		if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+		for _, word := range strings.Fields(text) {
+			want[word]++
+		}
+	}
+	missing := filepath.Join(dir, "missing.txt")
+
+	tests := []struct {
+		name    string
+		paths   []string
+		workers int
+		wantErr bool
+	}{
+		{"one worker", paths, 1, false},
+		{"several workers", paths, 3, false},
+		{"more workers than files", paths, 20, false},
+		{"no workers means one", paths, 0, false},
+		{"unreadable file", append([]string{missing}, paths...), 4, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter, err := CountFiles(tt.paths, tt.workers, DefaultOptions)
+			if tt.wantErr {
+				if !errors.Is(err, fs.ErrNotExist) || !strings.Contains(err.Error(), missing) {
+					t.Errorf("error = %v, want one naming %s", err, missing)
+				}
+			} else if err != nil {
+				t.Fatalf("CountFiles: %v", err)
+			}
+			if got := counter.Counts(); !reflect.DeepEqual(got, want) {
+				t.Errorf("Counts() = %v, want %v", got, want)
+			}
+			if counter.Total() != 11 {
+				t.Errorf("Total() = %d, want 11", counter.Total())
+			}
+		})
+	}
+
+	if _, err := CountFiles(nil, 4, DefaultOptions); err != nil {
+		t.Errorf("CountFiles with no paths: %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package wordfreq
+
+import (
+	"container/heap"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+type WordCount struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+// RankOptions selects which counts Rank returns. Zero values disable each
+// limit.
+type RankOptions struct {
+	// Top keeps only the N most frequent entries.
+	Top int
+	// MinCount drops entries seen fewer times.
+	MinCount int
+	// MinLength drops entries containing a word shorter than this many
+	// characters; for n-grams every word must be long enough.
+	MinLength int
+}
+
+// Rank returns counts ordered by descending count and then ascending word,
+// so the output is the same on every run. With Top set, a bounded heap keeps
+// only the best N entries instead of sorting the whole vocabulary.
+func Rank(counts map[string]int, opts RankOptions) []WordCount {
+	keep := func(word string, n int) bool {
+		if n < opts.MinCount {
+			return false
+		}
+		if opts.MinLength > 0 {
+			for _, w := range strings.Split(word, " ") {
+				if utf8.RuneCountInString(w) < opts.MinLength {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	if opts.Top <= 0 {
+		ranked := make([]WordCount, 0, len(counts))
+		for word, n := range counts {
+			if keep(word, n) {
+				ranked = append(ranked, WordCount{word, n})
+			}
+		}
+		sort.Slice(ranked, func(i, j int) bool { return ranksBefore(ranked[i], ranked[j]) })
+		return ranked
+	}
+
+	h := make(minHeap, 0, opts.Top+1)
+	for word, n := range counts {
+		if !keep(word, n) {
+			continue
+		}
+		wc := WordCount{word, n}
+		if len(h) < opts.Top {
+			heap.Push(&h, wc)
+		} else if ranksBefore(wc, h[0]) {
+			h[0] = wc
+			heap.Fix(&h, 0)
+		}
+	}
+
+	ranked := make([]WordCount, len(h))
+	for i := len(h) - 1; i >= 0; i-- {
+		ranked[i] = heap.Pop(&h).(WordCount)
+	}
+	return ranked
+}
+
+func ranksBefore(a, b WordCount) bool {
+	if a.Count != b.Count {
+		return a.Count > b.Count
+	}
+	return a.Word < b.Word
+}
+
+// minHeap keeps the lowest-ranked entry at the root so it can be replaced
+// when a better one arrives.
+type minHeap []WordCount
+
+func (h minHeap) Len() int            { return len(h) }
+func (h minHeap) Less(i, j int) bool  { return ranksBefore(h[j], h[i]) }
+func (h minHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *minHeap) Push(x interface{}) { *h = append(*h, x.(WordCount)) }
+func (h *minHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
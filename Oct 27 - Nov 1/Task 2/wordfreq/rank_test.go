@@ -0,0 +1,96 @@
+package wordfreq
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNGrams(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   Options
+		inputs []string
+		want   map[string]int
+	}{
+		{"bigrams", Options{FoldCase: true, NGram: 2}, []string{"The cat, the CAT"},
+			map[string]int{"the cat": 2, "cat the": 1}},
+		{"trigrams", Options{NGram: 3}, []string{"a b c d"}, map[string]int{"a b c": 1, "b c d": 1}},
+		{"runs span lines", Options{NGram: 2}, []string{"a\nb\n\nc"}, map[string]int{"a b": 1, "b c": 1}},
+		{"runs never span inputs", Options{NGram: 2}, []string{"a b", "c d"}, map[string]int{"a b": 1, "c d": 1}},
+		{"too few words", Options{NGram: 3}, []string{"a b"}, map[string]int{}},
+		{"stopwords are dropped first", Options{FoldCase: true, NGram: 2, StopwordLanguages: []string{"en"}}, []string{"cat and the hat"},
+			map[string]int{"cat hat": 1}},
+		{"Unicode words", Options{Unicode: true, KeepApostrophes: true, NGram: 2}, []string{"don't stop, now"},
+			map[string]int{"don't stop": 1, "stop now": 1}},
+		{"zero is single words", Options{}, []string{"a b a"}, map[string]int{"a": 2, "b": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter, err := NewWordFrequencyCounterWithOptions(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			total := 0
+			for _, input := range tt.inputs {
+				if err := counter.Count(strings.NewReader(input)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, n := range tt.want {
+				total += n
+			}
+			if got := counter.Counts(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Counts() = %v, want %v", got, tt.want)
+			}
+			if counter.Total() != total {
+				t.Errorf("Total() = %d, want %d", counter.Total(), total)
+			}
+		})
+	}
+
+	if _, err := NewWordFrequencyCounterWithOptions(Options{NGram: -1}); err == nil {
+		t.Error("negative n-gram size accepted")
+	}
+}
+
+func TestRank(t *testing.T) {
+	counts := map[string]int{
+		"pear": 3, "apple": 3, "fig": 3,
+		"kiwi": 1, "plum": 5, "banana": 1,
+		"big apple": 2, "a pear": 2,
+	}
+	all := []WordCount{
+		{"plum", 5}, {"apple", 3}, {"fig", 3}, {"pear", 3},
+		{"a pear", 2}, {"big apple", 2}, {"banana", 1}, {"kiwi", 1},
+	}
+
+	tests := []struct {
+		name string
+		opts RankOptions
+		want []WordCount
+	}{
+		{"all, ties by word", RankOptions{}, all},
+		{"top cuts inside a tie", RankOptions{Top: 3}, all[:3]},
+		{"top of one", RankOptions{Top: 1}, all[:1]},
+		{"top beyond the vocabulary", RankOptions{Top: 50}, all},
+		{"min count", RankOptions{MinCount: 3}, all[:4]},
+		{"min length checks every word", RankOptions{MinLength: 4}, []WordCount{{"plum", 5}, {"apple", 3}, {"pear", 3}, {"banana", 1}, {"kiwi", 1}}},
+		{"min length of n-grams", RankOptions{MinLength: 3}, []WordCount{{"plum", 5}, {"apple", 3}, {"fig", 3}, {"pear", 3}, {"big apple", 2}, {"banana", 1}, {"kiwi", 1}}},
+		{"all limits", RankOptions{Top: 2, MinCount: 2, MinLength: 4}, []WordCount{{"plum", 5}, {"apple", 3}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Map order differs between runs; the ranking must not.
+			for i := 0; i < 20; i++ {
+				if got := Rank(counts, tt.opts); !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("Rank() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	if got := Rank(nil, RankOptions{Top: 3}); len(got) != 0 {
+		t.Errorf("Rank(nil) = %v, want nothing", got)
+	}
+}
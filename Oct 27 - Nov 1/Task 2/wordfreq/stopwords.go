@@ -0,0 +1,49 @@
+package wordfreq
+
+import "sort"
+
+var stopwords = map[string][]string{
+	"en": {
+		"a", "about", "after", "all", "also", "an", "and", "any", "are", "as", "at",
+		"be", "because", "been", "but", "by", "can", "could", "did", "do", "does",
+		"for", "from", "had", "has", "have", "he", "her", "him", "his", "how", "i",
+		"if", "in", "into", "is", "it", "its", "me", "my", "no", "not", "of", "on",
+		"or", "our", "she", "so", "than", "that", "the", "their", "them", "then",
+		"there", "these", "they", "this", "those", "to", "up", "us", "was", "we",
+		"were", "what", "when", "which", "who", "will", "with", "would", "you", "your",
+	},
+	"fr": {
+		"au", "aux", "avec", "ce", "ces", "dans", "de", "des", "du", "elle", "en",
+		"est", "et", "eux", "il", "ils", "je", "la", "le", "les", "leur", "lui",
+		"ma", "mais", "me", "mes", "moi", "mon", "ne", "nous", "on", "ou", "par",
+		"pas", "pour", "qu", "que", "qui", "sa", "se", "ses", "son", "sur", "ta",
+		"te", "tes", "toi", "ton", "tu", "un", "une", "vos", "votre", "vous",
+	},
+	"es": {
+		"a", "al", "como", "con", "de", "del", "el", "ella", "ellos", "en", "es",
+		"esta", "este", "la", "las", "le", "les", "lo", "los", "mas", "me", "mi",
+		"no", "nos", "o", "para", "pero", "por", "que", "se", "si", "sin", "sobre",
+		"su", "sus", "te", "tu", "un", "una", "uno", "y", "ya", "yo",
+	},
+	"de": {
+		"aber", "als", "am", "an", "auch", "auf", "aus", "bei", "bin", "bis", "das",
+		"dass", "dem", "den", "der", "des", "die", "du", "ein", "eine", "einem",
+		"einen", "einer", "er", "es", "für", "hat", "ich", "ihr", "im", "in", "ist",
+		"mit", "nicht", "noch", "nur", "oder", "sein", "sich", "sie", "sind", "so",
+		"um", "und", "von", "vor", "war", "wie", "wir", "zu", "zum", "zur",
+	},
+	"am": {
+		"እና", "ነው", "ናቸው", "ነበር", "ላይ", "ውስጥ", "ወደ", "ግን", "ይህ", "ያ",
+		"እንደ", "ወይም", "ሁሉ", "እሱ", "እሷ", "እኔ", "እኛ", "እነሱ", "አንተ", "አንቺ",
+	},
+}
+
+// StopwordLanguages lists the language codes with built-in stopword lists.
+func StopwordLanguages() []string {
+	langs := make([]string, 0, len(stopwords))
+	for lang := range stopwords {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
@@ -0,0 +1,197 @@
+package wordfreq
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Options configures a Tokenizer. The zero value keeps ASCII words as they
+// are written; DefaultOptions, which WordFrequencyCount uses, also lower-cases
+// them.
+type Options struct {
+	// Unicode treats any Unicode letter, number or combining mark as part of
+	// a word and splits words on everything else. Without it, only ASCII
+	// letters, digits and underscores are kept and other characters are
+	// deleted from the whitespace-separated word they appear in.
+	Unicode bool
+	// KeepApostrophes keeps an apostrophe between two letters, so "don't"
+	// stays one word. Typographic apostrophes are rewritten as '. Unicode only.
+	KeepApostrophes bool
+	// KeepHyphens keeps a hyphen between two letters, as in "well-known".
+	// Unicode only.
+	KeepHyphens bool
+	// SplitIdeographs makes every Han, Hiragana and Katakana character its own
+	// word, since those scripts do not separate words with spaces. Unicode only.
+	SplitIdeographs bool
+	// FoldCase lower-cases ASCII words, or applies full Unicode case folding
+	// (so "Straße" and "STRASSE" match) in Unicode mode.
+	FoldCase bool
+	// NormalizeNFC composes characters before matching, so "é" written as
+	// e + combining acute counts the same as the precomposed "é".
+	NormalizeNFC bool
+	// StopwordLanguages names built-in stopword lists (see StopwordLanguages)
+	// whose words are dropped from the counts.
+	StopwordLanguages []string
+	// Stopwords are additional words to drop.
+	Stopwords []string
+	// NGram makes a WordFrequencyCounter count runs of this many consecutive
+	// words, joined by a space, instead of single words. 0 and 1 both count
+	// single words. Runs never span two inputs.
+	NGram int
+}
+
+var (
+	// DefaultOptions are the rules of WordFrequencyCount.
+	DefaultOptions = Options{FoldCase: true}
+	// UnicodeOptions keeps words in any script, contractions and hyphenated
+	// compounds intact.
+	UnicodeOptions = Options{
+		Unicode:         true,
+		KeepApostrophes: true,
+		KeepHyphens:     true,
+		FoldCase:        true,
+		NormalizeNFC:    true,
+	}
+)
+
+// Tokenizer splits text into words according to its Options. It holds
+// case-folding state and must not be shared between goroutines; create one
+// per goroutine with NewTokenizer.
+type Tokenizer struct {
+	opts      Options
+	caser     cases.Caser
+	stopwords map[string]struct{}
+}
+
+func NewTokenizer(opts Options) (*Tokenizer, error) {
+	t := &Tokenizer{opts: opts, caser: cases.Fold()}
+
+	var words []string
+	for _, lang := range opts.StopwordLanguages {
+		list, ok := stopwords[strings.ToLower(lang)]
+		if !ok {
+			return nil, fmt.Errorf("no stopword list for language %q", lang)
+		}
+		words = append(words, list...)
+	}
+	words = append(words, opts.Stopwords...)
+
+	if len(words) > 0 {
+		set := make(map[string]struct{}, len(words))
+		for _, word := range words {
+			for _, token := range t.appendTokens(nil, word) {
+				set[token] = struct{}{}
+			}
+		}
+		t.stopwords = set
+	}
+	return t, nil
+}
+
+// Tokens returns the words of text in order.
+func (t *Tokenizer) Tokens(text string) []string {
+	var tokens []string
+	for _, field := range strings.Fields(text) {
+		tokens = t.appendTokens(tokens, field)
+	}
+	return tokens
+}
+
+// appendTokens appends the words found in field, which contains no
+// whitespace, to dst and drops stopwords.
+func (t *Tokenizer) appendTokens(dst []string, field string) []string {
+	start := len(dst)
+	if t.opts.Unicode {
+		dst = t.appendUnicode(dst, field)
+	} else if word := t.asciiWord(field); word != "" {
+		dst = append(dst, word)
+	}
+
+	if t.stopwords == nil {
+		return dst
+	}
+	kept := dst[:start]
+	for _, word := range dst[start:] {
+		if _, stop := t.stopwords[word]; !stop {
+			kept = append(kept, word)
+		}
+	}
+	return kept
+}
+
+func (t *Tokenizer) asciiWord(field string) string {
+	var b strings.Builder
+	for _, r := range field {
+		if t.opts.FoldCase {
+			r = unicode.ToLower(r)
+		}
+		if r < unicode.MaxASCII && (r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func (t *Tokenizer) appendUnicode(dst []string, field string) []string {
+	if t.opts.NormalizeNFC {
+		field = norm.NFC.String(field)
+	}
+	runes := []rune(field)
+
+	var b strings.Builder
+	flush := func() {
+		if b.Len() == 0 {
+			return
+		}
+		word := b.String()
+		if t.opts.FoldCase {
+			word = t.caser.String(word)
+		}
+		dst = append(dst, word)
+		b.Reset()
+	}
+
+	for i, r := range runes {
+		switch {
+		case t.opts.SplitIdeographs && isIdeograph(r):
+			flush()
+			b.WriteRune(r)
+			flush()
+		case isWordRune(r):
+			b.WriteRune(r)
+		case t.opts.KeepApostrophes && isApostrophe(r) && joinsWords(runes, i):
+			b.WriteRune('\'')
+		case t.opts.KeepHyphens && isHyphen(r) && joinsWords(runes, i):
+			b.WriteRune('-')
+		default:
+			flush()
+		}
+	}
+	flush()
+	return dst
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r)
+}
+
+func isIdeograph(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
+
+func isApostrophe(r rune) bool {
+	return r == '\'' || r == '’'
+}
+
+func isHyphen(r rune) bool {
+	return r == '-' || r == '‐'
+}
+
+// joinsWords reports whether the rune at i sits between two word runes.
+func joinsWords(runes []rune, i int) bool {
+	return i > 0 && i+1 < len(runes) && isWordRune(runes[i-1]) && isWordRune(runes[i+1])
+}
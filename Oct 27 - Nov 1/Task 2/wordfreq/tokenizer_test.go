@@ -0,0 +1,111 @@
+package wordfreq
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokens(t *testing.T) {
+	unicodeOnly := Options{Unicode: true}
+
+	tests := []struct {
+		name  string
+		opts  Options
+		input string
+		want  []string
+	}{
+		{"zero value keeps case", Options{}, "Hello World", []string{"Hello", "World"}},
+		{"default folds ASCII case", DefaultOptions, "Hello WORLD", []string{"hello", "world"}},
+		{"default deletes non-word characters", DefaultOptions, "Don't stop-believing, café NAÏVE snake_case",
+			[]string{"dont", "stopbelieving", "caf", "nave", "snake_case"}},
+		{"default drops punctuation-only fields", DefaultOptions, "a -- b ...", []string{"a", "b"}},
+
+		{"Unicode splits on punctuation", unicodeOnly, "don't well-known", []string{"don", "t", "well", "known"}},
+		{"Unicode keeps letters in any script", unicodeOnly, "naïve ሰላም Ελλάδα", []string{"naïve", "ሰላም", "Ελλάδα"}},
+		{"apostrophes", Options{Unicode: true, KeepApostrophes: true}, "don't don’t 'quoted' rock'n'roll",
+			[]string{"don't", "don't", "quoted", "rock'n'roll"}},
+		{"hyphens", Options{Unicode: true, KeepHyphens: true}, "well-known self‐made a--b -x-",
+			[]string{"well-known", "self-made", "a", "b", "x"}},
+		{"without NFC", unicodeOnly, "cafe\u0301 café", []string{"cafe\u0301", "café"}},
+		{"NFC", Options{Unicode: true, NormalizeNFC: true}, "cafe\u0301 café", []string{"café", "café"}},
+		{"Unicode case folding", Options{Unicode: true, FoldCase: true}, "Straße STRASSE ΣΊΣΥΦΟΣ",
+			[]string{"strasse", "strasse", "σίσυφοσ"}},
+		{"ideographs", Options{Unicode: true, SplitIdeographs: true}, "日本語abc カタカナ", []string{"日", "本", "語", "abc", "カ", "タ", "カ", "ナ"}},
+		{"ideographs kept together", unicodeOnly, "日本語abc", []string{"日本語abc"}},
+
+		{"stopword language", Options{FoldCase: true, StopwordLanguages: []string{"EN"}}, "The cat and THE hat",
+			[]string{"cat", "hat"}},
+		{"several stopword languages", Options{FoldCase: true, StopwordLanguages: []string{"en", "fr"}}, "the chat et le cat",
+			[]string{"chat", "cat"}},
+		{"custom stopwords are tokenized too", Options{FoldCase: true, Stopwords: []string{"Cat!"}}, "cat CAT dog",
+			[]string{"dog"}},
+		{"Unicode stopwords", Options{Unicode: true, KeepApostrophes: true, FoldCase: true, Stopwords: []string{"don’t"}},
+			"Don't go", []string{"go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenizer, err := NewTokenizer(tt.opts)
+			if err != nil {
+				t.Fatalf("NewTokenizer: %v", err)
+			}
+			if got := tokenizer.Tokens(tt.input); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokens(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTokenizerUnknownLanguage(t *testing.T) {
+	if _, err := NewTokenizer(Options{StopwordLanguages: []string{"en", "xx"}}); err == nil || !strings.Contains(err.Error(), `"xx"`) {
+		t.Errorf("error = %v, want one naming \"xx\"", err)
+	}
+	for _, lang := range StopwordLanguages() {
+		if _, err := NewTokenizer(Options{StopwordLanguages: []string{lang}}); err != nil {
+			t.Errorf("listed language %q: %v", lang, err)
+		}
+	}
+}
+
+// WordFrequencyCount, the tokenizer and the streaming counter must agree on
+// what a word is.
+func TestWordFrequencyCountMatchesCounter(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]int
+	}{
+		{"empty", "", map[string]int{}},
+		{"sentence", "Hello, hello! How are you? Are you fine, hello?",
+			map[string]int{"hello": 3, "how": 1, "are": 2, "you": 2, "fine": 1}},
+		{"ASCII whitespace", "a\tb\nc\r\nd\ve\ff", map[string]int{"a": 1, "b": 1, "c": 1, "d": 1, "e": 1, "f": 1}},
+		{"no-break space", "one\u00a0two three", map[string]int{"one": 1, "two": 1, "three": 1}},
+		{"other Unicode spaces", "one\u2003two\u3000three\u0085four", map[string]int{"one": 1, "two": 1, "three": 1, "four": 1}},
+		{"non-ASCII letters", "Café NAÏVE über", map[string]int{"caf": 1, "nave": 1, "ber": 1}},
+		{"digits and underscores", "route_66 Route_66 2024", map[string]int{"route_66": 2, "2024": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WordFrequencyCount(tt.input); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WordFrequencyCount = %v, want %v", got, tt.want)
+			}
+
+			tokenizer, _ := NewTokenizer(DefaultOptions)
+			fromTokens := make(map[string]int)
+			for _, word := range tokenizer.Tokens(tt.input) {
+				fromTokens[word]++
+			}
+			if !reflect.DeepEqual(fromTokens, tt.want) {
+				t.Errorf("Tokens counted %v, want %v", fromTokens, tt.want)
+			}
+
+			counter := NewWordFrequencyCounter()
+			if err := counter.Count(strings.NewReader(tt.input)); err != nil {
+				t.Fatal(err)
+			}
+			if got := counter.Counts(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("counter counted %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package wordfreq
+
+// WordFrequencyCount counts the words of text with DefaultOptions: ASCII
+// letters, digits and underscores, lower-cased, with words separated by
+// Unicode whitespace.
+func WordFrequencyCount(text string) map[string]int {
+	frequency, _ := WordFrequencyCountWithOptions(text, DefaultOptions)
+	return frequency
+}
+
+// WordFrequencyCountWithOptions counts the words of text using a tokenizer
+// configured by opts.
+func WordFrequencyCountWithOptions(text string, opts Options) (map[string]int, error) {
+	tokenizer, err := NewTokenizer(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	frequency := make(map[string]int)
+	for _, word := range tokenizer.Tokens(text) {
+		frequency[word]++
+	}
+
+	return frequency, nil
+}
@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"library_management/models"
+	"library_management/services"
+	"library_management/storage"
+	"os"
+)
+
+// Exit codes returned by Run.
+const (
+	ExitOK       = 0
+	ExitError    = 1
+	ExitUsage    = 2
+	ExitNotFound = 3
+	ExitConflict = 4
+)
+
+const usage = `Usage: library [--data FILE] <command> [flags]
+
+Commands:
+  book add --id N --title T --author A [--isbn I] [--subject S]
+  book remove --id N
+  book show --id N
+  member add --id N --name NAME [--password P]
+  member list
+  borrow --book N --member N
+  return --book N --member N
+  list [--available] [--member N]
+  search [--q TEXT] [--isbn I] [--author A] [--subject S] [--available]
+         [--sort id|title|author] [--desc] [--offset N] [--limit N]
+  report [--since 30d | --from DATE] [--to DATE] [--top N]
+  import [--format json|csv] [--kind books|members] [--dry-run]
+         [--on-duplicate skip|upsert] FILE
+  export [--format json|csv] [--kind books|members] [--out FILE]
+  console    interactive menu (the default when no command is given)
+  serve      HTTP API on $PORT
+
+Read-only commands accept --json. The library is stored in FILE
+(default $LIBRARY_DATA or library.json) and saved after every change.
+`
+
+type app struct {
+	dataPath string
+	stdin    io.Reader
+	stdout   io.Writer
+	stderr   io.Writer
+}
+
+// Run executes the command line in args (without the program name) and
+// returns the process exit code.
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	a := &app{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	global := flag.NewFlagSet("library", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	global.Usage = func() { fmt.Fprint(stderr, usage) }
+	global.StringVar(&a.dataPath, "data", defaultDataPath(), "library data file")
+	if err := global.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+
+	rest := global.Args()
+	if len(rest) == 0 {
+		return a.console()
+	}
+
+	switch rest[0] {
+	case "book":
+		return a.book(rest[1:])
+	case "member":
+		return a.member(rest[1:])
+	case "borrow":
+		return a.loan(rest[1:], true)
+	case "return":
+		return a.loan(rest[1:], false)
+	case "list":
+		return a.list(rest[1:])
+	case "search":
+		return a.search(rest[1:])
+	case "report":
+		return a.report(rest[1:])
+	case "import":
+		return a.importFile(rest[1:])
+	case "export":
+		return a.exportFile(rest[1:])
+	case "console":
+		return a.console()
+	case "serve":
+		return a.serve()
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return ExitOK
+	}
+
+	return a.usageError("unknown command %q", rest[0])
+}
+
+func defaultDataPath() string {
+	if path := os.Getenv("LIBRARY_DATA"); path != "" {
+		return path
+	}
+	return "library.json"
+}
+
+func (a *app) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("library "+name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	return fs
+}
+
+func (a *app) parse(fs *flag.FlagSet, args []string) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK, false
+		}
+		return ExitUsage, false
+	}
+	if fs.NArg() > 0 {
+		return a.usageError("unexpected argument %q", fs.Arg(0)), false
+	}
+	return ExitOK, true
+}
+
+func (a *app) usageError(format string, args ...interface{}) int {
+	fmt.Fprintf(a.stderr, "library: "+format+"\n", args...)
+	fmt.Fprintln(a.stderr, "Run 'library help' for usage.")
+	return ExitUsage
+}
+
+func (a *app) fail(err error) int {
+	fmt.Fprintln(a.stderr, "library:", err)
+	switch {
+	case errors.Is(err, services.ErrBookNotFound), errors.Is(err, services.ErrMemberNotFound):
+		return ExitNotFound
+	case errors.Is(err, services.ErrBookBorrowed), errors.Is(err, services.ErrBookNotBorrowed),
+		errors.Is(err, services.ErrDuplicateID), errors.Is(err, services.ErrDuplicateISBN):
+		return ExitConflict
+	}
+	return ExitError
+}
+
+// update loads the library, applies fn and saves the result if fn succeeds.
+// Whatever fn prints is held back until the save succeeds, so a change that
+// could not be stored is never reported as done.
+func (a *app) update(fn func(library services.LibraryManager) error) int {
+	library, err := storage.Load(a.dataPath)
+	if err != nil {
+		return a.fail(err)
+	}
+
+	stdout := a.stdout
+	var out bytes.Buffer
+	a.stdout = &out
+	err = fn(library)
+	a.stdout = stdout
+	if err != nil {
+		return a.fail(err)
+	}
+
+	if err := storage.Save(a.dataPath, library); err != nil {
+		return a.fail(err)
+	}
+	if _, err := out.WriteTo(a.stdout); err != nil {
+		return a.fail(err)
+	}
+	return ExitOK
+}
+
+func (a *app) view(fn func(library services.LibraryManager) error) int {
+	library, err := storage.Load(a.dataPath)
+	if err != nil {
+		return a.fail(err)
+	}
+	if err := fn(library); err != nil {
+		return a.fail(err)
+	}
+	return ExitOK
+}
+
+func (a *app) writeJSON(v interface{}) error {
+	encoder := json.NewEncoder(a.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func (a *app) writeBooks(books []models.Book) {
+	for _, b := range books {
+		fmt.Fprintf(a.stdout, "ID: %d | Title: %s | Author: %s | ISBN: %s | Subject: %s | Status: %s\n",
+			b.ID, b.Title, b.Author, b.ISBN, b.Subject, b.Status)
+	}
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"library_management/models"
+)
+
+func run(t *testing.T, data string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := Run(append([]string{"--data", data}, args...), strings.NewReader(""), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestExitCodes(t *testing.T) {
+	data := filepath.Join(t.TempDir(), "library.json")
+	for _, args := range [][]string{
+		{"book", "add", "--id", "1", "--title", "Dune", "--author", "Frank Herbert", "--isbn", "978-0441172719"},
+		{"member", "add", "--id", "1", "--name", "Reader"},
+		{"borrow", "--book", "1", "--member", "1"},
+	} {
+		if code, _, stderr := run(t, data, args...); code != ExitOK {
+			t.Fatalf("%v: exit %d: %s", args, code, stderr)
+		}
+	}
+
+	tests := []struct {
+		name string
+		data string
+		args []string
+		want int
+	}{
+		{"help", data, []string{"help"}, ExitOK},
+		{"read-only command", data, []string{"book", "show", "--id", "1"}, ExitOK},
+		{"unknown command", data, []string{"lend"}, ExitUsage},
+		{"unknown subcommand", data, []string{"book", "lend"}, ExitUsage},
+		{"missing flags", data, []string{"book", "add", "--id", "2"}, ExitUsage},
+		{"bad flag", data, []string{"list", "--sideways"}, ExitUsage},
+		{"stray argument", data, []string{"list", "extra"}, ExitUsage},
+		{"unknown book", data, []string{"book", "show", "--id", "9"}, ExitNotFound},
+		{"unknown member", data, []string{"return", "--book", "1", "--member", "9"}, ExitNotFound},
+		{"duplicate ID", data, []string{"book", "add", "--id", "1", "--title", "Emma", "--author", "Jane Austen"}, ExitConflict},
+		{"duplicate ISBN", data, []string{"book", "add", "--id", "2", "--title", "Dune", "--author", "Frank Herbert", "--isbn", "9780441172719"}, ExitConflict},
+		{"already borrowed", data, []string{"borrow", "--book", "1", "--member", "1"}, ExitConflict},
+		{"unreadable data", t.TempDir(), []string{"list"}, ExitError},
+		{"unsaveable data", filepath.Join(t.TempDir(), "missing", "library.json"), []string{"member", "add", "--id", "1", "--name", "Reader"}, ExitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, stderr := run(t, tt.data, tt.args...)
+			if code != tt.want {
+				t.Errorf("exit code = %d, want %d (stderr %q)", code, tt.want, stderr)
+			}
+			if code != ExitOK && stderr == "" {
+				t.Error("failure reported nothing on stderr")
+			}
+		})
+	}
+}
+
+func TestJSONOutput(t *testing.T) {
+	data := filepath.Join(t.TempDir(), "library.json")
+
+	code, stdout, stderr := run(t, data, "book", "add", "--id", "1", "--title", "Dune", "--author", "Frank Herbert", "--subject", "Fiction", "--json")
+	if code != ExitOK {
+		t.Fatalf("book add: exit %d: %s", code, stderr)
+	}
+	var added models.Book
+	if err := json.Unmarshal([]byte(stdout), &added); err != nil {
+		t.Fatalf("book add printed %q: %v", stdout, err)
+	}
+	want := models.Book{ID: 1, Title: "Dune", Author: "Frank Herbert", Subject: "Fiction", Status: models.StatusAvailable}
+	if added != want {
+		t.Errorf("book add printed %+v, want %+v", added, want)
+	}
+
+	code, stdout, stderr = run(t, data, "search", "--q", "dune", "--json")
+	if code != ExitOK {
+		t.Fatalf("search: exit %d: %s", code, stderr)
+	}
+	var found struct {
+		Books []models.Book `json:"books"`
+		Total int           `json:"total"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &found); err != nil {
+		t.Fatalf("search printed %q: %v", stdout, err)
+	}
+	if found.Total != 1 || len(found.Books) != 1 || found.Books[0] != want {
+		t.Errorf("search printed %+v, want the added book", found)
+	}
+}
+
+// A change that cannot be saved must not print as if it succeeded.
+func TestNoOutputWhenSaveFails(t *testing.T) {
+	data := filepath.Join(t.TempDir(), "missing", "library.json")
+	for _, args := range [][]string{
+		{"book", "add", "--id", "1", "--title", "Dune", "--author", "Frank Herbert", "--json"},
+		{"book", "add", "--id", "1", "--title", "Dune", "--author", "Frank Herbert"},
+	} {
+		code, stdout, _ := run(t, data, args...)
+		if code != ExitError {
+			t.Errorf("%v: exit code = %d, want %d", args, code, ExitError)
+		}
+		if stdout != "" {
+			t.Errorf("%v: printed %q although the save failed", args, stdout)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package cli
+
+import (
+	"fmt"
+	"library_management/controllers"
+	"library_management/models"
+	"library_management/services"
+	"library_management/storage"
+)
+
+func (a *app) book(args []string) int {
+	if len(args) == 0 {
+		return a.usageError("book needs a subcommand: add, remove or show")
+	}
+
+	switch args[0] {
+	case "add":
+		fs := a.flagSet("book add")
+		id := fs.Int("id", 0, "book ID")
+		title := fs.String("title", "", "title")
+		author := fs.String("author", "", "author")
+		isbn := fs.String("isbn", "", "ISBN")
+		subject := fs.String("subject", "", "subject")
+		asJSON := fs.Bool("json", false, "print the added book as JSON")
+		if code, ok := a.parse(fs, args[1:]); !ok {
+			return code
+		}
+		if *id <= 0 || *title == "" || *author == "" {
+			return a.usageError("book add requires --id, --title and --author")
+		}
+
+		return a.update(func(library services.LibraryManager) error {
+			book := models.Book{ID: *id, Title: *title, Author: *author, ISBN: *isbn, Subject: *subject}
+			if err := library.AddBook(book); err != nil {
+				return err
+			}
+			if *asJSON {
+				added, err := library.GetBook(*id)
+				if err != nil {
+					return err
+				}
+				return a.writeJSON(added)
+			}
+			fmt.Fprintln(a.stdout, "Book added successfully.")
+			return nil
+		})
+
+	case "remove":
+		fs := a.flagSet("book remove")
+		id := fs.Int("id", 0, "book ID")
+		if code, ok := a.parse(fs, args[1:]); !ok {
+			return code
+		}
+		if *id <= 0 {
+			return a.usageError("book remove requires --id")
+		}
+
+		return a.update(func(library services.LibraryManager) error {
+			if err := library.RemoveBook(*id); err != nil {
+				return err
+			}
+			fmt.Fprintln(a.stdout, "Book removed successfully.")
+			return nil
+		})
+
+	case "show":
+		fs := a.flagSet("book show")
+		id := fs.Int("id", 0, "book ID")
+		asJSON := fs.Bool("json", false, "print JSON")
+		if code, ok := a.parse(fs, args[1:]); !ok {
+			return code
+		}
+		if *id <= 0 {
+			return a.usageError("book show requires --id")
+		}
+
+		return a.view(func(library services.LibraryManager) error {
+			book, err := library.GetBook(*id)
+			if err != nil {
+				return err
+			}
+			if *asJSON {
+				return a.writeJSON(book)
+			}
+			a.writeBooks([]models.Book{book})
+			return nil
+		})
+	}
+
+	return a.usageError("unknown book subcommand %q", args[0])
+}
+
+func (a *app) member(args []string) int {
+	if len(args) == 0 {
+		return a.usageError("member needs a subcommand: add or list")
+	}
+
+	switch args[0] {
+	case "add":
+		fs := a.flagSet("member add")
+		id := fs.Int("id", 0, "member ID")
+		name := fs.String("name", "", "member name")
+		password := fs.String("password", "", "password for API login")
+		if code, ok := a.parse(fs, args[1:]); !ok {
+			return code
+		}
+		if *id <= 0 || *name == "" {
+			return a.usageError("member add requires --id and --name")
+		}
+
+		return a.update(func(library services.LibraryManager) error {
+			member := models.Member{ID: *id, Name: *name, Password: *password}
+			if member.Password != "" {
+				if err := member.HashPassword(); err != nil {
+					return err
+				}
+			}
+			if err := library.AddMember(member); err != nil {
+				return err
+			}
+			fmt.Fprintln(a.stdout, "Member added successfully.")
+			return nil
+		})
+
+	case "list":
+		fs := a.flagSet("member list")
+		asJSON := fs.Bool("json", false, "print JSON")
+		if code, ok := a.parse(fs, args[1:]); !ok {
+			return code
+		}
+
+		return a.view(func(library services.LibraryManager) error {
+			members := library.ListMembers()
+			if *asJSON {
+				return a.writeJSON(members)
+			}
+			for _, m := range members {
+				fmt.Fprintf(a.stdout, "ID: %d | Name: %s | Borrowed: %d\n", m.ID, m.Name, len(m.BorrowedBooks))
+			}
+			return nil
+		})
+	}
+
+	return a.usageError("unknown member subcommand %q", args[0])
+}
+
+func (a *app) loan(args []string, borrow bool) int {
+	name := "return"
+	if borrow {
+		name = "borrow"
+	}
+
+	fs := a.flagSet(name)
+	bookID := fs.Int("book", 0, "book ID")
+	memberID := fs.Int("member", 0, "member ID")
+	if code, ok := a.parse(fs, args); !ok {
+		return code
+	}
+	if *bookID <= 0 || *memberID <= 0 {
+		return a.usageError("%s requires --book and --member", name)
+	}
+
+	return a.update(func(library services.LibraryManager) error {
+		if borrow {
+			if err := library.BorrowBook(*bookID, *memberID); err != nil {
+				return err
+			}
+			fmt.Fprintln(a.stdout, "Book borrowed successfully.")
+			return nil
+		}
+		if err := library.ReturnBook(*bookID, *memberID); err != nil {
+			return err
+		}
+		fmt.Fprintln(a.stdout, "Book returned successfully.")
+		return nil
+	})
+}
+
+func (a *app) list(args []string) int {
+	fs := a.flagSet("list")
+	available := fs.Bool("available", false, "only list available books")
+	memberID := fs.Int("member", 0, "list the books borrowed by this member")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if code, ok := a.parse(fs, args); !ok {
+		return code
+	}
+
+	return a.view(func(library services.LibraryManager) error {
+		var books []models.Book
+		switch {
+		case *memberID > 0:
+			if _, err := library.GetMember(*memberID); err != nil {
+				return err
+			}
+			books = library.ListBorrowedBooks(*memberID)
+		case *available:
+			books = library.Search(services.SearchQuery{Status: models.StatusAvailable}).Books
+		default:
+			books = library.ListBooks()
+		}
+
+		if *asJSON {
+			if books == nil {
+				books = []models.Book{}
+			}
+			return a.writeJSON(books)
+		}
+		a.writeBooks(books)
+		return nil
+	})
+}
+
+func (a *app) search(args []string) int {
+	fs := a.flagSet("search")
+	var query services.SearchQuery
+	fs.StringVar(&query.Text, "q", "", "words to match in title and author")
+	fs.StringVar(&query.ISBN, "isbn", "", "ISBN")
+	fs.StringVar(&query.Author, "author", "", "author")
+	fs.StringVar(&query.Subject, "subject", "", "subject")
+	fs.StringVar(&query.SortBy, "sort", services.SortByID, "sort by id, title or author")
+	fs.BoolVar(&query.Descending, "desc", false, "sort in descending order")
+	fs.IntVar(&query.Offset, "offset", 0, "number of results to skip")
+	fs.IntVar(&query.Limit, "limit", 0, "maximum number of results")
+	available := fs.Bool("available", false, "only match available books")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if code, ok := a.parse(fs, args); !ok {
+		return code
+	}
+
+	switch query.SortBy {
+	case services.SortByID, services.SortByTitle, services.SortByAuthor:
+	default:
+		return a.usageError("--sort must be id, title or author")
+	}
+	if query.Offset < 0 || query.Limit < 0 {
+		return a.usageError("--offset and --limit must not be negative")
+	}
+	if *available {
+		query.Status = models.StatusAvailable
+	}
+
+	return a.view(func(library services.LibraryManager) error {
+		result := library.Search(query)
+		if *asJSON {
+			if result.Books == nil {
+				result.Books = []models.Book{}
+			}
+			return a.writeJSON(struct {
+				Books []models.Book `json:"books"`
+				Total int           `json:"total"`
+			}{result.Books, result.Total})
+		}
+		a.writeBooks(result.Books)
+		fmt.Fprintf(a.stdout, "%d match(es)\n", result.Total)
+		return nil
+	})
+}
+
+func (a *app) console() int {
+	library, err := storage.Load(a.dataPath)
+	if err != nil {
+		return a.fail(err)
+	}
+	controllers.RunLibraryConsole(library, a.stdin, a.stdout)
+	if err := storage.Save(a.dataPath, library); err != nil {
+		return a.fail(err)
+	}
+	return ExitOK
+}
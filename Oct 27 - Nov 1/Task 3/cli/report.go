@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"fmt"
+	"library_management/reports"
+	"library_management/services"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (a *app) report(args []string) int {
+	fs := a.flagSet("report")
+	since := fs.String("since", "", "only count the last period, e.g. 30d or 12h")
+	from := fs.String("from", "", "start date (YYYY-MM-DD)")
+	to := fs.String("to", "", "end date, exclusive (YYYY-MM-DD)")
+	top := fs.Int("top", reports.DefaultTop, "entries per ranking (0 for all)")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if code, ok := a.parse(fs, args); !ok {
+		return code
+	}
+
+	var window reports.Window
+	var err error
+	if *since != "" {
+		if *from != "" {
+			return a.usageError("use either --since or --from, not both")
+		}
+		period, err := parsePeriod(*since)
+		if err != nil {
+			return a.usageError("invalid --since %q", *since)
+		}
+		window.From = time.Now().Add(-period)
+	}
+	if *from != "" {
+		if window.From, err = reports.ParseDate(*from); err != nil {
+			return a.usageError("invalid --from %q", *from)
+		}
+	}
+	if *to != "" {
+		if window.To, err = reports.ParseDate(*to); err != nil {
+			return a.usageError("invalid --to %q", *to)
+		}
+	}
+
+	return a.view(func(library services.LibraryManager) error {
+		report := reports.Build(library, window, *top)
+		if *asJSON {
+			return report.WriteJSON(a.stdout)
+		}
+		return report.WriteTable(a.stdout)
+	})
+}
+
+// parsePeriod accepts time.ParseDuration strings plus a whole number of days
+// such as "30d".
+func parsePeriod(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid period %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"fmt"
+	"library_management/controllers"
+	"library_management/router"
+	"library_management/storage"
+	"log"
+	"os"
+)
+
+// serve runs the HTTP API on the same data file as the other commands,
+// saving it after every change made through the API.
+func (a *app) serve() int {
+	if os.Getenv("JWT_SECRET") == "" {
+		os.Setenv("JWT_SECRET", "your-secret-key") // Change this in production
+	}
+
+	loaded, err := storage.Load(a.dataPath)
+	if err != nil {
+		return a.fail(err)
+	}
+	library := storage.NewPersistent(a.dataPath, loaded)
+
+	authController := controllers.NewAuthController(
+		library,
+		getEnv("LIBRARIAN_USERNAME", "librarian"),
+		getEnv("LIBRARIAN_PASSWORD", "librarian123"),
+	)
+	libraryController := controllers.NewLibraryController(library)
+
+	r := router.SetupRouter(authController, libraryController)
+
+	port := getEnv("PORT", "8080")
+	log.Printf("Server running on port %s\n", port)
+	if err := r.Run(":" + port); err != nil {
+		fmt.Fprintln(a.stderr, "library: failed to start server:", err)
+		return ExitError
+	}
+	return ExitOK
+}
+
+func getEnv(key, defaultValue string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"library_management/services"
+	"library_management/storage"
+	"library_management/transfer"
+	"os"
+	"strings"
+)
+
+func (a *app) importFile(args []string) int {
+	fs := a.flagSet("import")
+	format := fs.String("format", "", "json or csv (default from the file extension)")
+	kind := fs.String("kind", "books", "what a CSV file holds: books or members")
+	dryRun := fs.Bool("dry-run", false, "validate and report without changing the library")
+	onDuplicate := fs.String("on-duplicate", string(transfer.SkipDuplicates), "skip or upsert rows whose ID exists")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if fs.NArg() != 1 {
+		return a.usageError("import takes exactly one FILE (or - for stdin)")
+	}
+	path := fs.Arg(0)
+
+	opts := transfer.Options{DryRun: *dryRun, OnDuplicate: transfer.DuplicatePolicy(*onDuplicate)}
+	if opts.OnDuplicate != transfer.SkipDuplicates && opts.OnDuplicate != transfer.UpsertDuplicates {
+		return a.usageError("--on-duplicate must be skip or upsert")
+	}
+
+	var importer func(io.Reader, services.LibraryManager, transfer.Options) (transfer.Report, error)
+	switch formatFor(*format, path) {
+	case "json":
+		importer = transfer.ImportJSON
+	case "csv":
+		switch *kind {
+		case "books":
+			importer = transfer.ImportBooksCSV
+		case "members":
+			importer = transfer.ImportMembersCSV
+		default:
+			return a.usageError("--kind must be books or members")
+		}
+	default:
+		return a.usageError("--format must be json or csv")
+	}
+
+	in := a.stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return a.fail(err)
+		}
+		defer file.Close()
+		in = file
+	}
+
+	library, err := storage.Load(a.dataPath)
+	if err != nil {
+		return a.fail(err)
+	}
+	report, err := importer(in, library, opts)
+	if err != nil {
+		return a.fail(err)
+	}
+	if !opts.DryRun {
+		if err := storage.Save(a.dataPath, library); err != nil {
+			return a.fail(err)
+		}
+	}
+
+	if *asJSON {
+		type rowError struct {
+			Kind  string `json:"kind"`
+			Row   int    `json:"row"`
+			ID    int    `json:"id,omitempty"`
+			Error string `json:"error"`
+		}
+		out := struct {
+			DryRun  bool       `json:"dry_run"`
+			Created int        `json:"created"`
+			Updated int        `json:"updated"`
+			Skipped int        `json:"skipped"`
+			Errors  []rowError `json:"errors"`
+		}{report.DryRun, report.Created, report.Updated, report.Skipped, []rowError{}}
+		for _, e := range report.Errors {
+			out.Errors = append(out.Errors, rowError{e.Kind, e.Row, e.ID, e.Err.Error()})
+		}
+		if err := a.writeJSON(out); err != nil {
+			return a.fail(err)
+		}
+	} else {
+		if report.DryRun {
+			fmt.Fprintln(a.stdout, "Dry run: no changes were saved.")
+		}
+		fmt.Fprintf(a.stdout, "Created: %d | Updated: %d | Skipped: %d | Failed: %d\n",
+			report.Created, report.Updated, report.Skipped, len(report.Errors))
+		for _, e := range report.Errors {
+			fmt.Fprintln(a.stdout, e.Error())
+		}
+	}
+
+	if len(report.Errors) > 0 {
+		return ExitError
+	}
+	return ExitOK
+}
+
+func (a *app) exportFile(args []string) int {
+	fs := a.flagSet("export")
+	format := fs.String("format", "", "json or csv (default from --out, else json)")
+	kind := fs.String("kind", "books", "what to write as CSV: books or members")
+	outPath := fs.String("out", "", "file to write (default stdout)")
+	if code, ok := a.parse(fs, args); !ok {
+		return code
+	}
+
+	var exporter func(io.Writer, services.LibraryManager) error
+	switch formatFor(*format, *outPath) {
+	case "json":
+		exporter = transfer.ExportJSON
+	case "csv":
+		switch *kind {
+		case "books":
+			exporter = transfer.ExportBooksCSV
+		case "members":
+			exporter = transfer.ExportMembersCSV
+		default:
+			return a.usageError("--kind must be books or members")
+		}
+	default:
+		return a.usageError("--format must be json or csv")
+	}
+
+	library, err := storage.Load(a.dataPath)
+	if err != nil {
+		return a.fail(err)
+	}
+
+	out := a.stdout
+	if *outPath != "" {
+		file, err := os.Create(*outPath)
+		if err != nil {
+			return a.fail(err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if err := exporter(out, library); err != nil {
+		return a.fail(err)
+	}
+	return ExitOK
+}
+
+func formatFor(format, path string) string {
+	if format != "" {
+		return strings.ToLower(format)
+	}
+	if strings.HasSuffix(strings.ToLower(path), ".csv") {
+		return "csv"
+	}
+	return "json"
+}
@@ -0,0 +1,363 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"library_management/middleware"
+	"library_management/models"
+	"library_management/reports"
+	"library_management/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuthController struct {
+	library           services.LibraryManager
+	librarianUsername string
+	librarianPassword string
+}
+
+func NewAuthController(library services.LibraryManager, librarianUsername, librarianPassword string) *AuthController {
+	return &AuthController{
+		library:           library,
+		librarianUsername: librarianUsername,
+		librarianPassword: librarianPassword,
+	}
+}
+
+type LibraryController struct {
+	library services.LibraryManager
+}
+
+func NewLibraryController(library services.LibraryManager) *LibraryController {
+	return &LibraryController{library: library}
+}
+
+// Auth Handlers
+type LoginRequest struct {
+	Username string `json:"username"`
+	MemberID int    `json:"member_id"`
+	Password string `json:"password" binding:"required"`
+}
+
+func (ac *AuthController) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var memberID int
+	role := models.MemberRole
+	if req.Username != "" {
+		if !ac.isLibrarian(req.Username, req.Password) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+			return
+		}
+		role = models.LibrarianRole
+	} else {
+		member, err := ac.library.GetMember(req.MemberID)
+		if err != nil || !member.CheckPassword(req.Password) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+			return
+		}
+		memberID = member.ID
+	}
+
+	token, err := middleware.GenerateToken(memberID, role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":     token,
+		"role":      role,
+		"member_id": memberID,
+	})
+}
+
+func (ac *AuthController) isLibrarian(username, password string) bool {
+	usernameMatch := subtle.ConstantTimeCompare([]byte(username), []byte(ac.librarianUsername))
+	passwordMatch := subtle.ConstantTimeCompare([]byte(password), []byte(ac.librarianPassword))
+	return usernameMatch&passwordMatch == 1
+}
+
+// Book Handlers
+type CreateBookRequest struct {
+	ID      int    `json:"id" binding:"required"`
+	Title   string `json:"title" binding:"required"`
+	Author  string `json:"author" binding:"required"`
+	ISBN    string `json:"isbn"`
+	Subject string `json:"subject"`
+}
+
+func (lc *LibraryController) SearchBooks(c *gin.Context) {
+	query := services.SearchQuery{
+		Text:       c.Query("q"),
+		ISBN:       c.Query("isbn"),
+		Author:     c.Query("author"),
+		Subject:    c.Query("subject"),
+		Status:     c.Query("status"),
+		SortBy:     c.DefaultQuery("sort", services.SortByID),
+		Descending: c.Query("order") == "desc",
+	}
+	if c.Query("available") == "true" {
+		query.Status = models.StatusAvailable
+	}
+
+	switch query.SortBy {
+	case services.SortByID, services.SortByTitle, services.SortByAuthor:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of id, title, author"})
+		return
+	}
+
+	var err error
+	if query.Offset, err = queryInt(c, "offset"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+		return
+	}
+	if query.Limit, err = queryInt(c, "limit"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	result := lc.library.Search(query)
+	books := result.Books
+	if books == nil {
+		books = []models.Book{}
+	}
+	c.JSON(http.StatusOK, gin.H{"books": books, "total": result.Total})
+}
+
+func (lc *LibraryController) ListAvailableBooks(c *gin.Context) {
+	books := lc.library.Search(services.SearchQuery{Status: models.StatusAvailable}).Books
+	if books == nil {
+		books = []models.Book{}
+	}
+	c.JSON(http.StatusOK, books)
+}
+
+func (lc *LibraryController) GetBook(c *gin.Context) {
+	bookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid book ID"})
+		return
+	}
+
+	book, err := lc.library.GetBook(bookID)
+	if err != nil {
+		respondLibraryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, book)
+}
+
+func (lc *LibraryController) CreateBook(c *gin.Context) {
+	var req CreateBookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	book := models.Book{ID: req.ID, Title: req.Title, Author: req.Author, ISBN: req.ISBN, Subject: req.Subject}
+	if err := lc.library.AddBook(book); err != nil {
+		respondLibraryError(c, err)
+		return
+	}
+
+	created, err := lc.library.GetBook(book.ID)
+	if err != nil {
+		respondLibraryError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+func (lc *LibraryController) DeleteBook(c *gin.Context) {
+	bookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid book ID"})
+		return
+	}
+
+	if err := lc.library.RemoveBook(bookID); err != nil {
+		respondLibraryError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Member Handlers
+type CreateMemberRequest struct {
+	ID       int    `json:"id" binding:"required"`
+	Name     string `json:"name" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+func (lc *LibraryController) ListMembers(c *gin.Context) {
+	c.JSON(http.StatusOK, lc.library.ListMembers())
+}
+
+func (lc *LibraryController) CreateMember(c *gin.Context) {
+	var req CreateMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member := models.Member{ID: req.ID, Name: req.Name, Password: req.Password}
+	if err := member.HashPassword(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
+		return
+	}
+	if err := lc.library.AddMember(member); err != nil {
+		respondLibraryError(c, err)
+		return
+	}
+
+	member.BorrowedBooks = []models.Book{}
+	c.JSON(http.StatusCreated, member)
+}
+
+func (lc *LibraryController) GetMember(c *gin.Context) {
+	memberID, ok := authorizedMemberID(c)
+	if !ok {
+		return
+	}
+
+	member, err := lc.library.GetMember(memberID)
+	if err != nil {
+		respondLibraryError(c, err)
+		return
+	}
+	if member.BorrowedBooks == nil {
+		member.BorrowedBooks = []models.Book{}
+	}
+	c.JSON(http.StatusOK, member)
+}
+
+func (lc *LibraryController) ListLoans(c *gin.Context) {
+	memberID, ok := authorizedMemberID(c)
+	if !ok {
+		return
+	}
+
+	if _, err := lc.library.GetMember(memberID); err != nil {
+		respondLibraryError(c, err)
+		return
+	}
+
+	books := lc.library.ListBorrowedBooks(memberID)
+	if books == nil {
+		books = []models.Book{}
+	}
+	c.JSON(http.StatusOK, books)
+}
+
+// Loan Handlers
+type LoanRequest struct {
+	BookID   int `json:"book_id" binding:"required"`
+	MemberID int `json:"member_id" binding:"required"`
+}
+
+func (lc *LibraryController) BorrowBook(c *gin.Context) {
+	var req LoanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := lc.library.BorrowBook(req.BookID, req.MemberID); err != nil {
+		respondLibraryError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"book_id": req.BookID, "member_id": req.MemberID})
+}
+
+func (lc *LibraryController) ReturnBook(c *gin.Context) {
+	var req LoanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := lc.library.ReturnBook(req.BookID, req.MemberID); err != nil {
+		respondLibraryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "book returned"})
+}
+
+// Report Handlers
+func (lc *LibraryController) GetReport(c *gin.Context) {
+	var window reports.Window
+	var err error
+	if from := c.Query("from"); from != "" {
+		if window.From, err = reports.ParseDate(from); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a YYYY-MM-DD date"})
+			return
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		if window.To, err = reports.ParseDate(to); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a YYYY-MM-DD date"})
+			return
+		}
+	}
+
+	top := reports.DefaultTop
+	if c.Query("top") != "" {
+		if top, err = queryInt(c, "top"); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid top"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, reports.Build(lc.library, window, top))
+}
+
+// authorizedMemberID parses the :id path parameter and rejects members
+// asking for anyone but themselves. Librarians may view every member.
+func authorizedMemberID(c *gin.Context) (int, bool) {
+	memberID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid member ID"})
+		return 0, false
+	}
+
+	role, _ := c.Get("userRole")
+	callerID, _ := c.Get("memberID")
+	if role != models.LibrarianRole && callerID != memberID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not authorized to view this member"})
+		return 0, false
+	}
+	return memberID, true
+}
+
+func queryInt(c *gin.Context, key string) (int, error) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, errors.New("invalid " + key)
+	}
+	return n, nil
+}
+
+func respondLibraryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrBookNotFound), errors.Is(err, services.ErrMemberNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrBookBorrowed), errors.Is(err, services.ErrBookNotBorrowed),
+		errors.Is(err, services.ErrDuplicateID), errors.Is(err, services.ErrDuplicateISBN):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	}
+}
@@ -1,107 +1,203 @@
 package controllers
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"library_management/models"
 	"library_management/services"
+	"strconv"
+	"strings"
 )
 
-func RunLibraryConsole() {
-	library := services.NewLibrary()
+// RunLibraryConsole runs the interactive menu against library. Input is read
+// a line at a time, so titles and names may contain spaces. The loop ends on
+// the exit choice or when in is exhausted.
+func RunLibraryConsole(library services.LibraryManager, in io.Reader, out io.Writer) {
+	console := &console{scanner: bufio.NewScanner(in), out: out}
 
-	library.Members[1] = models.Member{ID: 1, Name: "first"}
-	library.Members[2] = models.Member{ID: 2, Name: "second"}
+	if len(library.ListMembers()) == 0 {
+		library.AddMember(models.Member{ID: 1, Name: "first"})
+		library.AddMember(models.Member{ID: 2, Name: "second"})
+	}
 
 	for {
-		fmt.Println("\n===== Library Management System =====")
-		fmt.Println("1. Add Book")
-		fmt.Println("2. Remove Book")
-		fmt.Println("3. Borrow Book")
-		fmt.Println("4. Return Book")
-		fmt.Println("5. List Available Books")
-		fmt.Println("6. List Borrowed Books by Member")
-		fmt.Println("7. Exit")
-		fmt.Print("Enter choice: ")
-
-		var choice int
-		fmt.Scan(&choice)
+		fmt.Fprintln(out, "\n===== Library Management System =====")
+		fmt.Fprintln(out, "1. Add Book")
+		fmt.Fprintln(out, "2. Remove Book")
+		fmt.Fprintln(out, "3. Borrow Book")
+		fmt.Fprintln(out, "4. Return Book")
+		fmt.Fprintln(out, "5. List Available Books")
+		fmt.Fprintln(out, "6. List Borrowed Books by Member")
+		fmt.Fprintln(out, "7. Search Books")
+		fmt.Fprintln(out, "8. Exit")
+
+		choice, ok := console.readInt("Enter choice: ")
+		if console.done {
+			fmt.Fprintln(out, "\nExiting... Goodbye!")
+			return
+		}
+		if !ok {
+			fmt.Fprintln(out, "Invalid choice. Please try again.")
+			continue
+		}
 
 		switch choice {
 		case 1:
-			var id int
-			var title, author string
-			fmt.Print("Enter Book ID: ")
-			fmt.Scan(&id)
-			fmt.Print("Enter Title: ")
-			fmt.Scan(&title)
-			fmt.Print("Enter Author: ")
-			fmt.Scan(&author)
-
-			library.AddBook(models.Book{ID: id, Title: title, Author: author})
-			fmt.Println("Book added successfully.")
+			id, ok := console.readInt("Enter Book ID: ")
+			if !ok {
+				fmt.Fprintln(out, "Error: invalid book ID")
+				continue
+			}
+			title := console.readLine("Enter Title: ")
+			author := console.readLine("Enter Author: ")
+			isbn := console.readLine("Enter ISBN: ")
+			subject := console.readLine("Enter Subject: ")
+
+			err := library.AddBook(models.Book{ID: id, Title: title, Author: author, ISBN: isbn, Subject: subject})
+			if err != nil {
+				fmt.Fprintln(out, "Error:", err)
+			} else {
+				fmt.Fprintln(out, "Book added successfully.")
+			}
 
 		case 2:
-			var id int
-			fmt.Print("Enter Book ID to remove: ")
-			fmt.Scan(&id)
-			library.RemoveBook(id)
-			fmt.Println("Book removed successfully.")
+			id, ok := console.readInt("Enter Book ID to remove: ")
+			if !ok {
+				fmt.Fprintln(out, "Error: invalid book ID")
+				continue
+			}
+			err := library.RemoveBook(id)
+			if err != nil {
+				fmt.Fprintln(out, "Error:", err)
+			} else {
+				fmt.Fprintln(out, "Book removed successfully.")
+			}
 
 		case 3:
-			var bookID, memberID int
-			fmt.Print("Enter Book ID: ")
-			fmt.Scan(&bookID)
-			fmt.Print("Enter Member ID: ")
-			fmt.Scan(&memberID)
+			bookID, memberID, ok := console.readLoan()
+			if !ok {
+				continue
+			}
 
 			err := library.BorrowBook(bookID, memberID)
 			if err != nil {
-				fmt.Println("Error:", err)
+				fmt.Fprintln(out, "Error:", err)
 			} else {
-				fmt.Println("Book borrowed successfully.")
+				fmt.Fprintln(out, "Book borrowed successfully.")
 			}
 
 		case 4:
-			var bookID, memberID int
-			fmt.Print("Enter Book ID: ")
-			fmt.Scan(&bookID)
-			fmt.Print("Enter Member ID: ")
-			fmt.Scan(&memberID)
+			bookID, memberID, ok := console.readLoan()
+			if !ok {
+				continue
+			}
 
 			err := library.ReturnBook(bookID, memberID)
 			if err != nil {
-				fmt.Println("Error:", err)
+				fmt.Fprintln(out, "Error:", err)
 			} else {
-				fmt.Println("Book returned successfully.")
+				fmt.Fprintln(out, "Book returned successfully.")
 			}
 
 		case 5:
-			fmt.Println("Available Books:")
+			fmt.Fprintln(out, "Available Books:")
 			for _, b := range library.ListAvailableBooks() {
-				fmt.Printf("ID: %d | Title: %s | Author: %s\n", b.ID, b.Title, b.Author)
+				fmt.Fprintf(out, "ID: %d | Title: %s | Author: %s\n", b.ID, b.Title, b.Author)
 			}
 
 		case 6:
-			var memberID int
-			fmt.Print("Enter Member ID: ")
-			fmt.Scan(&memberID)
+			memberID, ok := console.readInt("Enter Member ID: ")
+			if !ok {
+				fmt.Fprintln(out, "Error: invalid member ID")
+				continue
+			}
 
 			books := library.ListBorrowedBooks(memberID)
 			if len(books) == 0 {
-				fmt.Println("No borrowed books.")
+				fmt.Fprintln(out, "No borrowed books.")
 			} else {
-				fmt.Println("Borrowed Books:")
+				fmt.Fprintln(out, "Borrowed Books:")
 				for _, b := range books {
-					fmt.Printf("ID: %d | Title: %s | Author: %s\n", b.ID, b.Title, b.Author)
+					fmt.Fprintf(out, "ID: %d | Title: %s | Author: %s\n", b.ID, b.Title, b.Author)
 				}
 			}
 
 		case 7:
-			fmt.Println("Exiting... Goodbye!")
+			fmt.Fprintln(out, "Search by: 1. Title/Author  2. ISBN  3. Author  4. Subject")
+			field, _ := console.readInt("Enter choice: ")
+			term := console.readLine("Enter search term: ")
+			onlyAvailable := console.readLine("Available books only? (y/n): ")
+
+			query := services.SearchQuery{SortBy: services.SortByTitle}
+			switch field {
+			case 1:
+				query.Text = term
+			case 2:
+				query.ISBN = term
+			case 3:
+				query.Author = term
+			case 4:
+				query.Subject = term
+			default:
+				fmt.Fprintln(out, "Invalid search field.")
+				continue
+			}
+			if strings.EqualFold(onlyAvailable, "y") {
+				query.Status = models.StatusAvailable
+			}
+
+			result := library.Search(query)
+			if result.Total == 0 {
+				fmt.Fprintln(out, "No matching books.")
+			} else {
+				fmt.Fprintf(out, "Found %d book(s):\n", result.Total)
+				for _, b := range result.Books {
+					fmt.Fprintf(out, "ID: %d | Title: %s | Author: %s | ISBN: %s | Subject: %s | Status: %s\n",
+						b.ID, b.Title, b.Author, b.ISBN, b.Subject, b.Status)
+				}
+			}
+
+		case 8:
+			fmt.Fprintln(out, "Exiting... Goodbye!")
 			return
 
 		default:
-			fmt.Println("Invalid choice. Please try again.")
+			fmt.Fprintln(out, "Invalid choice. Please try again.")
 		}
 	}
 }
+
+type console struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+	done    bool
+}
+
+func (c *console) readLine(prompt string) string {
+	fmt.Fprint(c.out, prompt)
+	if !c.scanner.Scan() {
+		c.done = true
+		return ""
+	}
+	return strings.TrimSpace(c.scanner.Text())
+}
+
+func (c *console) readInt(prompt string) (int, bool) {
+	n, err := strconv.Atoi(c.readLine(prompt))
+	return n, err == nil
+}
+
+func (c *console) readLoan() (int, int, bool) {
+	bookID, ok := c.readInt("Enter Book ID: ")
+	if !ok {
+		fmt.Fprintln(c.out, "Error: invalid book ID")
+		return 0, 0, false
+	}
+	memberID, ok := c.readInt("Enter Member ID: ")
+	if !ok {
+		fmt.Fprintln(c.out, "Error: invalid member ID")
+		return 0, 0, false
+	}
+	return bookID, memberID, true
+}
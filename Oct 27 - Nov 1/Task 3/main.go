@@ -1,7 +1,10 @@
 package main
 
-import "library_management/controllers"
+import (
+	"library_management/cli"
+	"os"
+)
 
 func main() {
-	controllers.RunLibraryConsole()
+	os.Exit(cli.Run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
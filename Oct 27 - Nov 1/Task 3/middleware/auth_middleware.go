@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"library_management/models"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwtKey is read on every call rather than at package init, so a default
+// set by main before the server starts is honoured.
+func jwtKey() []byte {
+	return []byte(os.Getenv("JWT_SECRET"))
+}
+
+type Claims struct {
+	MemberID int         `json:"member_id,omitempty"`
+	Role     models.Role `json:"role"`
+	jwt.RegisteredClaims
+}
+
+func GenerateToken(memberID int, role models.Role) (string, error) {
+	expirationTime := time.Now().Add(24 * time.Hour)
+	claims := &Claims{
+		MemberID: memberID,
+		Role:     role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtKey())
+}
+
+func AuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+			c.Abort()
+			return
+		}
+
+		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+		claims := &Claims{}
+
+		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+			return jwtKey(), nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+
+		if err != nil || !token.Valid {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			c.Abort()
+			return
+		}
+
+		c.Set("memberID", claims.MemberID)
+		c.Set("userRole", claims.Role)
+		c.Next()
+	}
+}
+
+func LibrarianOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, exists := c.Get("userRole")
+		if !exists || role != models.LibrarianRole {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Librarian access required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,8 +1,15 @@
 package models
 
+const (
+	StatusAvailable = "Available"
+	StatusBorrowed  = "Borrowed"
+)
+
 type Book struct {
-	ID     int
-	Title  string
-	Author string
-	Status string
+	ID      int    `json:"id"`
+	Title   string `json:"title"`
+	Author  string `json:"author"`
+	ISBN    string `json:"isbn"`
+	Subject string `json:"subject"`
+	Status  string `json:"status"`
 }
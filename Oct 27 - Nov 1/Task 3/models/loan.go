@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+const (
+	LoanBorrowed = "borrow"
+	LoanReturned = "return"
+)
+
+// LoanEvent is one entry in the library's circulation history. Fine is set
+// on return events for books brought back after their due date.
+type LoanEvent struct {
+	Type     string    `json:"type"`
+	BookID   int       `json:"book_id"`
+	MemberID int       `json:"member_id"`
+	At       time.Time `json:"at"`
+	Fine     float64   `json:"fine,omitempty"`
+}
@@ -1,7 +1,34 @@
 package models
 
+import "golang.org/x/crypto/bcrypt"
+
+type Role string
+
+const (
+	LibrarianRole Role = "librarian"
+	MemberRole    Role = "member"
+)
+
 type Member struct {
-	ID            int
-	Name          string
-	BorrowedBooks []Book
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	Password      string `json:"-"`
+	BorrowedBooks []Book `json:"borrowed_books"`
+}
+
+func (m *Member) HashPassword() error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(m.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	m.Password = string(hashedPassword)
+	return nil
+}
+
+func (m *Member) CheckPassword(password string) bool {
+	if m.Password == "" {
+		return false
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(m.Password), []byte(password))
+	return err == nil
 }
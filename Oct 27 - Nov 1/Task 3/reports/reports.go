@@ -0,0 +1,260 @@
+package reports
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"library_management/models"
+	"library_management/services"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// DefaultTop is how many entries each ranking keeps when the caller does not
+// say, on the command line and in the API alike.
+const DefaultTop = 10
+
+// Window limits a report to loans that started, and returns that happened,
+// in [From, To). A zero bound leaves that side open.
+type Window struct {
+	From time.Time
+	To   time.Time
+}
+
+// ParseDate parses a YYYY-MM-DD window bound as midnight local time, so the
+// command line and the API agree on where a day starts.
+func ParseDate(s string) (time.Time, error) {
+	return time.ParseInLocation(time.DateOnly, s, time.Local)
+}
+
+func (w Window) contains(t time.Time) bool {
+	return (w.From.IsZero() || !t.Before(w.From)) && (w.To.IsZero() || t.Before(w.To))
+}
+
+type TitleCount struct {
+	BookID int    `json:"book_id"`
+	Title  string `json:"title"`
+	Author string `json:"author"`
+	Loans  int    `json:"loans"`
+}
+
+type AuthorCount struct {
+	Author string `json:"author"`
+	Loans  int    `json:"loans"`
+}
+
+type MemberActivity struct {
+	MemberID int     `json:"member_id"`
+	Name     string  `json:"name"`
+	Loans    int     `json:"loans"`
+	Fines    float64 `json:"fines"`
+}
+
+type Utilization struct {
+	Total     int     `json:"total"`
+	Borrowed  int     `json:"borrowed"`
+	Available int     `json:"available"`
+	Rate      float64 `json:"rate"`
+}
+
+type Report struct {
+	From               *time.Time       `json:"from,omitempty"`
+	To                 *time.Time       `json:"to,omitempty"`
+	PopularTitles      []TitleCount     `json:"popular_titles"`
+	PopularAuthors     []AuthorCount    `json:"popular_authors"`
+	ActiveMembers      []MemberActivity `json:"active_members"`
+	CompletedLoans     int              `json:"completed_loans"`
+	AverageLoanDays    float64          `json:"average_loan_days"`
+	TotalFines         float64          `json:"total_fines"`
+	CurrentUtilization Utilization      `json:"current_utilization"`
+}
+
+// Build summarises the library's circulation history over window. Each
+// ranking keeps at most top entries; top <= 0 keeps them all. Utilization
+// always describes the library as it is now.
+func Build(library services.LibraryManager, window Window, top int) Report {
+	books := make(map[int]models.Book)
+	report := Report{}
+	for _, b := range library.ListBooks() {
+		books[b.ID] = b
+		report.CurrentUtilization.Total++
+		if b.Status == models.StatusBorrowed {
+			report.CurrentUtilization.Borrowed++
+		}
+	}
+	u := &report.CurrentUtilization
+	u.Available = u.Total - u.Borrowed
+	if u.Total > 0 {
+		u.Rate = float64(u.Borrowed) / float64(u.Total)
+	}
+
+	members := make(map[int]*MemberActivity)
+	for _, m := range library.ListMembers() {
+		members[m.ID] = &MemberActivity{MemberID: m.ID, Name: m.Name}
+	}
+	activity := func(id int) *MemberActivity {
+		if members[id] == nil {
+			members[id] = &MemberActivity{MemberID: id, Name: fmt.Sprintf("#%d (removed)", id)}
+		}
+		return members[id]
+	}
+
+	titles := make(map[int]int)
+	authors := make(map[string]int)
+	openLoans := make(map[int]time.Time)
+	var totalDuration time.Duration
+
+	for _, e := range library.History() {
+		switch e.Type {
+		case models.LoanBorrowed:
+			openLoans[e.BookID] = e.At
+			if !window.contains(e.At) {
+				continue
+			}
+			titles[e.BookID]++
+			if b, ok := books[e.BookID]; ok {
+				authors[b.Author]++
+			}
+			activity(e.MemberID).Loans++
+
+		case models.LoanReturned:
+			borrowedAt, open := openLoans[e.BookID]
+			delete(openLoans, e.BookID)
+			if !window.contains(e.At) {
+				continue
+			}
+			activity(e.MemberID).Fines += e.Fine
+			report.TotalFines += e.Fine
+			if open {
+				report.CompletedLoans++
+				totalDuration += e.At.Sub(borrowedAt)
+			}
+		}
+	}
+	if report.CompletedLoans > 0 {
+		report.AverageLoanDays = totalDuration.Hours() / 24 / float64(report.CompletedLoans)
+	}
+
+	for id, loans := range titles {
+		tc := TitleCount{BookID: id, Loans: loans, Title: fmt.Sprintf("#%d (removed)", id)}
+		if b, ok := books[id]; ok {
+			tc.Title, tc.Author = b.Title, b.Author
+		}
+		report.PopularTitles = append(report.PopularTitles, tc)
+	}
+	sort.Slice(report.PopularTitles, func(i, j int) bool {
+		a, b := report.PopularTitles[i], report.PopularTitles[j]
+		if a.Loans != b.Loans {
+			return a.Loans > b.Loans
+		}
+		return a.BookID < b.BookID
+	})
+
+	for author, loans := range authors {
+		report.PopularAuthors = append(report.PopularAuthors, AuthorCount{Author: author, Loans: loans})
+	}
+	sort.Slice(report.PopularAuthors, func(i, j int) bool {
+		a, b := report.PopularAuthors[i], report.PopularAuthors[j]
+		if a.Loans != b.Loans {
+			return a.Loans > b.Loans
+		}
+		return a.Author < b.Author
+	})
+
+	for _, m := range members {
+		if m.Loans > 0 || m.Fines > 0 {
+			report.ActiveMembers = append(report.ActiveMembers, *m)
+		}
+	}
+	sort.Slice(report.ActiveMembers, func(i, j int) bool {
+		a, b := report.ActiveMembers[i], report.ActiveMembers[j]
+		if a.Loans != b.Loans {
+			return a.Loans > b.Loans
+		}
+		if a.Fines != b.Fines {
+			return a.Fines > b.Fines
+		}
+		return a.MemberID < b.MemberID
+	})
+
+	if top > 0 {
+		report.PopularTitles = truncate(report.PopularTitles, top)
+		report.PopularAuthors = truncate(report.PopularAuthors, top)
+		report.ActiveMembers = truncate(report.ActiveMembers, top)
+	}
+	if report.PopularTitles == nil {
+		report.PopularTitles = []TitleCount{}
+	}
+	if report.PopularAuthors == nil {
+		report.PopularAuthors = []AuthorCount{}
+	}
+	if report.ActiveMembers == nil {
+		report.ActiveMembers = []MemberActivity{}
+	}
+	if !window.From.IsZero() {
+		report.From = &window.From
+	}
+	if !window.To.IsZero() {
+		report.To = &window.To
+	}
+
+	return report
+}
+
+func truncate[T any](items []T, n int) []T {
+	if len(items) > n {
+		return items[:n]
+	}
+	return items
+}
+
+func (r Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteTable prints the report as aligned plain-text tables.
+func (r Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	period := "all time"
+	switch {
+	case r.From != nil && r.To != nil:
+		period = fmt.Sprintf("%s to %s", r.From.Format(time.DateOnly), r.To.Format(time.DateOnly))
+	case r.From != nil:
+		period = "since " + r.From.Format(time.DateOnly)
+	case r.To != nil:
+		period = "until " + r.To.Format(time.DateOnly)
+	}
+	fmt.Fprintf(tw, "===== Library Report (%s) =====\n", period)
+
+	fmt.Fprintln(tw, "\nMost Borrowed Titles")
+	fmt.Fprintln(tw, "ID\tTitle\tAuthor\tLoans")
+	for _, t := range r.PopularTitles {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\n", t.BookID, t.Title, t.Author, t.Loans)
+	}
+
+	fmt.Fprintln(tw, "\nMost Borrowed Authors")
+	fmt.Fprintln(tw, "Author\tLoans")
+	for _, a := range r.PopularAuthors {
+		fmt.Fprintf(tw, "%s\t%d\n", a.Author, a.Loans)
+	}
+
+	fmt.Fprintln(tw, "\nMost Active Members")
+	fmt.Fprintln(tw, "ID\tName\tLoans\tFines")
+	for _, m := range r.ActiveMembers {
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%.2f\n", m.MemberID, m.Name, m.Loans, m.Fines)
+	}
+
+	u := r.CurrentUtilization
+	fmt.Fprintln(tw, "\nCirculation")
+	fmt.Fprintf(tw, "Completed loans\t%d\n", r.CompletedLoans)
+	fmt.Fprintf(tw, "Average loan duration\t%.1f days\n", r.AverageLoanDays)
+	fmt.Fprintf(tw, "Fines charged\t%.2f\n", r.TotalFines)
+	fmt.Fprintf(tw, "Borrowed now\t%d of %d (%.0f%%)\n", u.Borrowed, u.Total, u.Rate*100)
+	fmt.Fprintf(tw, "Available now\t%d\n", u.Available)
+
+	return tw.Flush()
+}
@@ -0,0 +1,166 @@
+package reports
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"library_management/models"
+	"library_management/services"
+)
+
+var start = time.Date(2026, 9, 1, 0, 0, 0, 0, time.Local)
+
+func day(n int) time.Time {
+	return start.AddDate(0, 0, n)
+}
+
+// newLibrary builds a library whose history spans the first month from start:
+// Ada keeps Dune six days too long, Grace reads Emma and then Dune, and Ada
+// and Grace still hold Children of Dune and Dune.
+func newLibrary(t *testing.T) *services.Library {
+	t.Helper()
+	library := services.NewLibrary()
+	var now time.Time
+	library.SetClock(func() time.Time { return now })
+
+	for _, book := range []models.Book{
+		{ID: 1, Title: "Dune", Author: "Frank Herbert"},
+		{ID: 2, Title: "Children of Dune", Author: "Frank Herbert"},
+		{ID: 3, Title: "Emma", Author: "Jane Austen"},
+		{ID: 4, Title: "Neuromancer", Author: "William Gibson"},
+	} {
+		if err := library.AddBook(book); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, member := range []models.Member{{ID: 1, Name: "Ada"}, {ID: 2, Name: "Grace"}, {ID: 3, Name: "Alan"}} {
+		if err := library.AddMember(member); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, step := range []struct {
+		day            int
+		borrow         bool
+		book, memberID int
+	}{
+		{0, true, 1, 1},
+		{20, false, 1, 1},
+		{21, true, 3, 2},
+		{23, false, 3, 2},
+		{30, true, 1, 2},
+		{31, true, 2, 1},
+	} {
+		now = day(step.day)
+		var err error
+		if step.borrow {
+			err = library.BorrowBook(step.book, step.memberID)
+		} else {
+			err = library.ReturnBook(step.book, step.memberID)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return library
+}
+
+func TestBuild(t *testing.T) {
+	library := newLibrary(t)
+	utilization := Utilization{Total: 4, Borrowed: 2, Available: 2, Rate: 0.5}
+
+	tests := []struct {
+		name   string
+		window Window
+		top    int
+		want   Report
+	}{
+		{
+			name: "all time",
+			want: Report{
+				PopularTitles: []TitleCount{
+					{BookID: 1, Title: "Dune", Author: "Frank Herbert", Loans: 2},
+					{BookID: 2, Title: "Children of Dune", Author: "Frank Herbert", Loans: 1},
+					{BookID: 3, Title: "Emma", Author: "Jane Austen", Loans: 1},
+				},
+				PopularAuthors: []AuthorCount{{"Frank Herbert", 3}, {"Jane Austen", 1}},
+				ActiveMembers: []MemberActivity{
+					{MemberID: 1, Name: "Ada", Loans: 2, Fines: 3},
+					{MemberID: 2, Name: "Grace", Loans: 2},
+				},
+				CompletedLoans:     2,
+				AverageLoanDays:    11,
+				TotalFines:         3,
+				CurrentUtilization: utilization,
+			},
+		},
+		{
+			name:   "window",
+			window: Window{From: day(21), To: day(31)},
+			want: Report{
+				PopularTitles: []TitleCount{
+					{BookID: 1, Title: "Dune", Author: "Frank Herbert", Loans: 1},
+					{BookID: 3, Title: "Emma", Author: "Jane Austen", Loans: 1},
+				},
+				PopularAuthors:     []AuthorCount{{"Frank Herbert", 1}, {"Jane Austen", 1}},
+				ActiveMembers:      []MemberActivity{{MemberID: 2, Name: "Grace", Loans: 2}},
+				CompletedLoans:     1,
+				AverageLoanDays:    2,
+				CurrentUtilization: utilization,
+			},
+		},
+		{
+			name: "top",
+			top:  1,
+			want: Report{
+				PopularTitles:      []TitleCount{{BookID: 1, Title: "Dune", Author: "Frank Herbert", Loans: 2}},
+				PopularAuthors:     []AuthorCount{{"Frank Herbert", 3}},
+				ActiveMembers:      []MemberActivity{{MemberID: 1, Name: "Ada", Loans: 2, Fines: 3}},
+				CompletedLoans:     2,
+				AverageLoanDays:    11,
+				TotalFines:         3,
+				CurrentUtilization: utilization,
+			},
+		},
+		{
+			name:   "empty window",
+			window: Window{From: day(60)},
+			want: Report{
+				PopularTitles:      []TitleCount{},
+				PopularAuthors:     []AuthorCount{},
+				ActiveMembers:      []MemberActivity{},
+				CurrentUtilization: utilization,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Build(library, tt.window, tt.top)
+			got.From, got.To = nil, nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Build() = %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	report := Build(newLibrary(t), Window{From: day(21), To: day(31)}, DefaultTop)
+
+	var buf bytes.Buffer
+	if err := report.WriteTable(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Library Report (2026-09-22 to 2026-10-02)",
+		"Average loan duration  2.0 days",
+		"Borrowed now           2 of 4 (50%)",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("table lacks %q:\n%s", want, buf.String())
+		}
+	}
+}
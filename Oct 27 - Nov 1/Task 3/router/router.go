@@ -0,0 +1,55 @@
+package router
+
+import (
+	"library_management/controllers"
+	"library_management/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupRouter(authController *controllers.AuthController, libraryController *controllers.LibraryController) *gin.Engine {
+	r := gin.Default()
+
+	// Auth routes
+	auth := r.Group("/api/auth")
+	{
+		auth.POST("/login", authController.Login)
+	}
+
+	// Protected routes
+	api := r.Group("/api")
+	api.Use(middleware.AuthMiddleware())
+	{
+		// Book routes
+		books := api.Group("/books")
+		{
+			books.GET("", libraryController.SearchBooks)
+			books.GET("/available", libraryController.ListAvailableBooks)
+			books.GET("/:id", libraryController.GetBook)
+			books.POST("", middleware.LibrarianOnly(), libraryController.CreateBook)
+			books.DELETE("/:id", middleware.LibrarianOnly(), libraryController.DeleteBook)
+		}
+
+		// Member routes
+		members := api.Group("/members")
+		{
+			members.GET("", middleware.LibrarianOnly(), libraryController.ListMembers)
+			members.POST("", middleware.LibrarianOnly(), libraryController.CreateMember)
+			members.GET("/:id", libraryController.GetMember)
+			members.GET("/:id/loans", libraryController.ListLoans)
+		}
+
+		// Loan routes
+		loans := api.Group("/loans")
+		loans.Use(middleware.LibrarianOnly())
+		{
+			loans.POST("", libraryController.BorrowBook)
+			loans.POST("/return", libraryController.ReturnBook)
+		}
+
+		// Report routes
+		api.GET("/reports", middleware.LibrarianOnly(), libraryController.GetReport)
+	}
+
+	return r
+}
@@ -0,0 +1,12 @@
+package services
+
+import "errors"
+
+var (
+	ErrBookNotFound    = errors.New("book not found")
+	ErrBookBorrowed    = errors.New("book is borrowed")
+	ErrBookNotBorrowed = errors.New("book not borrowed by this member")
+	ErrDuplicateID     = errors.New("id already exists")
+	ErrDuplicateISBN   = errors.New("isbn already belongs to another book")
+	ErrMemberNotFound  = errors.New("member not found")
+)
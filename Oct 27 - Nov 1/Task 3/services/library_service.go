@@ -1,71 +1,253 @@
 package services
 
 import (
-	"errors"
 	"library_management/models"
+	"math"
+	"sort"
+	"sync"
+	"time"
 )
 
 type LibraryManager interface {
-	AddBook(book models.Book)
-	RemoveBook(bookID int)
+	AddBook(book models.Book) error
+	RemoveBook(bookID int) error
+	AddMember(member models.Member) error
+	UpdateBook(book models.Book) error
+	UpdateMember(member models.Member) error
+	GetBook(bookID int) (models.Book, error)
+	GetMember(memberID int) (models.Member, error)
 	BorrowBook(bookID int, memberID int) error
 	ReturnBook(bookID int, memberID int) error
+	RestoreLoan(bookID int, memberID int, borrowedAt time.Time) error
+	History() []models.LoanEvent
+	MergeHistory(events []models.LoanEvent)
+	ListBooks() []models.Book
+	ListMembers() []models.Member
 	ListAvailableBooks() []models.Book
 	ListBorrowedBooks(memberID int) []models.Book
+	Search(query SearchQuery) SearchResult
 }
 
+// Library is safe for concurrent use. Every exported method holds mu for its
+// whole check-then-write sequence, so two callers can never both borrow the
+// same book.
 type Library struct {
-	Books   map[int]models.Book
-	Members map[int]models.Member
+	mu      sync.RWMutex
+	books   map[int]models.Book
+	members map[int]models.Member
+	index   *searchIndex
+	history []models.LoanEvent
+	policy  LoanPolicy
+	now     func() time.Time
 }
 
+// LoanPolicy sets how long a book may be kept and the fine charged for each
+// started day it is returned late.
+type LoanPolicy struct {
+	Period    time.Duration
+	DailyFine float64
+}
+
+var DefaultLoanPolicy = LoanPolicy{Period: 14 * 24 * time.Hour, DailyFine: 0.50}
+
 func NewLibrary() *Library {
 	return &Library{
-		Books:   make(map[int]models.Book),
-		Members: make(map[int]models.Member),
+		books:   make(map[int]models.Book),
+		members: make(map[int]models.Member),
+		index:   newSearchIndex(),
+		policy:  DefaultLoanPolicy,
+		now:     time.Now,
+	}
+}
+
+func (l *Library) SetLoanPolicy(policy LoanPolicy) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.policy = policy
+}
+
+// SetClock replaces the time source used to stamp loan events.
+func (l *Library) SetClock(now func() time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.now = now
+}
+
+func (l *Library) AddBook(book models.Book) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, exists := l.books[book.ID]; exists {
+		return ErrDuplicateID
+	}
+	if l.isbnTaken(book) {
+		return ErrDuplicateISBN
 	}
+	book.Status = models.StatusAvailable
+	l.books[book.ID] = book
+	l.index.add(book)
+	return nil
 }
 
-func (l *Library) AddBook(book models.Book) {
-	book.Status = "Available"
-	l.Books[book.ID] = book
+func (l *Library) RemoveBook(bookID int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	book, exists := l.books[bookID]
+	if !exists {
+		return ErrBookNotFound
+	}
+	if book.Status == models.StatusBorrowed {
+		return ErrBookBorrowed
+	}
+	l.index.remove(book)
+	delete(l.books, bookID)
+	return nil
 }
 
-func (l *Library) RemoveBook(bookID int) {
-	delete(l.Books, bookID)
+func (l *Library) AddMember(member models.Member) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, exists := l.members[member.ID]; exists {
+		return ErrDuplicateID
+	}
+	member.BorrowedBooks = nil
+	l.members[member.ID] = member
+	return nil
+}
+
+// UpdateBook replaces the catalogue details of an existing book. Its loan
+// status is kept, as is the copy held by the borrowing member.
+func (l *Library) UpdateBook(book models.Book) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	existing, exists := l.books[book.ID]
+	if !exists {
+		return ErrBookNotFound
+	}
+	if l.isbnTaken(book) {
+		return ErrDuplicateISBN
+	}
+	book.Status = existing.Status
+	l.index.remove(existing)
+	l.books[book.ID] = book
+	l.index.add(book)
+
+	if book.Status == models.StatusBorrowed {
+		for id, member := range l.members {
+			for i, b := range member.BorrowedBooks {
+				if b.ID == book.ID {
+					member.BorrowedBooks[i] = book
+					l.members[id] = member
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// isbnTaken reports whether another book already has book's ISBN.
+func (l *Library) isbnTaken(book models.Book) bool {
+	id, ok := l.index.lookupISBN(book.ISBN)
+	return ok && id != book.ID
+}
+
+// UpdateMember replaces an existing member's name, and their password when
+// a new one is given. Their loans are kept.
+func (l *Library) UpdateMember(member models.Member) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	existing, exists := l.members[member.ID]
+	if !exists {
+		return ErrMemberNotFound
+	}
+	if member.Password == "" {
+		member.Password = existing.Password
+	}
+	member.BorrowedBooks = existing.BorrowedBooks
+	l.members[member.ID] = member
+	return nil
+}
+
+func (l *Library) GetBook(bookID int) (models.Book, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	book, exists := l.books[bookID]
+	if !exists {
+		return models.Book{}, ErrBookNotFound
+	}
+	return book, nil
+}
+
+func (l *Library) GetMember(memberID int) (models.Member, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	member, exists := l.members[memberID]
+	if !exists {
+		return models.Member{}, ErrMemberNotFound
+	}
+	member.BorrowedBooks = append([]models.Book(nil), member.BorrowedBooks...)
+	return member, nil
 }
 
 func (l *Library) BorrowBook(bookID int, memberID int) error {
-	book, exists := l.Books[bookID]
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.borrow(bookID, memberID, l.now())
+}
+
+// RestoreLoan lends a book like BorrowBook but records the loan as starting
+// at borrowedAt, so restored loans keep their original due date.
+func (l *Library) RestoreLoan(bookID int, memberID int, borrowedAt time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.borrow(bookID, memberID, borrowedAt)
+}
+
+func (l *Library) borrow(bookID int, memberID int, at time.Time) error {
+	book, exists := l.books[bookID]
 	if !exists {
-		return errors.New("book not found")
+		return ErrBookNotFound
 	}
-	if book.Status == "Borrowed" {
-		return errors.New("book already borrowed")
+	if book.Status == models.StatusBorrowed {
+		return ErrBookBorrowed
 	}
 
-	member, exists := l.Members[memberID]
+	member, exists := l.members[memberID]
 	if !exists {
-		return errors.New("member not found")
+		return ErrMemberNotFound
 	}
 
-	book.Status = "Borrowed"
+	book.Status = models.StatusBorrowed
 	member.BorrowedBooks = append(member.BorrowedBooks, book)
-	l.Books[bookID] = book
-	l.Members[memberID] = member
+	l.books[bookID] = book
+	l.members[memberID] = member
+	l.record(models.LoanEvent{Type: models.LoanBorrowed, BookID: bookID, MemberID: memberID, At: at})
 
 	return nil
 }
 
 func (l *Library) ReturnBook(bookID int, memberID int) error {
-	book, exists := l.Books[bookID]
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	book, exists := l.books[bookID]
 	if !exists {
-		return errors.New("book not found")
+		return ErrBookNotFound
 	}
 
-	member, exists := l.Members[memberID]
+	member, exists := l.members[memberID]
 	if !exists {
-		return errors.New("member not found")
+		return ErrMemberNotFound
 	}
 
 	found := false
@@ -77,20 +259,120 @@ func (l *Library) ReturnBook(bookID int, memberID int) error {
 		}
 	}
 	if !found {
-		return errors.New("book not borrowed by this member")
+		return ErrBookNotBorrowed
 	}
 
-	book.Status = "Available"
-	l.Books[bookID] = book
-	l.Members[memberID] = member
+	book.Status = models.StatusAvailable
+	l.books[bookID] = book
+	l.members[memberID] = member
+
+	now := l.now()
+	event := models.LoanEvent{Type: models.LoanReturned, BookID: bookID, MemberID: memberID, At: now}
+	if borrowedAt, ok := l.openLoanStart(bookID, memberID); ok {
+		event.Fine = l.policy.fine(borrowedAt, now)
+	}
+	l.record(event)
 
 	return nil
 }
 
+// History returns every borrow and return event in the order they happened.
+func (l *Library) History() []models.LoanEvent {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return append([]models.LoanEvent(nil), l.history...)
+}
+
+// MergeHistory adds events from a backup to the history, ignoring any that
+// are already recorded, and keeps the history in time order.
+func (l *Library) MergeHistory(events []models.LoanEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	type eventKey struct {
+		Type             string
+		BookID, MemberID int
+		At               int64
+	}
+	keyOf := func(e models.LoanEvent) eventKey {
+		return eventKey{e.Type, e.BookID, e.MemberID, e.At.UnixNano()}
+	}
+
+	seen := make(map[eventKey]struct{}, len(l.history))
+	for _, e := range l.history {
+		seen[keyOf(e)] = struct{}{}
+	}
+	for _, e := range events {
+		if _, ok := seen[keyOf(e)]; !ok {
+			seen[keyOf(e)] = struct{}{}
+			l.history = append(l.history, e)
+		}
+	}
+	sort.SliceStable(l.history, func(i, j int) bool { return l.history[i].At.Before(l.history[j].At) })
+}
+
+func (l *Library) record(event models.LoanEvent) {
+	l.history = append(l.history, event)
+}
+
+// openLoanStart finds when memberID borrowed bookID in the loan that is
+// currently open.
+func (l *Library) openLoanStart(bookID int, memberID int) (time.Time, bool) {
+	for i := len(l.history) - 1; i >= 0; i-- {
+		e := l.history[i]
+		if e.BookID != bookID {
+			continue
+		}
+		if e.Type == models.LoanBorrowed && e.MemberID == memberID {
+			return e.At, true
+		}
+		return time.Time{}, false
+	}
+	return time.Time{}, false
+}
+
+func (p LoanPolicy) fine(borrowedAt, returnedAt time.Time) float64 {
+	late := returnedAt.Sub(borrowedAt.Add(p.Period))
+	if late <= 0 {
+		return 0
+	}
+	days := math.Ceil(late.Hours() / 24)
+	return days * p.DailyFine
+}
+
+func (l *Library) ListBooks() []models.Book {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	books := make([]models.Book, 0, len(l.books))
+	for _, b := range l.books {
+		books = append(books, b)
+	}
+	sortBooks(books, SortByID, false)
+	return books
+}
+
+func (l *Library) ListMembers() []models.Member {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	members := make([]models.Member, 0, len(l.members))
+	for _, m := range l.members {
+		m.BorrowedBooks = append([]models.Book(nil), m.BorrowedBooks...)
+		members = append(members, m)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].ID < members[j].ID })
+	return members
+}
+
 func (l *Library) ListAvailableBooks() []models.Book {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
 	var available []models.Book
-	for _, b := range l.Books {
-		if b.Status == "Available" {
+	for _, b := range l.books {
+		if b.Status == models.StatusAvailable {
 			available = append(available, b)
 		}
 	}
@@ -98,9 +380,12 @@ func (l *Library) ListAvailableBooks() []models.Book {
 }
 
 func (l *Library) ListBorrowedBooks(memberID int) []models.Book {
-	member, exists := l.Members[memberID]
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	member, exists := l.members[memberID]
 	if !exists {
 		return nil
 	}
-	return member.BorrowedBooks
+	return append([]models.Book(nil), member.BorrowedBooks...)
 }
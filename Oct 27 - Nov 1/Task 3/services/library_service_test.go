@@ -0,0 +1,130 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"library_management/models"
+)
+
+// Run with -race: borrowers that all go for the same book at once must see
+// exactly one success, and the library must end up consistent.
+func TestBorrowBookConcurrent(t *testing.T) {
+	const borrowers = 64
+	const rounds = 50
+
+	for round := 0; round < rounds; round++ {
+		library := NewLibrary()
+		if err := library.AddBook(models.Book{ID: 1, Title: "Dune", Status: models.StatusAvailable}); err != nil {
+			t.Fatal(err)
+		}
+		for id := 1; id <= borrowers; id++ {
+			if err := library.AddMember(models.Member{ID: id, Name: "member"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		start := make(chan struct{})
+		errs := make([]error, borrowers)
+		var wg sync.WaitGroup
+		for i := 0; i < borrowers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				<-start
+				errs[i] = library.BorrowBook(1, i+1)
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+
+		winner := 0
+		for i, err := range errs {
+			switch {
+			case err == nil:
+				if winner != 0 {
+					t.Fatalf("round %d: members %d and %d both borrowed the book", round, winner, i+1)
+				}
+				winner = i + 1
+			case !errors.Is(err, ErrBookBorrowed):
+				t.Fatalf("round %d: member %d: got %v, want %v", round, i+1, err, ErrBookBorrowed)
+			}
+		}
+		if winner == 0 {
+			t.Fatalf("round %d: no borrower succeeded", round)
+		}
+
+		book, err := library.GetBook(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if book.Status != models.StatusBorrowed {
+			t.Fatalf("round %d: book status = %q, want %q", round, book.Status, models.StatusBorrowed)
+		}
+		for _, member := range library.ListMembers() {
+			want := 0
+			if member.ID == winner {
+				want = 1
+			}
+			if got := len(member.BorrowedBooks); got != want {
+				t.Fatalf("round %d: member %d holds %d books, want %d", round, member.ID, got, want)
+			}
+		}
+		if got := len(library.History()); got != 1 {
+			t.Fatalf("round %d: %d loan events recorded, want 1", round, got)
+		}
+	}
+}
+
+// Borrowing and returning from many goroutines must never lend a book twice
+// or lose one.
+func TestBorrowReturnStress(t *testing.T) {
+	const books = 4
+	const members = 16
+	const iterations = 200
+
+	library := NewLibrary()
+	for id := 1; id <= books; id++ {
+		if err := library.AddBook(models.Book{ID: id, Title: "book", Status: models.StatusAvailable}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for id := 1; id <= members; id++ {
+		if err := library.AddMember(models.Member{ID: id, Name: "member"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for memberID := 1; memberID <= members; memberID++ {
+		wg.Add(1)
+		go func(memberID int) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				bookID := (memberID+i)%books + 1
+				switch err := library.BorrowBook(bookID, memberID); {
+				case err == nil:
+					if err := library.ReturnBook(bookID, memberID); err != nil {
+						t.Errorf("member %d returning book %d: %v", memberID, bookID, err)
+						return
+					}
+				case !errors.Is(err, ErrBookBorrowed):
+					t.Errorf("member %d borrowing book %d: %v", memberID, bookID, err)
+					return
+				}
+				library.ListAvailableBooks()
+			}
+		}(memberID)
+	}
+	wg.Wait()
+
+	if got := len(library.ListAvailableBooks()); got != books {
+		t.Fatalf("%d books available after all returns, want %d", got, books)
+	}
+	for _, member := range library.ListMembers() {
+		if len(member.BorrowedBooks) != 0 {
+			t.Fatalf("member %d still holds %d books", member.ID, len(member.BorrowedBooks))
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"library_management/models"
+)
+
+const (
+	SortByID     = "id"
+	SortByTitle  = "title"
+	SortByAuthor = "author"
+)
+
+// SearchQuery describes a catalogue search. Empty fields do not filter, and
+// a zero Limit returns every match after Offset.
+type SearchQuery struct {
+	Text       string
+	ISBN       string
+	Author     string
+	Subject    string
+	Status     string
+	SortBy     string
+	Descending bool
+	Offset     int
+	Limit      int
+}
+
+// SearchResult holds one page of matching books along with the total number
+// of matches before paging.
+type SearchResult struct {
+	Books []models.Book
+	Total int
+}
+
+func (l *Library) Search(query SearchQuery) SearchResult {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	hasText := len(tokenize(query.Text)) > 0
+	var textHits map[int]struct{}
+	if hasText {
+		textHits = l.index.match(query.Text)
+	}
+
+	var candidates []models.Book
+	switch {
+	case query.ISBN != "":
+		if id, ok := l.index.lookupISBN(query.ISBN); ok {
+			candidates = append(candidates, l.books[id])
+		}
+	case hasText:
+		for id := range textHits {
+			candidates = append(candidates, l.books[id])
+		}
+	default:
+		for _, b := range l.books {
+			candidates = append(candidates, b)
+		}
+	}
+
+	var matches []models.Book
+	for _, b := range candidates {
+		if hasText {
+			if _, ok := textHits[b.ID]; !ok {
+				continue
+			}
+		}
+		if query.Author != "" && !strings.EqualFold(strings.TrimSpace(b.Author), strings.TrimSpace(query.Author)) {
+			continue
+		}
+		if query.Subject != "" && !strings.EqualFold(strings.TrimSpace(b.Subject), strings.TrimSpace(query.Subject)) {
+			continue
+		}
+		if query.Status != "" && !strings.EqualFold(b.Status, query.Status) {
+			continue
+		}
+		matches = append(matches, b)
+	}
+
+	sortBooks(matches, query.SortBy, query.Descending)
+
+	result := SearchResult{Total: len(matches)}
+	if query.Offset > 0 {
+		if query.Offset >= len(matches) {
+			return result
+		}
+		matches = matches[query.Offset:]
+	}
+	if query.Limit > 0 && query.Limit < len(matches) {
+		matches = matches[:query.Limit]
+	}
+	result.Books = matches
+	return result
+}
+
+func sortBooks(books []models.Book, sortBy string, descending bool) {
+	key := func(b models.Book) string {
+		switch sortBy {
+		case SortByTitle:
+			return strings.ToLower(b.Title)
+		case SortByAuthor:
+			return strings.ToLower(b.Author)
+		}
+		return ""
+	}
+
+	sort.SliceStable(books, func(i, j int) bool {
+		a, b := books[i], books[j]
+		if descending {
+			a, b = b, a
+		}
+		if ka, kb := key(a), key(b); ka != kb {
+			return ka < kb
+		}
+		return a.ID < b.ID
+	})
+}
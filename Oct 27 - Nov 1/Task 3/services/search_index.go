@@ -0,0 +1,105 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"library_management/models"
+)
+
+// searchIndex is an inverted index from lower-cased title and author terms
+// to book IDs, plus an exact lookup table for normalized ISBNs. The terms
+// are also kept sorted, so the terms a prefix matches are found by binary
+// search instead of a scan of the whole vocabulary.
+type searchIndex struct {
+	terms  map[string]map[int]struct{}
+	sorted []string
+	isbns  map[string]int
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		terms: make(map[string]map[int]struct{}),
+		isbns: make(map[string]int),
+	}
+}
+
+func (idx *searchIndex) add(book models.Book) {
+	for _, term := range tokenize(book.Title + " " + book.Author) {
+		ids, ok := idx.terms[term]
+		if !ok {
+			ids = make(map[int]struct{})
+			idx.terms[term] = ids
+			i := sort.SearchStrings(idx.sorted, term)
+			idx.sorted = append(idx.sorted, "")
+			copy(idx.sorted[i+1:], idx.sorted[i:])
+			idx.sorted[i] = term
+		}
+		ids[book.ID] = struct{}{}
+	}
+	if isbn := normalizeISBN(book.ISBN); isbn != "" {
+		idx.isbns[isbn] = book.ID
+	}
+}
+
+func (idx *searchIndex) remove(book models.Book) {
+	for _, term := range tokenize(book.Title + " " + book.Author) {
+		ids, ok := idx.terms[term]
+		if !ok {
+			continue
+		}
+		delete(ids, book.ID)
+		if len(ids) == 0 {
+			delete(idx.terms, term)
+			i := sort.SearchStrings(idx.sorted, term)
+			idx.sorted = append(idx.sorted[:i], idx.sorted[i+1:]...)
+		}
+	}
+	if isbn := normalizeISBN(book.ISBN); isbn != "" && idx.isbns[isbn] == book.ID {
+		delete(idx.isbns, isbn)
+	}
+}
+
+// match returns the IDs of books whose title or author contain every term of
+// text, where a query term matches any indexed term it is a prefix of.
+func (idx *searchIndex) match(text string) map[int]struct{} {
+	var result map[int]struct{}
+	for _, term := range tokenize(text) {
+		hits := make(map[int]struct{})
+		for i := sort.SearchStrings(idx.sorted, term); i < len(idx.sorted) && strings.HasPrefix(idx.sorted[i], term); i++ {
+			for id := range idx.terms[idx.sorted[i]] {
+				if result == nil {
+					hits[id] = struct{}{}
+				} else if _, ok := result[id]; ok {
+					hits[id] = struct{}{}
+				}
+			}
+		}
+		result = hits
+		if len(result) == 0 {
+			break
+		}
+	}
+	return result
+}
+
+func (idx *searchIndex) lookupISBN(isbn string) (int, bool) {
+	id, ok := idx.isbns[normalizeISBN(isbn)]
+	return id, ok
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func normalizeISBN(isbn string) string {
+	return strings.ToUpper(strings.Map(func(r rune) rune {
+		if r == '-' || unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, isbn))
+}
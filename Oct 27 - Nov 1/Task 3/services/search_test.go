@@ -0,0 +1,158 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"library_management/models"
+)
+
+func newCatalogue(t *testing.T) *Library {
+	t.Helper()
+	library := NewLibrary()
+	for _, book := range []models.Book{
+		{ID: 1, Title: "The Go Programming Language", Author: "Alan Donovan", ISBN: "978-0-13-419044-0", Subject: "Programming"},
+		{ID: 2, Title: "Go in Action", Author: "William Kennedy", ISBN: "978-1617291784", Subject: "Programming"},
+		{ID: 3, Title: "Good Omens", Author: "Terry Pratchett", ISBN: "978-0060853983", Subject: "Fiction"},
+		{ID: 4, Title: "Gophers and Golang", Author: "Ada Lovelace", Subject: "Programming"},
+		{ID: 5, Title: "Dune", Author: "Frank Herbert", ISBN: "978-0441172719", Subject: "Fiction"},
+	} {
+		if err := library.AddBook(book); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return library
+}
+
+func ids(books []models.Book) []int {
+	out := make([]int, len(books))
+	for i, b := range books {
+		out[i] = b.ID
+	}
+	return out
+}
+
+func TestSearch(t *testing.T) {
+	library := newCatalogue(t)
+	if err := library.AddMember(models.Member{ID: 1, Name: "Reader"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := library.BorrowBook(5, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		query SearchQuery
+		want  []int
+		total int
+	}{
+		{"everything by ID", SearchQuery{}, []int{1, 2, 3, 4, 5}, 5},
+		{"whole word", SearchQuery{Text: "dune"}, []int{5}, 1},
+		{"case-insensitive", SearchQuery{Text: "DUNE"}, []int{5}, 1},
+		{"prefix", SearchQuery{Text: "go"}, []int{1, 2, 3, 4}, 4},
+		{"longer prefix", SearchQuery{Text: "gop"}, []int{4}, 1},
+		{"prefix past the last term", SearchQuery{Text: "zz"}, nil, 0},
+		{"prefix before the first term", SearchQuery{Text: "0"}, nil, 0},
+		{"author words", SearchQuery{Text: "kennedy"}, []int{2}, 1},
+		{"every term must match", SearchQuery{Text: "go action"}, []int{2}, 1},
+		{"terms across title and author", SearchQuery{Text: "golang ada"}, []int{4}, 1},
+		{"no match", SearchQuery{Text: "dune pratchett"}, nil, 0},
+		{"punctuation only is no text filter", SearchQuery{Text: "--"}, []int{1, 2, 3, 4, 5}, 5},
+		{"ISBN ignores hyphens and spaces", SearchQuery{ISBN: "978 0134190440"}, []int{1}, 1},
+		{"ISBN without hyphens", SearchQuery{ISBN: "9781617291784"}, []int{2}, 1},
+		{"unknown ISBN", SearchQuery{ISBN: "000"}, nil, 0},
+		{"ISBN and text", SearchQuery{ISBN: "978-0060853983", Text: "dune"}, nil, 0},
+		{"author", SearchQuery{Author: " frank herbert "}, []int{5}, 1},
+		{"subject", SearchQuery{Subject: "fiction"}, []int{3, 5}, 2},
+		{"status", SearchQuery{Status: models.StatusBorrowed}, []int{5}, 1},
+		{"text and status", SearchQuery{Text: "go", Status: models.StatusAvailable, Subject: "Programming"}, []int{1, 2, 4}, 3},
+		{"by title", SearchQuery{SortBy: SortByTitle}, []int{5, 2, 3, 4, 1}, 5},
+		{"by author descending", SearchQuery{SortBy: SortByAuthor, Descending: true}, []int{2, 3, 5, 1, 4}, 5},
+		{"page", SearchQuery{Offset: 1, Limit: 2}, []int{2, 3}, 5},
+		{"offset past the end", SearchQuery{Offset: 9}, nil, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := library.Search(tt.query)
+			if got := ids(result.Books); len(got)+len(tt.want) > 0 && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("books = %v, want %v", got, tt.want)
+			}
+			if result.Total != tt.total {
+				t.Errorf("total = %d, want %d", result.Total, tt.total)
+			}
+		})
+	}
+}
+
+func TestSearchFollowsChanges(t *testing.T) {
+	library := newCatalogue(t)
+
+	if err := library.RemoveBook(4); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(library.Search(SearchQuery{Text: "gop"}).Books); len(got) != 0 {
+		t.Errorf("removed book still found: %v", got)
+	}
+
+	book, _ := library.GetBook(3)
+	book.Title = "Going Postal"
+	if err := library.UpdateBook(book); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(library.Search(SearchQuery{Text: "omens"}).Books); len(got) != 0 {
+		t.Errorf("old title still found: %v", got)
+	}
+	if got := ids(library.Search(SearchQuery{Text: "postal"}).Books); !reflect.DeepEqual(got, []int{3}) {
+		t.Errorf("new title found %v, want [3]", got)
+	}
+
+	// Terms other books share stay indexed
+	if got := ids(library.Search(SearchQuery{Text: "go"}).Books); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("go found %v, want [1 2 3]", got)
+	}
+
+	// The sorted terms stay consistent with the index
+	terms := make([]string, 0, len(library.index.terms))
+	for term := range library.index.terms {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+	if !reflect.DeepEqual(terms, library.index.sorted) {
+		t.Errorf("sorted terms = %q, want %q", library.index.sorted, terms)
+	}
+}
+
+func TestDuplicateISBN(t *testing.T) {
+	library := newCatalogue(t)
+
+	err := library.AddBook(models.Book{ID: 6, Title: "Dune Messiah", ISBN: "9780441172719"})
+	if !errors.Is(err, ErrDuplicateISBN) {
+		t.Errorf("AddBook with a taken ISBN: error = %v, want %v", err, ErrDuplicateISBN)
+	}
+	if _, err := library.GetBook(6); !errors.Is(err, ErrBookNotFound) {
+		t.Errorf("rejected book was stored: %v", err)
+	}
+
+	book, _ := library.GetBook(2)
+	book.ISBN = "978-0-441-17271-9"
+	if err := library.UpdateBook(book); !errors.Is(err, ErrDuplicateISBN) {
+		t.Errorf("UpdateBook to a taken ISBN: error = %v, want %v", err, ErrDuplicateISBN)
+	}
+	if id, _ := library.index.lookupISBN("978-0441172719"); id != 5 {
+		t.Errorf("ISBN now belongs to book %d, want 5", id)
+	}
+
+	// A book keeps its own ISBN when updated, and books without one never
+	// clash
+	book, _ = library.GetBook(5)
+	book.Title = "Dune (40th anniversary)"
+	if err := library.UpdateBook(book); err != nil {
+		t.Errorf("UpdateBook keeping its ISBN: %v", err)
+	}
+	if err := library.AddBook(models.Book{ID: 7, Title: "Untitled"}); err != nil {
+		t.Errorf("AddBook without an ISBN: %v", err)
+	}
+}
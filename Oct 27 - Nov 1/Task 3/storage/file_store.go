@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"errors"
+	"io/fs"
+	"library_management/services"
+	"library_management/transfer"
+	"os"
+	"path/filepath"
+)
+
+// Load reads the library stored at path, which holds a transfer.Backup
+// document. A missing file yields an empty library.
+func Load(path string) (*services.Library, error) {
+	library := services.NewLibrary()
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return library, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	report, err := transfer.ImportJSON(file, library, transfer.Options{})
+	if err != nil {
+		return nil, err
+	}
+	if len(report.Errors) > 0 {
+		return nil, report.Errors[0]
+	}
+
+	return library, nil
+}
+
+// Save writes library to path, replacing any previous contents atomically.
+func Save(path string, library services.LibraryManager) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := transfer.ExportJSON(tmp, library); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
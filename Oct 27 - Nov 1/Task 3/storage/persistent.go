@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"fmt"
+	"library_management/models"
+	"library_management/services"
+	"log"
+	"sync"
+	"time"
+)
+
+// Persistent is a LibraryManager that saves the library to its file after
+// every successful change, so a long-running process such as the HTTP
+// server shares its data with the command line.
+type Persistent struct {
+	services.LibraryManager
+	path string
+	mu   sync.Mutex
+}
+
+// NewPersistent wraps library, saving it to path.
+func NewPersistent(path string, library services.LibraryManager) *Persistent {
+	return &Persistent{LibraryManager: library, path: path}
+}
+
+// change applies fn and saves the library if it succeeds. Changes are
+// serialized so the file is always written with the latest state.
+func (p *Persistent) change(fn func() error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := fn(); err != nil {
+		return err
+	}
+	if err := Save(p.path, p.LibraryManager); err != nil {
+		return fmt.Errorf("saving library: %w", err)
+	}
+	return nil
+}
+
+func (p *Persistent) AddBook(book models.Book) error {
+	return p.change(func() error { return p.LibraryManager.AddBook(book) })
+}
+
+func (p *Persistent) RemoveBook(bookID int) error {
+	return p.change(func() error { return p.LibraryManager.RemoveBook(bookID) })
+}
+
+func (p *Persistent) AddMember(member models.Member) error {
+	return p.change(func() error { return p.LibraryManager.AddMember(member) })
+}
+
+func (p *Persistent) UpdateBook(book models.Book) error {
+	return p.change(func() error { return p.LibraryManager.UpdateBook(book) })
+}
+
+func (p *Persistent) UpdateMember(member models.Member) error {
+	return p.change(func() error { return p.LibraryManager.UpdateMember(member) })
+}
+
+func (p *Persistent) BorrowBook(bookID int, memberID int) error {
+	return p.change(func() error { return p.LibraryManager.BorrowBook(bookID, memberID) })
+}
+
+func (p *Persistent) ReturnBook(bookID int, memberID int) error {
+	return p.change(func() error { return p.LibraryManager.ReturnBook(bookID, memberID) })
+}
+
+func (p *Persistent) RestoreLoan(bookID int, memberID int, borrowedAt time.Time) error {
+	return p.change(func() error { return p.LibraryManager.RestoreLoan(bookID, memberID, borrowedAt) })
+}
+
+func (p *Persistent) MergeHistory(events []models.LoanEvent) {
+	err := p.change(func() error {
+		p.LibraryManager.MergeHistory(events)
+		return nil
+	})
+	if err != nil {
+		log.Println("library:", err)
+	}
+}
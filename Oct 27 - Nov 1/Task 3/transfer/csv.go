@@ -0,0 +1,142 @@
+package transfer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"library_management/services"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	bookColumns   = []string{"id", "title", "author", "isbn", "subject", "status", "borrowed_by", "borrowed_at"}
+	memberColumns = []string{"id", "name", "password_hash"}
+)
+
+func ExportBooksCSV(w io.Writer, library services.LibraryManager) error {
+	writer := csv.NewWriter(w)
+	writer.Write(bookColumns)
+	for _, b := range Snapshot(library).Books {
+		borrowedBy, borrowedAt := "", ""
+		if b.BorrowedBy != 0 {
+			borrowedBy = strconv.Itoa(b.BorrowedBy)
+		}
+		if b.BorrowedAt != nil {
+			borrowedAt = b.BorrowedAt.Format(time.RFC3339)
+		}
+		writer.Write([]string{strconv.Itoa(b.ID), b.Title, b.Author, b.ISBN, b.Subject, b.Status, borrowedBy, borrowedAt})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func ExportMembersCSV(w io.Writer, library services.LibraryManager) error {
+	writer := csv.NewWriter(w)
+	writer.Write(memberColumns)
+	for _, m := range Snapshot(library).Members {
+		writer.Write([]string{strconv.Itoa(m.ID), m.Name, m.PasswordHash})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ImportBooksCSV reads books from CSV with a header row naming the columns
+// of ExportBooksCSV; only id, title and author are required. Members named
+// in borrowed_by must already exist, so import members first.
+func ImportBooksCSV(r io.Reader, library services.LibraryManager, opts Options) (Report, error) {
+	return importCSV(r, library, opts, "books", []string{"id", "title", "author"}, func(im *importer, row int, get func(string) string) {
+		rec := BookRecord{Title: get("title"), Author: get("author"), ISBN: get("isbn"), Subject: get("subject"), Status: get("status")}
+		var err error
+		if rec.ID, err = parseID(get("id")); err != nil {
+			im.fail("books", row, 0, err)
+			return
+		}
+		if value := get("borrowed_by"); value != "" {
+			if rec.BorrowedBy, err = parseID(value); err != nil {
+				im.fail("books", row, rec.ID, fmt.Errorf("%w: borrowed_by must be a member ID", ErrInvalidRecord))
+				return
+			}
+		}
+		if value := get("borrowed_at"); value != "" {
+			at, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				im.fail("books", row, rec.ID, fmt.Errorf("%w: borrowed_at must be an RFC 3339 time", ErrInvalidRecord))
+				return
+			}
+			rec.BorrowedAt = &at
+		}
+		im.book(row, rec)
+	})
+}
+
+// ImportMembersCSV reads members from CSV with a header row naming the
+// columns of ExportMembersCSV; password_hash is optional.
+func ImportMembersCSV(r io.Reader, library services.LibraryManager, opts Options) (Report, error) {
+	return importCSV(r, library, opts, "members", []string{"id", "name"}, func(im *importer, row int, get func(string) string) {
+		rec := MemberRecord{Name: get("name"), PasswordHash: get("password_hash")}
+		var err error
+		if rec.ID, err = parseID(get("id")); err != nil {
+			im.fail("members", row, 0, err)
+			return
+		}
+		im.member(row, rec)
+	})
+}
+
+func importCSV(r io.Reader, library services.LibraryManager, opts Options, kind string, required []string,
+	apply func(im *importer, row int, get func(string) string)) (Report, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return Report{}, fmt.Errorf("reading %s header: %w", kind, err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range required {
+		if _, ok := columns[name]; !ok {
+			return Report{}, fmt.Errorf("%s CSV is missing the %q column", kind, name)
+		}
+	}
+
+	return run(library, opts, func(im *importer) error {
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				return nil
+			}
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				im.fail(kind, parseErr.Line, 0, parseErr.Err)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			row, _ := reader.FieldPos(0)
+
+			apply(im, row, func(name string) string {
+				i, ok := columns[name]
+				if !ok || i >= len(record) {
+					return ""
+				}
+				return strings.TrimSpace(record[i])
+			})
+		}
+	})
+}
+
+func parseID(value string) (int, error) {
+	id, err := strconv.Atoi(value)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%w: id must be a positive integer", ErrInvalidRecord)
+	}
+	return id, nil
+}
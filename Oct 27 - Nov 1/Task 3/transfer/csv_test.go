@@ -0,0 +1,42 @@
+package transfer
+
+import (
+	"strings"
+	"testing"
+
+	"library_management/services"
+)
+
+func TestImportBooksCSVMalformedRows(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		created int
+		rows    []int
+	}{
+		{"bare quote in first field", "id,title,author\n2\"x,b,c\n3,Emma,Austen\n", 1, []int{2}},
+		{"bare quote in later field", "id,title,author\n2,b\"x,c\n3,Emma,Austen\n", 1, []int{2}},
+		{"unterminated quote", "id,title,author\n3,Emma,Austen\n4,\"Dune,Herbert\n", 1, []int{3}},
+		{"invalid id", "id,title,author\nx,Emma,Austen\n", 0, []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			library := services.NewLibrary()
+			report, err := ImportBooksCSV(strings.NewReader(tt.input), library, Options{})
+			if err != nil {
+				t.Fatalf("ImportBooksCSV: %v", err)
+			}
+			if report.Created != tt.created {
+				t.Errorf("Created = %d, want %d", report.Created, tt.created)
+			}
+			if len(report.Errors) != len(tt.rows) {
+				t.Fatalf("Errors = %v, want rows %v", report.Errors, tt.rows)
+			}
+			for i, row := range tt.rows {
+				if report.Errors[i].Row != row {
+					t.Errorf("Errors[%d].Row = %d, want %d", i, report.Errors[i].Row, row)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"library_management/models"
+	"library_management/services"
+	"time"
+)
+
+type DuplicatePolicy string
+
+const (
+	SkipDuplicates   DuplicatePolicy = "skip"
+	UpsertDuplicates DuplicatePolicy = "upsert"
+)
+
+// Options controls an import. The zero value applies changes and skips rows
+// whose ID already exists.
+type Options struct {
+	DryRun      bool
+	OnDuplicate DuplicatePolicy
+}
+
+// RowError reports why a single row was rejected. Row is the line number for
+// CSV input and the 1-based array position for JSON input.
+type RowError struct {
+	Kind string
+	Row  int
+	ID   int
+	Err  error
+}
+
+func (e RowError) Error() string {
+	if e.ID > 0 {
+		return fmt.Sprintf("%s row %d (id %d): %v", e.Kind, e.Row, e.ID, e.Err)
+	}
+	return fmt.Sprintf("%s row %d: %v", e.Kind, e.Row, e.Err)
+}
+
+func (e RowError) Unwrap() error {
+	return e.Err
+}
+
+// Report summarises an import. With DryRun set the counts describe what
+// would have happened; the library itself is left untouched.
+type Report struct {
+	DryRun  bool
+	Created int
+	Updated int
+	Skipped int
+	Errors  []RowError
+}
+
+type importer struct {
+	library services.LibraryManager
+	opts    Options
+	report  *Report
+	holders map[int]int
+}
+
+// run prepares an importer for library, or for a scratch copy of it when
+// opts.DryRun is set, and hands it to fn.
+func run(library services.LibraryManager, opts Options, fn func(im *importer) error) (Report, error) {
+	if opts.OnDuplicate == "" {
+		opts.OnDuplicate = SkipDuplicates
+	}
+	if opts.OnDuplicate != SkipDuplicates && opts.OnDuplicate != UpsertDuplicates {
+		return Report{}, fmt.Errorf("unknown duplicate policy %q", opts.OnDuplicate)
+	}
+
+	target := library
+	if opts.DryRun {
+		scratch := services.NewLibrary()
+		if _, err := restore(scratch, Snapshot(library)); err != nil {
+			return Report{}, err
+		}
+		target = scratch
+	}
+
+	report := Report{DryRun: opts.DryRun}
+	im := &importer{library: target, opts: opts, report: &report, holders: make(map[int]int)}
+	for _, m := range target.ListMembers() {
+		for _, b := range m.BorrowedBooks {
+			im.holders[b.ID] = m.ID
+		}
+	}
+
+	err := fn(im)
+	return report, err
+}
+
+// restore loads backup into library, which is expected to be empty.
+func restore(library services.LibraryManager, backup Backup) (Report, error) {
+	return run(library, Options{}, func(im *importer) error {
+		for i, m := range backup.Members {
+			im.member(i+1, m)
+		}
+		for i, b := range backup.Books {
+			im.book(i+1, b)
+		}
+		im.library.MergeHistory(backup.History)
+		return nil
+	})
+}
+
+func (im *importer) fail(kind string, row, id int, err error) {
+	im.report.Errors = append(im.report.Errors, RowError{Kind: kind, Row: row, ID: id, Err: err})
+}
+
+func (im *importer) book(row int, rec BookRecord) {
+	if err := rec.validate(); err != nil {
+		im.fail("books", row, rec.ID, err)
+		return
+	}
+	if rec.BorrowedBy != 0 {
+		if _, err := im.library.GetMember(rec.BorrowedBy); err != nil {
+			im.fail("books", row, rec.ID, err)
+			return
+		}
+	}
+
+	book := models.Book{ID: rec.ID, Title: rec.Title, Author: rec.Author, ISBN: rec.ISBN, Subject: rec.Subject}
+	_, err := im.library.GetBook(rec.ID)
+	existed := err == nil
+	switch {
+	case existed && im.opts.OnDuplicate == SkipDuplicates:
+		im.report.Skipped++
+		return
+	case existed:
+		err = im.library.UpdateBook(book)
+	case errors.Is(err, services.ErrBookNotFound):
+		err = im.library.AddBook(book)
+	}
+	if err == nil {
+		err = im.syncLoan(rec.ID, rec.BorrowedBy, rec.BorrowedAt)
+	}
+	if err != nil {
+		im.fail("books", row, rec.ID, err)
+		return
+	}
+
+	if existed {
+		im.report.Updated++
+	} else {
+		im.report.Created++
+	}
+}
+
+func (im *importer) member(row int, rec MemberRecord) {
+	if err := rec.validate(); err != nil {
+		im.fail("members", row, rec.ID, err)
+		return
+	}
+
+	member := models.Member{ID: rec.ID, Name: rec.Name, Password: rec.PasswordHash}
+	_, err := im.library.GetMember(rec.ID)
+	switch {
+	case err == nil && im.opts.OnDuplicate == SkipDuplicates:
+		im.report.Skipped++
+	case err == nil:
+		if err := im.library.UpdateMember(member); err != nil {
+			im.fail("members", row, rec.ID, err)
+			return
+		}
+		im.report.Updated++
+	case errors.Is(err, services.ErrMemberNotFound):
+		if err := im.library.AddMember(member); err != nil {
+			im.fail("members", row, rec.ID, err)
+			return
+		}
+		im.report.Created++
+	default:
+		im.fail("members", row, rec.ID, err)
+	}
+}
+
+// syncLoan moves book bookID to member want (0 meaning nobody), returning it
+// from its current holder first if necessary. A known borrowedAt is kept as
+// the start of the new loan.
+func (im *importer) syncLoan(bookID, want int, borrowedAt *time.Time) error {
+	have := im.holders[bookID]
+	if have == want {
+		return nil
+	}
+	if have != 0 {
+		if err := im.library.ReturnBook(bookID, have); err != nil {
+			return err
+		}
+		delete(im.holders, bookID)
+	}
+	if want != 0 {
+		var err error
+		if borrowedAt != nil {
+			err = im.library.RestoreLoan(bookID, want, *borrowedAt)
+		} else {
+			err = im.library.BorrowBook(bookID, want)
+		}
+		if err != nil {
+			return err
+		}
+		im.holders[bookID] = want
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package transfer
+
+import (
+	"encoding/json"
+	"io"
+	"library_management/models"
+	"library_management/services"
+)
+
+// ExportJSON writes every book and member of library, with loan state, as a
+// Backup document.
+func ExportJSON(w io.Writer, library services.LibraryManager) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(Snapshot(library))
+}
+
+// ImportJSON reads a Backup document into library. Members are imported
+// before books so that borrowed_by can refer to members in the same file,
+// and history events not already known are merged in last. Records that fail
+// to decode or validate are reported and skipped.
+func ImportJSON(r io.Reader, library services.LibraryManager, opts Options) (Report, error) {
+	var doc struct {
+		Books   []json.RawMessage  `json:"books"`
+		Members []json.RawMessage  `json:"members"`
+		History []models.LoanEvent `json:"history"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return Report{}, err
+	}
+
+	return run(library, opts, func(im *importer) error {
+		for i, raw := range doc.Members {
+			var rec MemberRecord
+			if err := json.Unmarshal(raw, &rec); err != nil {
+				im.fail("members", i+1, 0, err)
+				continue
+			}
+			im.member(i+1, rec)
+		}
+		for i, raw := range doc.Books {
+			var rec BookRecord
+			if err := json.Unmarshal(raw, &rec); err != nil {
+				im.fail("books", i+1, 0, err)
+				continue
+			}
+			im.book(i+1, rec)
+		}
+		im.library.MergeHistory(doc.History)
+		return nil
+	})
+}
@@ -0,0 +1,145 @@
+package transfer
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"library_management/models"
+	"library_management/services"
+)
+
+func newLibrary(t *testing.T) *services.Library {
+	t.Helper()
+	now := time.Date(2025, 10, 1, 9, 0, 0, 0, time.UTC)
+	library := services.NewLibrary()
+	library.SetClock(func() time.Time {
+		now = now.Add(time.Hour)
+		return now
+	})
+
+	for _, book := range []models.Book{
+		{ID: 1, Title: "Dune", Author: "Frank Herbert", ISBN: "978-0441172719", Subject: "Fiction"},
+		{ID: 2, Title: "Emma", Author: "Jane Austen"},
+		{ID: 3, Title: "Good Omens", Author: "Terry Pratchett"},
+	} {
+		if err := library.AddBook(book); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, member := range []models.Member{{ID: 1, Name: "Ada", Password: "$2a$10$hash"}, {ID: 2, Name: "Grace"}} {
+		if err := library.AddMember(member); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, step := range []func() error{
+		func() error { return library.RestoreLoan(3, 2, time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)) },
+		func() error { return library.BorrowBook(1, 1) },
+		func() error { return library.BorrowBook(2, 2) },
+		func() error { return library.ReturnBook(2, 2) },
+	} {
+		if err := step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return library
+}
+
+func export(t *testing.T, library services.LibraryManager) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := ExportJSON(&buf, library); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	original := export(t, newLibrary(t))
+
+	restored := services.NewLibrary()
+	report, err := ImportJSON(strings.NewReader(original), restored, Options{})
+	if err != nil {
+		t.Fatalf("ImportJSON: %v", err)
+	}
+	if len(report.Errors) > 0 {
+		t.Fatalf("ImportJSON reported %v", report.Errors)
+	}
+	if report.Created != 5 {
+		t.Errorf("Created = %d, want 5", report.Created)
+	}
+
+	if got := export(t, restored); got != original {
+		t.Errorf("export after import differs:\n%s\nwant:\n%s", got, original)
+	}
+	member, err := restored.GetMember(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(member.BorrowedBooks) != 1 || member.BorrowedBooks[0].ID != 3 {
+		t.Errorf("member 2 holds %v, want book 3", member.BorrowedBooks)
+	}
+}
+
+func TestImportJSONConflicts(t *testing.T) {
+	const input = `{
+		"members": [{"id": 2, "name": "Grace Hopper"}, {"id": 3, "name": "Alan"}],
+		"books": [
+			{"id": 2, "title": "Emma (annotated)", "author": "Jane Austen", "status": "Borrowed", "borrowed_by": 3},
+			{"id": 4, "title": "Neuromancer", "author": "William Gibson"},
+			{"id": 5, "title": "", "author": "Nobody"}
+		]
+	}`
+
+	tests := []struct {
+		name    string
+		opts    Options
+		report  Report
+		title   string
+		member  string
+		status  string
+		changed bool
+	}{
+		{"skip by default", Options{}, Report{Created: 2, Skipped: 2}, "Emma", "Grace", models.StatusAvailable, true},
+		{"upsert", Options{OnDuplicate: UpsertDuplicates}, Report{Created: 2, Updated: 2}, "Emma (annotated)", "Grace Hopper", models.StatusBorrowed, true},
+		{"dry run", Options{DryRun: true, OnDuplicate: UpsertDuplicates}, Report{DryRun: true, Created: 2, Updated: 2}, "Emma", "Grace", models.StatusAvailable, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			library := newLibrary(t)
+			before := export(t, library)
+
+			report, err := ImportJSON(strings.NewReader(input), library, tt.opts)
+			if err != nil {
+				t.Fatalf("ImportJSON: %v", err)
+			}
+			if len(report.Errors) != 1 || report.Errors[0].Kind != "books" || report.Errors[0].Row != 3 {
+				t.Errorf("Errors = %v, want books row 3", report.Errors)
+			}
+			report.Errors = nil
+			if !reflect.DeepEqual(report, tt.report) {
+				t.Errorf("report = %+v, want %+v", report, tt.report)
+			}
+
+			if changed := export(t, library) != before; changed != tt.changed {
+				t.Errorf("library changed = %v, want %v", changed, tt.changed)
+			}
+			book, err := library.GetBook(2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if book.Title != tt.title || book.Status != tt.status {
+				t.Errorf("book 2 = %q (%s), want %q (%s)", book.Title, book.Status, tt.title, tt.status)
+			}
+			member, err := library.GetMember(2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if member.Name != tt.member {
+				t.Errorf("member 2 name = %q, want %q", member.Name, tt.member)
+			}
+		})
+	}
+}
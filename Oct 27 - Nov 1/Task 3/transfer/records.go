@@ -0,0 +1,115 @@
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"library_management/models"
+	"library_management/services"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRecord = errors.New("invalid record")
+
+// Backup is the JSON form of a whole library, including who holds each
+// borrowed book and the circulation history, so it can be restored into an
+// empty library.
+type Backup struct {
+	Books   []BookRecord       `json:"books"`
+	Members []MemberRecord     `json:"members"`
+	History []models.LoanEvent `json:"history"`
+}
+
+type BookRecord struct {
+	ID         int        `json:"id"`
+	Title      string     `json:"title"`
+	Author     string     `json:"author"`
+	ISBN       string     `json:"isbn"`
+	Subject    string     `json:"subject"`
+	Status     string     `json:"status"`
+	BorrowedBy int        `json:"borrowed_by,omitempty"`
+	BorrowedAt *time.Time `json:"borrowed_at,omitempty"`
+}
+
+type MemberRecord struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	PasswordHash string `json:"password_hash,omitempty"`
+}
+
+// Snapshot captures the current state of library as a Backup.
+func Snapshot(library services.LibraryManager) Backup {
+	backup := Backup{Books: []BookRecord{}, Members: []MemberRecord{}, History: library.History()}
+	if backup.History == nil {
+		backup.History = []models.LoanEvent{}
+	}
+	borrowedAt := make(map[int]time.Time)
+	for _, e := range backup.History {
+		if e.Type == models.LoanBorrowed {
+			borrowedAt[e.BookID] = e.At
+		}
+	}
+
+	holders := make(map[int]int)
+	for _, m := range library.ListMembers() {
+		backup.Members = append(backup.Members, MemberRecord{ID: m.ID, Name: m.Name, PasswordHash: m.Password})
+		for _, b := range m.BorrowedBooks {
+			holders[b.ID] = m.ID
+		}
+	}
+	for _, b := range library.ListBooks() {
+		record := BookRecord{
+			ID:         b.ID,
+			Title:      b.Title,
+			Author:     b.Author,
+			ISBN:       b.ISBN,
+			Subject:    b.Subject,
+			Status:     b.Status,
+			BorrowedBy: holders[b.ID],
+		}
+		if at, ok := borrowedAt[b.ID]; ok && record.BorrowedBy != 0 {
+			record.BorrowedAt = &at
+		}
+		backup.Books = append(backup.Books, record)
+	}
+	return backup
+}
+
+func (r BookRecord) validate() error {
+	switch {
+	case r.ID <= 0:
+		return fmt.Errorf("%w: id must be a positive integer", ErrInvalidRecord)
+	case strings.TrimSpace(r.Title) == "":
+		return fmt.Errorf("%w: title is required", ErrInvalidRecord)
+	case strings.TrimSpace(r.Author) == "":
+		return fmt.Errorf("%w: author is required", ErrInvalidRecord)
+	case r.BorrowedBy < 0:
+		return fmt.Errorf("%w: borrowed_by must be a member ID", ErrInvalidRecord)
+	case r.BorrowedAt != nil && r.BorrowedBy == 0:
+		return fmt.Errorf("%w: borrowed_at needs borrowed_by", ErrInvalidRecord)
+	}
+
+	switch {
+	case r.Status == "" || strings.EqualFold(r.Status, models.StatusAvailable):
+		if r.BorrowedBy != 0 {
+			return fmt.Errorf("%w: an available book cannot have borrowed_by", ErrInvalidRecord)
+		}
+	case strings.EqualFold(r.Status, models.StatusBorrowed):
+		if r.BorrowedBy == 0 {
+			return fmt.Errorf("%w: a borrowed book needs borrowed_by", ErrInvalidRecord)
+		}
+	default:
+		return fmt.Errorf("%w: status must be %s or %s", ErrInvalidRecord, models.StatusAvailable, models.StatusBorrowed)
+	}
+	return nil
+}
+
+func (r MemberRecord) validate() error {
+	switch {
+	case r.ID <= 0:
+		return fmt.Errorf("%w: id must be a positive integer", ErrInvalidRecord)
+	case strings.TrimSpace(r.Name) == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidRecord)
+	}
+	return nil
+}
@@ -0,0 +1,383 @@
+package controllers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"sum/aggregate"
+	"text_stats/problem"
+	"word_frequency/wordfreq"
+)
+
+// DefaultMaxBodyBytes is the request size limit used when none is configured.
+const DefaultMaxBodyBytes = 10 << 20
+
+// maxNumberSize bounds a single token of a numbers upload.
+const maxNumberSize = 1 << 10
+
+var (
+	errInvalidNumber = errors.New("invalid number")
+	errOutOfRange    = errors.New("out of range")
+)
+
+type StatsController struct {
+	maxBodyBytes int64
+}
+
+func NewStatsController(maxBodyBytes int64) *StatsController {
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = DefaultMaxBodyBytes
+	}
+	return &StatsController{maxBodyBytes: maxBodyBytes}
+}
+
+type WordStatsResponse struct {
+	Total       int                  `json:"total"`
+	Unique      int                  `json:"unique"`
+	Top         []wordfreq.WordCount `json:"top"`
+	Frequencies map[string]int       `json:"frequencies,omitempty"`
+}
+
+type NumberStatsResponse struct {
+	Count       int                `json:"count"`
+	Sum         json.Number        `json:"sum"`
+	Mean        float64            `json:"mean"`
+	Min         float64            `json:"min"`
+	Max         float64            `json:"max"`
+	Variance    float64            `json:"variance"`
+	StdDev      float64            `json:"std_dev"`
+	Median      float64            `json:"median"`
+	Percentiles map[string]float64 `json:"percentiles,omitempty"`
+}
+
+type wordsRequest struct {
+	Text string `json:"text"`
+}
+
+type numbersRequest struct {
+	Numbers []json.Number `json:"numbers"`
+}
+
+// WordStats counts the words of a text/plain body, a JSON {"text": ...} body
+// or every file of a multipart/form-data upload. Plain and multipart bodies
+// are counted as they are read, so only the vocabulary is held in memory.
+func (sc *StatsController) WordStats(c *gin.Context) {
+	opts, rank, withFrequencies, ok := wordOptions(c)
+	if !ok {
+		return
+	}
+
+	counter, err := wordfreq.NewWordFrequencyCounterWithOptions(opts)
+	if err != nil {
+		problem.AbortWithStatus(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, sc.maxBodyBytes)
+	err = sc.readBody(c, body, func(r io.Reader) error {
+		return counter.Count(r)
+	}, func(data []byte) error {
+		var req wordsRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return err
+		}
+		return counter.Count(strings.NewReader(req.Text))
+	})
+	if err != nil {
+		respondReadError(c, err)
+		return
+	}
+
+	counts := counter.Counts()
+	response := WordStatsResponse{
+		Total:  counter.Total(),
+		Unique: counter.Unique(),
+		Top:    wordfreq.Rank(counts, rank),
+	}
+	if withFrequencies {
+		response.Frequencies = counts
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// NumberStats aggregates numbers sent as a JSON {"numbers": [...]} body, or
+// as whitespace- or comma-separated text in a text/plain body or uploaded
+// files. Integer input is summed exactly; the sum falls back to floating
+// point only if it would overflow an int64.
+func (sc *StatsController) NumberStats(c *gin.Context) {
+	percentiles, ok := percentileParams(c)
+	if !ok {
+		return
+	}
+
+	agg := numberAggregator{allInts: true}
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, sc.maxBodyBytes)
+	err := sc.readBody(c, body, agg.readText, func(data []byte) error {
+		var req numbersRequest
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&req); err != nil {
+			return err
+		}
+		for _, n := range req.Numbers {
+			if err := agg.add(n.String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		respondReadError(c, err)
+		return
+	}
+	if agg.floats.Count() == 0 {
+		problem.AbortWithStatus(c, http.StatusUnprocessableEntity, "no numbers in request")
+		return
+	}
+
+	response, err := agg.response(percentiles)
+	if err != nil {
+		problem.AbortWithStatus(c, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// readBody dispatches on the request content type. Streaming bodies are
+// passed to stream; JSON bodies are read whole, within the size limit, and
+// passed to decode.
+func (sc *StatsController) readBody(c *gin.Context, body io.Reader, stream func(io.Reader) error, decode func([]byte) error) error {
+	mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	switch mediaType {
+	case "application/json":
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		if err := decode(data); err != nil {
+			return err
+		}
+		return nil
+
+	case "multipart/form-data":
+		c.Request.Body = io.NopCloser(body)
+		reader, err := c.Request.MultipartReader()
+		if err != nil {
+			return err
+		}
+		files := 0
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if part.FileName() == "" && part.FormName() != "text" {
+				part.Close()
+				continue
+			}
+			files++
+			err = stream(part)
+			part.Close()
+			if err != nil {
+				return err
+			}
+		}
+		if files == 0 {
+			return errors.New(`multipart body has no file or "text" field`)
+		}
+		return nil
+
+	case "text/plain":
+		return stream(body)
+	}
+
+	return unsupportedMediaType(mediaType)
+}
+
+type unsupportedMediaType string
+
+func (e unsupportedMediaType) Error() string {
+	return "unsupported content type " + strconv.Quote(string(e))
+}
+
+func respondReadError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	var unsupported unsupportedMediaType
+	switch {
+	case errors.As(err, &tooLarge):
+		problem.AbortWithStatus(c, http.StatusRequestEntityTooLarge,
+			"request body exceeds "+strconv.FormatInt(tooLarge.Limit, 10)+" bytes")
+	case errors.As(err, &unsupported):
+		problem.AbortWithStatus(c, http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, errInvalidNumber):
+		problem.AbortWithStatus(c, http.StatusUnprocessableEntity, err.Error())
+	default:
+		problem.AbortWithStatus(c, http.StatusBadRequest, err.Error())
+	}
+}
+
+func wordOptions(c *gin.Context) (wordfreq.Options, wordfreq.RankOptions, bool, bool) {
+	opts := wordfreq.DefaultOptions
+	if c.Query("unicode") == "true" {
+		opts = wordfreq.UnicodeOptions
+	}
+	if stopwords := c.Query("stopwords"); stopwords != "" {
+		opts.StopwordLanguages = strings.Split(stopwords, ",")
+	}
+
+	var rank wordfreq.RankOptions
+	var ok bool
+	if opts.NGram, ok = queryInt(c, "ngram", 1, 1, 3); !ok {
+		return opts, rank, false, false
+	}
+	if rank.Top, ok = queryInt(c, "top", 10, 0, math.MaxInt32); !ok {
+		return opts, rank, false, false
+	}
+	if rank.MinCount, ok = queryInt(c, "min_count", 0, 0, math.MaxInt32); !ok {
+		return opts, rank, false, false
+	}
+	if rank.MinLength, ok = queryInt(c, "min_length", 0, 0, math.MaxInt32); !ok {
+		return opts, rank, false, false
+	}
+	return opts, rank, c.Query("frequencies") == "true", true
+}
+
+// percentileParams parses ?percentiles=90,99 into the percentiles to report.
+func percentileParams(c *gin.Context) ([]float64, bool) {
+	param := c.Query("percentiles")
+	if param == "" {
+		return nil, true
+	}
+	var ps []float64
+	for _, field := range strings.Split(param, ",") {
+		p, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil || p < 0 || p > 100 {
+			problem.AbortWithStatus(c, http.StatusBadRequest, "percentiles must be numbers between 0 and 100")
+			return nil, false
+		}
+		ps = append(ps, p)
+	}
+	return ps, true
+}
+
+func queryInt(c *gin.Context, name string, defaultValue, min, max int) (int, bool) {
+	param := c.Query(name)
+	if param == "" {
+		return defaultValue, true
+	}
+	n, err := strconv.Atoi(param)
+	if err != nil || n < min || n > max {
+		problem.AbortWithStatus(c, http.StatusBadRequest,
+			name+" must be an integer between "+strconv.Itoa(min)+" and "+strconv.Itoa(max))
+		return 0, false
+	}
+	return n, true
+}
+
+// numberAggregator accumulates numbers as both float64 and, while every
+// value is an integer, int64 so integer sums stay exact. Values are kept
+// for the median and percentiles; the request size limit bounds them.
+type numberAggregator struct {
+	floats  aggregate.Accumulator[float64]
+	ints    aggregate.Accumulator[int64]
+	allInts bool
+	values  []float64
+}
+
+func (a *numberAggregator) add(token string) error {
+	f, err := strconv.ParseFloat(token, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Errorf("%w %q", errInvalidNumber, token)
+	}
+	if a.allInts {
+		if i, err := strconv.ParseInt(token, 10, 64); err == nil {
+			a.ints.Add(i)
+		} else {
+			a.allInts = false
+		}
+	}
+	a.floats.Add(f)
+	a.values = append(a.values, f)
+	return nil
+}
+
+func (a *numberAggregator) readText(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 4*1024), maxNumberSize)
+	scanner.Split(bufio.ScanWords)
+	for scanner.Scan() {
+		for _, token := range strings.Split(scanner.Text(), ",") {
+			if token == "" {
+				continue
+			}
+			if err := a.add(token); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// response computes the statistics. Finite inputs can still overflow
+// float64, and JSON has no Inf, so such results are reported as
+// errOutOfRange instead.
+func (a *numberAggregator) response(percentiles []float64) (NumberStatsResponse, error) {
+	var response NumberStatsResponse
+	response.Count = a.floats.Count()
+	response.Mean, _ = a.floats.Mean()
+	response.Min, _ = a.floats.Min()
+	response.Max, _ = a.floats.Max()
+	response.Variance, _ = a.floats.Variance()
+	response.StdDev, _ = a.floats.StdDev()
+
+	if sum, err := a.ints.Sum(); a.allInts && err == nil {
+		response.Sum = json.Number(strconv.FormatInt(sum, 10))
+	} else {
+		sum, _ := a.floats.Sum()
+		if err := checkFinite("sum", sum); err != nil {
+			return NumberStatsResponse{}, err
+		}
+		response.Sum = json.Number(strconv.FormatFloat(sum, 'g', -1, 64))
+	}
+	if err := checkFinite("mean", response.Mean); err != nil {
+		return NumberStatsResponse{}, err
+	}
+	if err := checkFinite("variance", response.Variance); err != nil {
+		return NumberStatsResponse{}, err
+	}
+
+	response.Median, _ = aggregate.Median(a.values)
+	if len(percentiles) > 0 {
+		values, _ := aggregate.Percentiles(a.values, percentiles...)
+		response.Percentiles = make(map[string]float64, len(values))
+		for i, p := range percentiles {
+			response.Percentiles["p"+strconv.FormatFloat(p, 'f', -1, 64)] = values[i]
+		}
+	}
+	return response, nil
+}
+
+func checkFinite(name string, value float64) error {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Errorf("%s is %w of float64", name, errOutOfRange)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"text_stats/problem"
+)
+
+func TestNumberStatsOutOfRange(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/stats/numbers", NewStatsController(0).NumberStats)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		detail      string
+	}{
+		{"ok", "text/plain", "1 2 3", http.StatusOK, ""},
+		{"huge but finite", "text/plain", "1e300 1e300 1e300", http.StatusOK, ""},
+		{"sum overflows", "text/plain", "1e308 1e308", http.StatusUnprocessableEntity, "sum is out of range of float64"},
+		{"negative sum overflows", "application/json", `{"numbers": [-1e308, -1e308]}`, http.StatusUnprocessableEntity, "sum is out of range of float64"},
+		{"variance overflows", "text/plain", "1e308,-1e308", http.StatusUnprocessableEntity, "variance is out of range of float64"},
+		{"integer sum falls back to float", "text/plain", "9223372036854775807 1", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/stats/numbers", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status == http.StatusOK {
+				var response NumberStatsResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+					t.Fatalf("decoding %q: %v", w.Body, err)
+				}
+				return
+			}
+			if ct := w.Header().Get("Content-Type"); ct != problem.ContentType {
+				t.Errorf("Content-Type = %q, want %q", ct, problem.ContentType)
+			}
+			var p problem.Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+				t.Fatalf("decoding %q: %v", w.Body, err)
+			}
+			if p.Detail != tt.detail {
+				t.Errorf("detail = %q, want %q", p.Detail, tt.detail)
+			}
+		})
+	}
+}
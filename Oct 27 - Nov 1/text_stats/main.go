@@ -0,0 +1,36 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+
+	"text_stats/controllers"
+	"text_stats/router"
+)
+
+func main() {
+	// Request size limit, in bytes
+	maxBodyBytes := int64(controllers.DefaultMaxBodyBytes)
+	if value := os.Getenv("MAX_BODY_BYTES"); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n <= 0 {
+			log.Fatal("Invalid MAX_BODY_BYTES:", value)
+		}
+		maxBodyBytes = n
+	}
+
+	statsController := controllers.NewStatsController(maxBodyBytes)
+	r := router.SetupRouter(statsController)
+
+	// Start server
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	log.Printf("Server running on port %s\n", port)
+	if err := r.Run(":" + port); err != nil {
+		log.Fatal("Failed to start server:", err)
+	}
+}
@@ -0,0 +1,44 @@
+// Package problem writes RFC 7807 "problem details" error responses.
+package problem
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ContentType = "application/problem+json"
+
+// Problem is the body of an application/problem+json response.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// New returns a problem of the generic "about:blank" type, titled with the
+// standard text for status.
+func New(status int, detail string) Problem {
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// Abort writes p for the current request and stops the handler chain.
+func Abort(c *gin.Context, p Problem) {
+	if p.Instance == "" {
+		p.Instance = c.Request.URL.Path
+	}
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// AbortWithStatus is shorthand for Abort(c, New(status, detail)).
+func AbortWithStatus(c *gin.Context, status int, detail string) {
+	Abort(c, New(status, detail))
+}
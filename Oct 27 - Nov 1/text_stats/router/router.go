@@ -0,0 +1,32 @@
+package router
+
+import (
+	"net/http"
+
+	"text_stats/controllers"
+	"text_stats/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupRouter(statsController *controllers.StatsController) *gin.Engine {
+	r := gin.Default()
+	r.HandleMethodNotAllowed = true
+
+	// Unknown routes get problem+json bodies like every other error
+	r.NoRoute(func(c *gin.Context) {
+		problem.AbortWithStatus(c, http.StatusNotFound, "no such endpoint")
+	})
+	r.NoMethod(func(c *gin.Context) {
+		problem.AbortWithStatus(c, http.StatusMethodNotAllowed, "method not allowed")
+	})
+
+	// Stats routes
+	stats := r.Group("/stats")
+	{
+		stats.POST("/words", statsController.WordStats)
+		stats.POST("/numbers", statsController.NumberStats)
+	}
+
+	return r
+}